@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/celestix/gotgproto/dispatcher"
+	"github.com/celestix/gotgproto/ext"
+	"github.com/charmbracelet/log"
+	"github.com/krau/SaveAny-Bot/config"
+	"github.com/krau/SaveAny-Bot/core"
+)
+
+func handleReloadCmd(ctx *ext.Context, update *ext.Update) error {
+	if err := config.Reload(ctx); err != nil {
+		log.FromContext(ctx).Errorf("Failed to reload config: %s", err)
+		ctx.Reply(update, ext.ReplyTextString("重载配置失败, 当前配置保持不变:\n"+err.Error()), nil)
+		return dispatcher.EndGroups
+	}
+	ctx.Reply(update, ext.ReplyTextString("配置已重载"), nil)
+	return dispatcher.EndGroups
+}
+
+func handleCancelAllCmd(ctx *ext.Context, update *ext.Update) error {
+	count := core.CancelAll(ctx)
+	log.FromContext(ctx).Infof("User %d cancelled all %d tasks", update.GetUserChat().GetID(), count)
+	ctx.Reply(update, ext.ReplyTextString(fmt.Sprintf("已取消 %d 个任务", count)), nil)
+	return dispatcher.EndGroups
+}
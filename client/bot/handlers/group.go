@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"github.com/celestix/gotgproto/dispatcher"
+	"github.com/celestix/gotgproto/ext"
+	"github.com/celestix/gotgproto/types"
+	"github.com/charmbracelet/log"
+	"github.com/duke-git/lancet/v2/slice"
+	"github.com/gotd/td/tg"
+	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/mediautil"
+	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/shortcut"
+	"github.com/krau/SaveAny-Bot/common/utils/tgutil"
+	"github.com/krau/SaveAny-Bot/config"
+	"github.com/krau/SaveAny-Bot/pkg/tfile"
+	"github.com/krau/SaveAny-Bot/storage"
+)
+
+// notPrivateChat matches messages from groups and channels
+func notPrivateChat(m *types.Message) bool {
+	_, ok := m.PeerID.(*tg.PeerUser)
+	return !ok
+}
+
+// addGroupTask saves the files sent to a group, tests replace it to see them
+var addGroupTask = shortcut.CreateAndAddGroupTaskInChat
+
+// handleGroupChatMessage saves the media sent to the groups in the config to the group's default storage.
+// Messages from other groups and channels are ignored.
+func handleGroupChatMessage(ctx *ext.Context, update *ext.Update) error {
+	chatID := update.EffectiveChat().GetID()
+	group, ok := config.C().GetGroup(chatID)
+	if !ok {
+		return dispatcher.EndGroups
+	}
+	message := update.EffectiveMessage.Message
+	if message.Media == nil || !mediautil.IsSupported(message.Media) {
+		return dispatcher.EndGroups
+	}
+	sender := update.EffectiveUser()
+	if sender == nil {
+		return dispatcher.EndGroups
+	}
+	userID := sender.GetID()
+	if !slice.Contain(config.C().GetUsersID(), userID) || !group.AllowUser(userID) {
+		return dispatcher.EndGroups
+	}
+
+	logger := log.FromContext(ctx)
+	// 以发送者的身份检查存储权限, 但不应用发送者通过 /rule 设置的规则
+	stor, err := storage.GetStorageByUserIDAndName(ctx, userID, group.DefaultStorage)
+	if err != nil {
+		logger.Warnf("Failed to get storage %s for user %d in group %d: %s", group.DefaultStorage, userID, chatID, err)
+		if !group.Silent {
+			ctx.Reply(update, ext.ReplyTextString("获取存储失败: "+err.Error()), nil)
+		}
+		return dispatcher.EndGroups
+	}
+	file, err := tfile.FromMediaMessage(message.Media, ctx.Raw, message, tfile.WithNameIfEmpty(
+		tgutil.GenFileNameFromMessage(*message),
+	))
+	if err != nil {
+		logger.Errorf("Failed to get file from media: %s", err)
+		return dispatcher.EndGroups
+	}
+	save := func(files []tfile.TGFileMessage) {
+		trackMsgID := 0
+		if !group.Silent {
+			msg, err := ctx.Reply(update, ext.ReplyTextString("正在保存文件..."), nil)
+			if err != nil {
+				logger.Errorf("Failed to reply: %s", err)
+				return
+			}
+			trackMsgID = msg.ID
+		}
+		addGroupTask(ctx, chatID, userID, stor, group.DefaultPath, files, trackMsgID)
+	}
+
+	groupID, isGroup := message.GetGroupedID()
+	if isGroup && groupID != 0 {
+		mediaGroupHandler.add(groupID, file, save)
+		return dispatcher.EndGroups
+	}
+	save([]tfile.TGFileMessage{file})
+	return dispatcher.EndGroups
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/celestix/gotgproto/ext"
+	"github.com/gotd/td/tg"
+	"github.com/krau/SaveAny-Bot/config"
+	"github.com/krau/SaveAny-Bot/database"
+	"github.com/krau/SaveAny-Bot/pkg/tfile"
+	"github.com/krau/SaveAny-Bot/storage"
+)
+
+const (
+	memberID   = 1001 // in the user list and allowed in the restricted group
+	outsiderID = 1002 // in the user list but not allowed in the restricted group
+	strangerID = 1003 // not in the user list
+
+	restrictedGroup = 1234567890
+	openGroup       = 1111111111
+	unlistedGroup   = 2222222222
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "handlers")
+	if err != nil {
+		panic(err)
+	}
+	code := func() int {
+		defer os.RemoveAll(dir)
+		configFile := filepath.Join(dir, "config.toml")
+		toml := fmt.Sprintf(`
+[telegram]
+token = "123:abc"
+
+[db]
+path = %[1]q
+session = %[2]q
+
+[temp]
+base_path = %[3]q
+
+[[storages]]
+name = "group"
+type = "local"
+enable = true
+base_path = %[4]q
+
+[[users]]
+id = %[5]d
+storages = []
+blacklist = true
+
+[[users]]
+id = %[6]d
+storages = []
+blacklist = true
+
+[[groups]]
+id = -100%[7]d
+users = [%[5]d]
+default_storage = "group"
+default_path = "restricted"
+silent = true
+
+[[groups]]
+id = %[8]d
+default_storage = "group"
+default_path = "open"
+silent = true
+`, filepath.Join(dir, "data", "saveany.db"), filepath.Join(dir, "data", "session.db"), filepath.Join(dir, "cache"),
+			filepath.Join(dir, "group"), memberID, outsiderID, restrictedGroup, openGroup)
+		if err := os.WriteFile(configFile, []byte(toml), 0o644); err != nil {
+			panic(err)
+		}
+		ctx := context.Background()
+		config.SetConfigFile(configFile)
+		if err := config.Init(ctx); err != nil {
+			panic(err)
+		}
+		database.Init(ctx)
+		storage.LoadStorages(ctx)
+		return m.Run()
+	}()
+	os.Exit(code)
+}
+
+// groupMessage builds the update of a document sent by senderID to the supergroup chatID
+func groupMessage(chatID, senderID int64, name string) *ext.Update {
+	msg := &tg.Message{
+		ID:     1,
+		PeerID: &tg.PeerChannel{ChannelID: chatID},
+		FromID: &tg.PeerUser{UserID: senderID},
+		Media: &tg.MessageMediaDocument{Document: &tg.Document{
+			ID:         1,
+			Size:       1024,
+			Attributes: []tg.DocumentAttributeClass{&tg.DocumentAttributeFilename{FileName: name}},
+		}},
+	}
+	entities := &tg.Entities{
+		Users:    map[int64]*tg.User{senderID: {ID: senderID}},
+		Channels: map[int64]*tg.Channel{chatID: {ID: chatID}},
+	}
+	return ext.GetNewUpdate(context.Background(), nil, 0, nil, entities, &tg.UpdateNewChannelMessage{Message: msg})
+}
+
+type groupSave struct {
+	chatID, userID int64
+	storage, path  string
+	files          []string
+}
+
+// captureGroupTasks replaces addGroupTask for the test and returns the saves
+func captureGroupTasks(t *testing.T) *[]groupSave {
+	t.Helper()
+	saved := addGroupTask
+	var got []groupSave
+	addGroupTask = func(ctx *ext.Context, chatID, userID int64, stor storage.Storage, dirPath string, files []tfile.TGFileMessage, trackMsgID int) error {
+		save := groupSave{chatID: chatID, userID: userID, storage: stor.Name(), path: dirPath}
+		for _, file := range files {
+			save.files = append(save.files, file.Name())
+		}
+		got = append(got, save)
+		return nil
+	}
+	t.Cleanup(func() { addGroupTask = saved })
+	return &got
+}
+
+func TestHandleGroupChatMessage(t *testing.T) {
+	ctx := &ext.Context{Context: context.Background()}
+	tests := []struct {
+		name     string
+		chatID   int64
+		senderID int64
+		want     *groupSave
+	}{
+		{"allowed member", restrictedGroup, memberID, &groupSave{chatID: restrictedGroup, userID: memberID, storage: "group", path: "restricted", files: []string{"a.txt"}}},
+		{"user not allowed in group", restrictedGroup, outsiderID, nil},
+		{"not in user list", restrictedGroup, strangerID, nil},
+		{"group allowing all users", openGroup, outsiderID, &groupSave{chatID: openGroup, userID: outsiderID, storage: "group", path: "open", files: []string{"a.txt"}}},
+		{"not in user list, group allowing all users", openGroup, strangerID, nil},
+		{"unlisted group", unlistedGroup, memberID, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := captureGroupTasks(t)
+			handleGroupChatMessage(ctx, groupMessage(tt.chatID, tt.senderID, "a.txt"))
+			if tt.want == nil {
+				if len(*got) != 0 {
+					t.Errorf("saved %+v, want the message ignored", *got)
+				}
+				return
+			}
+			if len(*got) != 1 {
+				t.Fatalf("saved %d times, want once", len(*got))
+			}
+			if g := (*got)[0]; fmt.Sprint(g) != fmt.Sprint(*tt.want) {
+				t.Errorf("saved %+v, want %+v", g, *tt.want)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/celestix/gotgproto/dispatcher"
 	"github.com/celestix/gotgproto/ext"
+	"github.com/krau/SaveAny-Bot/config"
 	"github.com/krau/SaveAny-Bot/pkg/consts"
 )
 
@@ -18,9 +19,11 @@ Save Any Bot - 转存你的 Telegram 文件
 /help - 显示帮助
 /silent - 开关静默模式
 /storage - 设置默认存储位置
+/setdefault <存储名> [路径] - 设置默认存储及路径, 保存时不再询问
 /save [自定义文件名] - 保存文件
 /dir - 管理存储目录
 /rule - 管理规则
+/whichrule - 回复文件, 查看会命中的配置规则
 
 使用帮助: https://sabot.unv.app/usage/
 `
@@ -28,6 +31,21 @@ Save Any Bot - 转存你的 Telegram 文件
 	if len(shortHash) > 7 {
 		shortHash = shortHash[:7]
 	}
-	ctx.Reply(update, ext.ReplyTextString(fmt.Sprintf(helpText, consts.Version, shortHash)), nil)
+	text := fmt.Sprintf(helpText, consts.Version, shortHash)
+	if config.C().IsAdmin(update.GetUserChat().GetID()) {
+		text += adminHelpText
+	}
+	ctx.Reply(update, ext.ReplyTextString(text), nil)
 	return dispatcher.EndGroups
 }
+
+const adminHelpText string = `
+管理员命令:
+/reload - 重载配置文件
+/cancelall - 取消所有用户的任务
+/rules - 查看全部配置规则及命中次数
+/ruleadd <字段=值> ... - 添加规则, 字段与配置文件相同
+/ruledel <编号> - 删除通过 /ruleadd 添加的规则
+/ruletoggle <编号> - 启用或禁用通过 /ruleadd 添加的规则
+/ruletest [文件描述] - 回复文件或描述一个文件, 测试规则而不保存
+`
@@ -19,6 +19,10 @@ func handleMessageLink(ctx *ext.Context, update *ext.Update) error {
 	}
 	logger := log.FromContext(ctx)
 	userId := update.GetUserChat().GetID()
+	ruled, files := shortcut.MatchConfigRules(ctx, userId, files)
+	if len(files) == 0 {
+		return shortcut.CreateAndAddRuledTaskWithEdit(ctx, userId, ruled, replied.ID)
+	}
 	stors := storage.GetUserStorages(ctx, userId)
 	if len(files) == 1 {
 		req, err := msgelem.BuildAddOneSelectStorageMessage(ctx, stors, files[0], replied.ID)
@@ -55,8 +59,5 @@ func handleSilentSaveLink(ctx *ext.Context, update *ext.Update) error {
 		return err
 	}
 	userId := update.GetUserChat().GetID()
-	if len(files) == 1 {
-		return shortcut.CreateAndAddTGFileTaskWithEdit(ctx, userId, stor, "", files[0], replied.ID)
-	}
-	return shortcut.CreateAndAddBatchTGFileTaskWithEdit(ctx, userId, stor, "", files, replied.ID)
+	return shortcut.CreateAndAddDefaultTaskWithEdit(ctx, userId, stor, defaultDirFromContext(ctx), files, replied.ID)
 }
@@ -32,6 +32,9 @@ func handleMediaMessage(ctx *ext.Context, update *ext.Update) error {
 		return err
 	}
 	userId := update.GetUserChat().GetID()
+	if ruled, rest := shortcut.MatchConfigRules(ctx, userId, []tfile.TGFileMessage{file}); len(rest) == 0 {
+		return shortcut.CreateAndAddRuledTaskWithEdit(ctx, userId, ruled, msg.ID)
+	}
 	stors := storage.GetUserStorages(ctx, userId)
 	req, err := msgelem.BuildAddOneSelectStorageMessage(ctx, stors, file, msg.ID)
 	if err != nil {
@@ -62,7 +65,7 @@ func handleSilentSaveMedia(ctx *ext.Context, update *ext.Update) error {
 	if err != nil {
 		return err
 	}
-	return shortcut.CreateAndAddTGFileTaskWithEdit(ctx, userID, stor, "", file, msg.ID)
+	return shortcut.CreateAndAddDefaultTaskWithEdit(ctx, userID, stor, defaultDirFromContext(ctx), []tfile.TGFileMessage{file}, msg.ID)
 }
 
 type MediaGroupHandler struct {
@@ -92,29 +95,36 @@ func handleGroupMediaMessage(ctx *ext.Context, update *ext.Update, message *tg.M
 		logger.Errorf("Failed to get file from media: %s", err)
 		return dispatcher.EndGroups
 	}
-	mediaGroupHandler.mu.Lock()
-	defer mediaGroupHandler.mu.Unlock()
-	if mediaGroupHandler.groups[groupID] == nil {
-		mediaGroupHandler.groups[groupID] = make([]tfile.TGFileMessage, 0)
+	mediaGroupHandler.add(groupID, file, func(items []tfile.TGFileMessage) {
+		processMediaGroup(ctx, update, groupID, items)
+	})
+	return dispatcher.EndGroups
+}
+
+// add collects the files of a media group and calls process with all of them once no more files arrive
+func (h *MediaGroupHandler) add(groupID int64, file tfile.TGFileMessage, process func(items []tfile.TGFileMessage)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.groups[groupID] == nil {
+		h.groups[groupID] = make([]tfile.TGFileMessage, 0)
 	}
-	mediaGroupHandler.groups[groupID] = append(mediaGroupHandler.groups[groupID], file)
+	h.groups[groupID] = append(h.groups[groupID], file)
 
-	if timer, exists := mediaGroupHandler.timers[groupID]; exists {
+	if timer, exists := h.timers[groupID]; exists {
 		timer.Stop()
 	}
-	mediaGroupHandler.timers[groupID] = time.AfterFunc(mediaGroupHandler.timeout, func() {
-		processMediaGroup(ctx, update, groupID)
+	h.timers[groupID] = time.AfterFunc(h.timeout, func() {
+		h.mu.Lock()
+		items := h.groups[groupID]
+		delete(h.groups, groupID)
+		delete(h.timers, groupID)
+		h.mu.Unlock()
+		process(items)
 	})
-	return dispatcher.EndGroups
 }
 
-func processMediaGroup(ctx *ext.Context, update *ext.Update, groupID int64) {
+func processMediaGroup(ctx *ext.Context, update *ext.Update, groupID int64, items []tfile.TGFileMessage) {
 	logger := log.FromContext(ctx)
-	mediaGroupHandler.mu.Lock()
-	items := mediaGroupHandler.groups[groupID]
-	delete(mediaGroupHandler.groups, groupID)
-	delete(mediaGroupHandler.timers, groupID)
-	mediaGroupHandler.mu.Unlock()
 	if len(items) == 0 {
 		logger.Warn("No media items to process for group", "groupID", groupID)
 		return
@@ -130,14 +140,15 @@ func processMediaGroup(ctx *ext.Context, update *ext.Update, groupID int64) {
 	stor := storage.FromContext(ctx)
 	if stor != nil {
 		// In silent mode
-		if len(items) == 1 {
-			shortcut.CreateAndAddTGFileTaskWithEdit(ctx, userId, stor, "", items[0], msg.ID)
-			return
-		}
-		shortcut.CreateAndAddBatchTGFileTaskWithEdit(ctx, userId, stor, "", items, msg.ID)
+		shortcut.CreateAndAddDefaultTaskWithEdit(ctx, userId, stor, defaultDirFromContext(ctx), items, msg.ID)
 		return
 	}
 
+	ruled, items := shortcut.MatchConfigRules(ctx, userId, items)
+	if len(items) == 0 {
+		shortcut.CreateAndAddRuledTaskWithEdit(ctx, userId, ruled, msg.ID)
+		return
+	}
 	stors := storage.GetUserStorages(ctx, userId)
 	markup, err := msgelem.BuildAddSelectStorageKeyboard(stors, tcbdata.Add{
 		Files:   items,
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"context"
+
 	"github.com/celestix/gotgproto/dispatcher"
 	"github.com/celestix/gotgproto/ext"
 	"github.com/duke-git/lancet/v2/slice"
@@ -11,7 +13,7 @@ import (
 
 func checkPermission(ctx *ext.Context, update *ext.Update) error {
 	userID := update.GetUserChat().GetID()
-	if !slice.Contain(config.Cfg.GetUsersID(), userID) {
+	if !slice.Contain(config.C().GetUsersID(), userID) {
 		const noPermissionText string = `
 您不在白名单中, 无法使用此 Bot.
 您可以部署自己的实例: https://github.com/krau/SaveAny-Bot
@@ -23,6 +25,17 @@ func checkPermission(ctx *ext.Context, update *ext.Update) error {
 	return dispatcher.ContinueGroups
 }
 
+// requireAdmin only lets users with the admin role run the handler
+func requireAdmin(handler func(*ext.Context, *ext.Update) error) func(*ext.Context, *ext.Update) error {
+	return func(ctx *ext.Context, update *ext.Update) error {
+		if !config.C().IsAdmin(update.GetUserChat().GetID()) {
+			ctx.Reply(update, ext.ReplyTextString("此命令仅限管理员使用"), nil)
+			return dispatcher.EndGroups
+		}
+		return handler(ctx, update)
+	}
+}
+
 func handleSilentMode(next func(*ext.Context, *ext.Update) error, handler func(*ext.Context, *ext.Update) error) func(*ext.Context, *ext.Update) error {
 	return func(ctx *ext.Context, update *ext.Update) error {
 		userID := update.GetUserChat().GetID()
@@ -31,19 +44,43 @@ func handleSilentMode(next func(*ext.Context, *ext.Update) error, handler func(*
 			ctx.Reply(update, ext.ReplyTextString("获取用户信息失败: "+err.Error()), nil)
 			return dispatcher.EndGroups
 		}
-		if !user.Silent {
-			return next(ctx, update)
-		}
-		if user.DefaultStorage == "" {
-			ctx.Reply(update, ext.ReplyTextString("您已开启静默模式, 但未设置默认存储端, 请先使用 /storage 设置"), nil)
+		storName, dirPath := userDefault(user)
+		if storName == "" {
+			if user.Silent {
+				ctx.Reply(update, ext.ReplyTextString("您已开启静默模式, 但未设置默认存储端, 请先使用 /storage 或 /setdefault 设置"), nil)
+			}
 			return next(ctx, update)
 		}
-		stor, err := storage.GetStorageByUserIDAndName(ctx, userID, user.DefaultStorage)
+		stor, err := storage.GetStorageByUserIDAndName(ctx, userID, storName)
 		if err != nil {
 			ctx.Reply(update, ext.ReplyTextString("获取默认存储失败: "+err.Error()), nil)
 			return dispatcher.EndGroups
 		}
-		ctx.Context = storage.WithContext(ctx.Context, stor)
+		ctx.Context = withDefaultDir(storage.WithContext(ctx.Context, stor), dirPath)
 		return handler(ctx, update)
 	}
 }
+
+// userDefault returns the storage and path to save to without asking the user:
+// the silent mode storage, then the default set with /setdefault, then the one in the config.
+func userDefault(user *database.User) (storageName, dirPath string) {
+	if user.Silent && user.DefaultStorage != "" {
+		return user.DefaultStorage, ""
+	}
+	if user.DefaultSaveStorage != "" {
+		return user.DefaultSaveStorage, user.DefaultSavePath
+	}
+	return config.C().GetUserDefault(user.ChatID)
+}
+
+type defaultDirKey struct{}
+
+func withDefaultDir(ctx context.Context, dirPath string) context.Context {
+	return context.WithValue(ctx, defaultDirKey{}, dirPath)
+}
+
+// defaultDirFromContext returns the default path the storage in the context was picked with
+func defaultDirFromContext(ctx context.Context) string {
+	dirPath, _ := ctx.Value(defaultDirKey{}).(string)
+	return dirPath
+}
@@ -1,47 +1,50 @@
 package handlers
 
 import (
-	"path"
 	"regexp"
 	"strings"
 
 	"github.com/celestix/gotgproto/dispatcher"
 	"github.com/celestix/gotgproto/dispatcher/handlers"
 	"github.com/celestix/gotgproto/dispatcher/handlers/filters"
-	"github.com/celestix/gotgproto/ext"
 	"github.com/charmbracelet/log"
 	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/re"
-	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/ruleutil"
+	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/shortcut"
 	userclient "github.com/krau/SaveAny-Bot/client/user"
-	"github.com/krau/SaveAny-Bot/common/utils/tgutil"
 	"github.com/krau/SaveAny-Bot/config"
-	"github.com/krau/SaveAny-Bot/core"
-	"github.com/krau/SaveAny-Bot/core/tftask"
 	"github.com/krau/SaveAny-Bot/database"
 	"github.com/krau/SaveAny-Bot/pkg/tcbdata"
+	"github.com/krau/SaveAny-Bot/pkg/tfile"
 	"github.com/krau/SaveAny-Bot/storage"
-	"github.com/rs/xid"
 )
 
 func Register(disp dispatcher.Dispatcher) {
-	disp.AddHandler(handlers.NewMessage(filters.Message.ChatType(filters.ChatTypeChannel), func(ctx *ext.Context, u *ext.Update) error {
-		return dispatcher.EndGroups
-	}))
-	disp.AddHandler(handlers.NewMessage(filters.Message.ChatType(filters.ChatTypeChat), func(ctx *ext.Context, u *ext.Update) error {
-		return dispatcher.EndGroups
-	}))
+	disp.AddHandler(handlers.NewMessage(notPrivateChat, handleGroupChatMessage))
 	disp.AddHandler(handlers.NewMessage(filters.Message.All, checkPermission))
 	disp.AddHandler(handlers.NewCommand("start", handleHelpCmd))
 	disp.AddHandler(handlers.NewCommand("help", handleHelpCmd))
 	disp.AddHandler(handlers.NewCommand("silent", handleSilentCmd))
 	disp.AddHandler(handlers.NewCommand("storage", handleStorageCmd))
+	disp.AddHandler(handlers.NewCommand("setdefault", handleSetDefaultCmd))
 	disp.AddHandler(handlers.NewCommand("dir", handleDirCmd))
 	disp.AddHandler(handlers.NewCommand("rule", handleRuleCmd))
+	disp.AddHandler(handlers.NewCommand("whichrule", handleWhichRuleCmd))
 	disp.AddHandler(handlers.NewCommand("watch", handleWatchCmd))
 	disp.AddHandler(handlers.NewCommand("unwatch", handleUnwatchCmd))
 	disp.AddHandler(handlers.NewCommand("save", handleSilentMode(handleSaveCmd, handleSilentSaveReplied)))
+	disp.AddHandler(handlers.NewCommand("reload", requireAdmin(handleReloadCmd)))
+	disp.AddHandler(handlers.NewCommand("cancelall", requireAdmin(handleCancelAllCmd)))
+	disp.AddHandler(handlers.NewCommand("rules", requireAdmin(handleRulesCmd)))
+	disp.AddHandler(handlers.NewCommand("ruleadd", requireAdmin(handleRuleAddCmd)))
+	disp.AddHandler(handlers.NewCommand("ruledel", requireAdmin(handleRuleDelCmd)))
+	disp.AddHandler(handlers.NewCommand("ruletoggle", requireAdmin(handleRuleToggleCmd)))
+	disp.AddHandler(handlers.NewCommand("ruletest", requireAdmin(handleRuleTestCmd)))
+	if !config.C().HasAdmin() {
+		log.Warn("No user has the admin role, admin commands (/reload, /cancelall, /rules...) can not be used")
+	}
 	disp.AddHandler(handlers.NewCallbackQuery(filters.CallbackQuery.Prefix(tcbdata.TypeAdd), handleAddCallback))
 	disp.AddHandler(handlers.NewCallbackQuery(filters.CallbackQuery.Prefix(tcbdata.TypeSetDefault), handleSetDefaultCallback))
+	disp.AddHandler(handlers.NewCallbackQuery(filters.CallbackQuery.Prefix(tcbdata.TypeChoose), handleChooseCallback))
 	disp.AddHandler(handlers.NewCallbackQuery(filters.CallbackQuery.Prefix("cancel"), handleCancelCallback))
 	linkRegexFilter, err := filters.Message.Regex(re.TgMessageLinkRegexString)
 	if err != nil {
@@ -55,7 +58,7 @@ func Register(disp dispatcher.Dispatcher) {
 	disp.AddHandler(handlers.NewMessage(telegraphUrlRegexFilter, handleSilentMode(handleTelegraphUrlMessage, handleSilentSaveTelegraph)))
 	disp.AddHandler(handlers.NewMessage(filters.Message.Media, handleSilentMode(handleMediaMessage, handleSilentSaveMedia)))
 
-	if config.Cfg.Telegram.Userbot.Enable {
+	if config.C().Telegram.Userbot.Enable {
 		go listenMediaMessageEvent(userclient.GetMediaMessageCh())
 	}
 }
@@ -98,39 +101,17 @@ func listenMediaMessageEvent(ch chan userclient.MediaMessageEvent) {
 				logger.Errorf("Failed to get user by ID %d: %v", chat.UserID, err)
 				continue
 			}
-			if user.DefaultStorage == "" {
+			storName, dirPath := watchDefault(user)
+			if storName == "" {
 				logger.Warnf("User %d has no default storage set, skipping media message handling", chat.UserID)
 				continue
 			}
-			stor, err := storage.GetStorageByUserIDAndName(ctx, user.ChatID, user.DefaultStorage)
-			if err != nil {
-				logger.Errorf("Failed to get storage by user ID %d and name %s: %v", user.ChatID, user.DefaultStorage, err)
-				continue
-			}
-			var dirPath string
-			if user.ApplyRule && user.Rules != nil {
-				matchedStorageName, matchedDirPath := ruleutil.ApplyRule(ctx, user.Rules, ruleutil.NewInput(file))
-				dirPath = matchedDirPath.String()
-				if matchedStorageName.IsUsable() {
-					stor, err = storage.GetStorageByUserIDAndName(ctx, user.ChatID, matchedStorageName.String())
-					if err != nil {
-						logger.Errorf("Failed to get storage by user ID and name: %s", err)
-						continue
-					}
-				}
-			}
-			storagePath := stor.JoinStoragePath(path.Join(dirPath, file.Name()))
-			injectCtx := tgutil.ExtWithContext(ctx.Context, ctx)
-			taskid := xid.New().String()
-			task, err := tftask.NewTGFileTask(taskid, injectCtx, file, stor, storagePath, nil)
+			stor, err := storage.GetStorageByUserIDAndName(ctx, user.ChatID, storName)
 			if err != nil {
-				logger.Errorf("create task failed: %s", err)
-				continue
-			}
-			if err := core.AddTask(injectCtx, task); err != nil {
-				logger.Errorf("add task failed: %s", err)
+				logger.Errorf("Failed to get storage by user ID %d and name %s: %v", user.ChatID, storName, err)
 				continue
 			}
+			shortcut.CreateAndAddDefaultTaskInChat(ctx, user.ChatID, user.ChatID, stor, dirPath, []tfile.TGFileMessage{file}, 0)
 			logger.Infof("Added media message task for user %d in chat %d: %s", chat.UserID, event.ChatID, file.Name())
 		}
 	}
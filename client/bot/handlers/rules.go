@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/celestix/gotgproto/dispatcher"
+	"github.com/celestix/gotgproto/ext"
+	"github.com/charmbracelet/log"
+	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/ruleutil"
+	"github.com/krau/SaveAny-Bot/config"
+	"github.com/krau/SaveAny-Bot/database"
+	"github.com/krau/SaveAny-Bot/pkg/ruleengine"
+)
+
+const ruleAddHelpText = `用法: /ruleadd <字段=值> ...
+字段与配置文件中的 [[rules]] 相同, 包含空格的值需要用引号括起来, 如:
+/ruleadd type=extension rule="epub, mobi" storage=WebDAV path=Books priority=1`
+
+// commandArgs returns the text after the command
+func commandArgs(update *ext.Update) string {
+	_, args, _ := strings.Cut(strings.TrimSpace(update.EffectiveMessage.Text), " ")
+	return strings.TrimSpace(args)
+}
+
+func handleRulesCmd(ctx *ext.Context, update *ext.Update) error {
+	botRules, err := database.GetBotRules(ctx)
+	if err != nil {
+		log.FromContext(ctx).Errorf("Failed to get rules: %s", err)
+		ctx.Reply(update, ext.ReplyTextString("获取规则失败: "+err.Error()), nil)
+		return dispatcher.EndGroups
+	}
+	engine := ruleutil.Engine(ctx)
+	if len(engine.Rules()) == 0 && len(botRules) == 0 {
+		ctx.Reply(update, ext.ReplyTextString("没有任何规则, 可以使用 /ruleadd 添加"), nil)
+		return dispatcher.EndGroups
+	}
+	var sb strings.Builder
+	sb.WriteString("规则列表 (按匹配顺序):\n")
+	var active []string
+	for _, r := range engine.Rules() {
+		active = append(active, r.Source)
+		fmt.Fprintf(&sb, "[%d] %s, 命中 %d 次\n", r.Priority, r, ruleutil.Hits(r))
+	}
+	cfg := config.C()
+	var disabled, invalid []string
+	for _, br := range botRules {
+		source := ruleutil.BotRuleSource(br.ID)
+		if slices.Contains(active, source) {
+			continue
+		}
+		desc := source
+		if r, err := ruleengine.NewRule(ruleutil.BotRuleDef(cfg, br)); err == nil {
+			desc = r.String()
+		}
+		if br.Enabled {
+			invalid = append(invalid, desc)
+		} else {
+			disabled = append(disabled, desc)
+		}
+	}
+	if len(disabled) > 0 {
+		sb.WriteString("\n已禁用:\n" + strings.Join(disabled, "\n") + "\n")
+	}
+	if len(invalid) > 0 {
+		sb.WriteString("\n无效, 已忽略:\n" + strings.Join(invalid, "\n") + "\n")
+	}
+	ctx.Reply(update, ext.ReplyTextString(strings.TrimSpace(sb.String())), nil)
+	return dispatcher.EndGroups
+}
+
+func handleRuleAddCmd(ctx *ext.Context, update *ext.Update) error {
+	args := commandArgs(update)
+	if args == "" {
+		ctx.Reply(update, ext.ReplyTextString(ruleAddHelpText), nil)
+		return dispatcher.EndGroups
+	}
+	def, err := ruleengine.ParseRule(args)
+	if err == nil {
+		err = ruleutil.CheckBotRule(ctx, def)
+	}
+	if err != nil {
+		ctx.Reply(update, ext.ReplyTextString("规则无效:\n"+err.Error()), nil)
+		return dispatcher.EndGroups
+	}
+	rule := ruleutil.NewBotRule(def, update.GetUserChat().GetID())
+	if err := database.CreateBotRule(ctx, &rule); err != nil {
+		log.FromContext(ctx).Errorf("Failed to create rule: %s", err)
+		ctx.Reply(update, ext.ReplyTextString("添加规则失败: "+err.Error()), nil)
+		return dispatcher.EndGroups
+	}
+	ruleutil.InvalidateEngine()
+	desc := ruleutil.BotRuleSource(rule.ID)
+	if r, err := ruleengine.NewRule(ruleutil.BotRuleDef(config.C(), rule)); err == nil {
+		desc = r.String()
+	}
+	ctx.Reply(update, ext.ReplyTextString("已添加规则: "+desc), nil)
+	return dispatcher.EndGroups
+}
+
+func handleRuleDelCmd(ctx *ext.Context, update *ext.Update) error {
+	rule, ok := botRuleFromArgs(ctx, update, "/ruledel")
+	if !ok {
+		return dispatcher.EndGroups
+	}
+	if err := checkNotReferenced(ctx, rule); err != nil {
+		ctx.Reply(update, ext.ReplyTextString(err.Error()), nil)
+		return dispatcher.EndGroups
+	}
+	if err := database.DeleteBotRule(ctx, rule.ID); err != nil {
+		log.FromContext(ctx).Errorf("Failed to delete rule: %s", err)
+		ctx.Reply(update, ext.ReplyTextString("删除规则失败: "+err.Error()), nil)
+		return dispatcher.EndGroups
+	}
+	ruleutil.InvalidateEngine()
+	ctx.Reply(update, ext.ReplyTextString("已删除规则 "+ruleutil.BotRuleSource(rule.ID)), nil)
+	return dispatcher.EndGroups
+}
+
+func handleRuleToggleCmd(ctx *ext.Context, update *ext.Update) error {
+	rule, ok := botRuleFromArgs(ctx, update, "/ruletoggle")
+	if !ok {
+		return dispatcher.EndGroups
+	}
+	if rule.Enabled {
+		if err := checkNotReferenced(ctx, rule); err != nil {
+			ctx.Reply(update, ext.ReplyTextString(err.Error()), nil)
+			return dispatcher.EndGroups
+		}
+	} else if err := ruleutil.CheckBotRule(ctx, ruleutil.BotRuleDef(config.C(), rule)); err != nil {
+		// the config may have changed since the rule was added
+		ctx.Reply(update, ext.ReplyTextString("规则无效, 无法启用:\n"+err.Error()), nil)
+		return dispatcher.EndGroups
+	}
+	enabled, err := database.ToggleBotRule(ctx, rule.ID)
+	if err != nil {
+		log.FromContext(ctx).Errorf("Failed to toggle rule: %s", err)
+		ctx.Reply(update, ext.ReplyTextString("切换规则状态失败: "+err.Error()), nil)
+		return dispatcher.EndGroups
+	}
+	ruleutil.InvalidateEngine()
+	state := "禁用"
+	if enabled {
+		state = "启用"
+	}
+	ctx.Reply(update, ext.ReplyTextString(fmt.Sprintf("已%s规则 %s", state, ruleutil.BotRuleSource(rule.ID))), nil)
+	return dispatcher.EndGroups
+}
+
+// botRuleFromArgs returns the rule in the database given by the argument of the command, like 3, #3 or db#3.
+// It replies the error to the user if there is no such rule.
+func botRuleFromArgs(ctx *ext.Context, update *ext.Update, cmd string) (database.BotRule, bool) {
+	arg := strings.TrimPrefix(strings.TrimPrefix(commandArgs(update), "db"), "#")
+	id, err := strconv.ParseUint(arg, 10, 64)
+	if err != nil || id == 0 {
+		ctx.Reply(update, ext.ReplyTextString(fmt.Sprintf("用法: %s <规则编号>\n编号可以通过 /rules 查看, 如 db#3, 只能操作通过 /ruleadd 添加的规则", cmd)), nil)
+		return database.BotRule{}, false
+	}
+	rules, err := database.GetBotRules(ctx)
+	if err != nil {
+		log.FromContext(ctx).Errorf("Failed to get rules: %s", err)
+		ctx.Reply(update, ext.ReplyTextString("获取规则失败: "+err.Error()), nil)
+		return database.BotRule{}, false
+	}
+	idx := slices.IndexFunc(rules, func(r database.BotRule) bool { return r.ID == uint(id) })
+	if idx < 0 {
+		ctx.Reply(update, ext.ReplyTextString(fmt.Sprintf("规则 %s 不存在", ruleutil.BotRuleSource(uint(id)))), nil)
+		return database.BotRule{}, false
+	}
+	return rules[idx], true
+}
+
+// checkNotReferenced returns an error if other enabled rules in the database reference the rule by its name.
+// Rules in the config can not reference rules in the database.
+func checkNotReferenced(ctx *ext.Context, rule database.BotRule) error {
+	if rule.Name == "" {
+		return nil
+	}
+	rules, err := database.GetBotRules(ctx)
+	if err != nil {
+		return fmt.Errorf("获取规则失败: %w", err)
+	}
+	var refs []string
+	cfg := config.C()
+	for _, r := range rules {
+		if r.ID == rule.ID || !r.Enabled {
+			continue
+		}
+		def := ruleutil.BotRuleDef(cfg, r)
+		if slices.Contains(def.AllOf, rule.Name) || slices.Contains(def.AnyOf, rule.Name) {
+			refs = append(refs, def.Source)
+		}
+	}
+	if len(refs) > 0 {
+		return fmt.Errorf("规则 %s 被以下规则引用, 请先删除或禁用它们: %s", ruleutil.BotRuleSource(rule.ID), strings.Join(refs, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/celestix/gotgproto/dispatcher"
+	"github.com/celestix/gotgproto/ext"
+	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/ruleutil"
+	"github.com/krau/SaveAny-Bot/config"
+	"github.com/krau/SaveAny-Bot/pkg/ruleengine"
+)
+
+const ruleTestHelpText = `用法: 回复一条包含文件的消息发送 /ruletest, 或描述一个文件:
+/ruletest name=foo.mkv size=700MB chat=-1001234567890
+可用字段: name, size, media, caption, chat, chat_title, sender, user (默认为你自己), date (如 2025-01-31)`
+
+// handleRuleTestCmd evaluates the rules for a file without saving it
+func handleRuleTestCmd(ctx *ext.Context, update *ext.Update) error {
+	var in ruleengine.Input
+	if args := commandArgs(update); args != "" {
+		var err error
+		if in, err = ruleengine.ParseInput(args); err != nil {
+			ctx.Reply(update, ext.ReplyTextString("文件描述无效: "+err.Error()+"\n\n"+ruleTestHelpText), nil)
+			return dispatcher.EndGroups
+		}
+		if in.UserID == 0 {
+			in.UserID = update.GetUserChat().GetID()
+		}
+	} else if update.EffectiveMessage.ReplyToMessage == nil {
+		ctx.Reply(update, ext.ReplyTextString(ruleTestHelpText), nil)
+		return dispatcher.EndGroups
+	} else {
+		var ok bool
+		if in, ok = repliedRuleInput(ctx, update); !ok {
+			return dispatcher.EndGroups
+		}
+	}
+	ctx.Reply(update, ext.ReplyTextString(traceRules(ctx, in)), nil)
+	return dispatcher.EndGroups
+}
+
+// traceRules evaluates the rules in order like a real save does, and describes each rule considered
+func traceRules(ctx *ext.Context, in ruleengine.Input) string {
+	var sb strings.Builder
+	describeInput(&sb, in)
+	fmt.Fprintf(&sb, "用户: %d\n\n", in.UserID)
+
+	cfg := config.C()
+	var fired *ruleengine.Rule
+	for _, r := range ruleutil.Engine(ctx).Rules() {
+		if !r.Routes() {
+			continue
+		}
+		matched := r.Match(in)
+		result := "不匹配"
+		switch {
+		case matched && !cfg.RuleAvailable(in.UserID, r):
+			result = fmt.Sprintf("匹配, 但用户无权使用存储 %s, 继续", r.Storage)
+		case matched:
+			result = "命中"
+			fired = r
+		}
+		fmt.Fprintf(&sb, "%s\n  %s: %s\n", r, strings.Join(r.Explain(in), ", "), result)
+		if fired != nil {
+			break
+		}
+	}
+
+	sb.WriteString("\n结果: ")
+	switch {
+	case fired == nil:
+		sb.WriteString("没有命中任何规则, 保存时将询问存储位置")
+	case fired.Skips():
+		fmt.Fprintf(&sb, "跳过 (%s)", fired.Source)
+	default:
+		dirPath, err := fired.DirPath(in)
+		if err != nil {
+			dirPath = err.Error()
+		}
+		fmt.Fprintf(&sb, "%s 到 %s:%s (%s)", fired.Action, fired.Storage, dirPath, fired.Source)
+	}
+	sb.WriteString("\n未保存任何文件")
+	return sb.String()
+}
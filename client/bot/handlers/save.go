@@ -47,6 +47,9 @@ func handleSaveCmd(ctx *ext.Context, update *ext.Update) error {
 		return err
 	}
 	userId := update.GetUserChat().GetID()
+	if ruled, rest := shortcut.MatchConfigRules(ctx, userId, []tfile.TGFileMessage{file}); len(rest) == 0 {
+		return shortcut.CreateAndAddRuledTaskWithEdit(ctx, userId, ruled, msg.ID)
+	}
 	stors := storage.GetUserStorages(ctx, userId)
 	req, err := msgelem.BuildAddOneSelectStorageMessage(ctx, stors, file, msg.ID)
 	if err != nil {
@@ -90,7 +93,7 @@ func handleSilentSaveReplied(ctx *ext.Context, update *ext.Update) error {
 	if err != nil {
 		return err
 	}
-	return shortcut.CreateAndAddTGFileTaskWithEdit(ctx, update.GetUserChat().GetID(), stor, "", file, msg.GetID())
+	return shortcut.CreateAndAddDefaultTaskWithEdit(ctx, update.GetUserChat().GetID(), stor, defaultDirFromContext(ctx), []tfile.TGFileMessage{file}, msg.GetID())
 }
 
 func handleBatchSave(ctx *ext.Context, update *ext.Update, args []string) error {
@@ -172,6 +175,10 @@ func handleBatchSave(ctx *ext.Context, update *ext.Update, args []string) error
 	stor := storage.FromContext(ctx)
 	if stor == nil {
 		// not in silent mode
+		ruled, files := shortcut.MatchConfigRules(ctx, update.GetUserChat().GetID(), files)
+		if len(files) == 0 {
+			return shortcut.CreateAndAddRuledTaskWithEdit(ctx, update.GetUserChat().GetID(), ruled, replied.ID)
+		}
 		stors := storage.GetUserStorages(ctx, update.GetUserChat().GetID())
 		markup, err := msgelem.BuildAddSelectStorageKeyboard(stors, tcbdata.Add{
 			Files: files,
@@ -191,5 +198,5 @@ func handleBatchSave(ctx *ext.Context, update *ext.Update, args []string) error
 		})
 		return dispatcher.EndGroups
 	}
-	return shortcut.CreateAndAddBatchTGFileTaskWithEdit(ctx, update.GetUserChat().GetID(), stor, "", files, replied.ID)
+	return shortcut.CreateAndAddDefaultTaskWithEdit(ctx, update.GetUserChat().GetID(), stor, defaultDirFromContext(ctx), files, replied.ID)
 }
@@ -1,13 +1,18 @@
 package handlers
 
 import (
+	"fmt"
+	"path"
 	"strings"
 
 	"github.com/celestix/gotgproto/dispatcher"
 	"github.com/celestix/gotgproto/ext"
+	"github.com/charmbracelet/log"
 	"github.com/gotd/td/tg"
 	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/msgelem"
+	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/shortcut"
 	"github.com/krau/SaveAny-Bot/common/cache"
+	"github.com/krau/SaveAny-Bot/core"
 	"github.com/krau/SaveAny-Bot/database"
 	"github.com/krau/SaveAny-Bot/pkg/tcbdata"
 	"github.com/krau/SaveAny-Bot/storage"
@@ -102,3 +107,80 @@ func handleStorageCmd(ctx *ext.Context, update *ext.Update) error {
 	})
 	return dispatcher.EndGroups
 }
+
+// /setdefault <storage> [path], /setdefault off
+func handleSetDefaultCmd(ctx *ext.Context, update *ext.Update) error {
+	userID := update.GetUserChat().GetID()
+	user, err := database.GetUserByChatID(ctx, userID)
+	if err != nil {
+		ctx.Reply(update, ext.ReplyTextString("获取用户信息失败: "+err.Error()), nil)
+		return dispatcher.EndGroups
+	}
+	args := strings.Fields(update.EffectiveMessage.Text)
+	if len(args) < 2 {
+		storName, dirPath := userDefault(user)
+		text := "当前未设置默认存储"
+		if storName != "" {
+			text = fmt.Sprintf("当前默认存储: %s, 默认路径: %s", storName, path.Join("/", dirPath))
+		}
+		ctx.Reply(update, ext.ReplyTextString(text+"\n\n用法:\n/setdefault <存储名> [路径] - 设置默认存储及路径, 保存时不再询问\n/setdefault off - 取消设置, 恢复使用配置文件中的默认值"), nil)
+		return dispatcher.EndGroups
+	}
+	if args[1] == "off" {
+		user.DefaultSaveStorage = ""
+		user.DefaultSavePath = ""
+		if err := database.UpdateUser(ctx, user); err != nil {
+			ctx.Reply(update, ext.ReplyTextString("更新用户信息失败: "+err.Error()), nil)
+			return dispatcher.EndGroups
+		}
+		ctx.Reply(update, ext.ReplyTextString("已取消默认存储设置"), nil)
+		return dispatcher.EndGroups
+	}
+	stor, err := storage.GetStorageByUserIDAndName(ctx, userID, args[1])
+	if err != nil {
+		ctx.Reply(update, ext.ReplyTextString(err.Error()), nil)
+		return dispatcher.EndGroups
+	}
+	dirPath := ""
+	if len(args) > 2 {
+		dirPath = strings.Join(args[2:], " ")
+	}
+	user.DefaultSaveStorage = stor.Name()
+	user.DefaultSavePath = dirPath
+	if err := database.UpdateUser(ctx, user); err != nil {
+		ctx.Reply(update, ext.ReplyTextString("更新用户信息失败: "+err.Error()), nil)
+		return dispatcher.EndGroups
+	}
+	ctx.Reply(update, ext.ReplyTextString(fmt.Sprintf("已将默认存储设置为: %s, 默认路径: %s", stor.Name(), path.Join("/", dirPath))), nil)
+	return dispatcher.EndGroups
+}
+
+func handleChooseCallback(ctx *ext.Context, update *ext.Update) error {
+	dataid := strings.Split(string(update.CallbackQuery.Data), " ")[1]
+	data, err := shortcut.GetCallbackDataWithAnswer[tcbdata.Choose](ctx, update, dataid)
+	if err != nil {
+		return err
+	}
+	queryID := update.CallbackQuery.GetQueryID()
+	userID := update.CallbackQuery.GetUserID()
+	if err := core.RemoveTask(ctx, data.TaskID); err != nil {
+		ctx.AnswerCallback(msgelem.AlertCallbackAnswer(queryID, "任务已开始, 无法更换存储"))
+		return dispatcher.EndGroups
+	}
+	stors := storage.GetUserStorages(ctx, userID)
+	markup, err := msgelem.BuildAddSelectStorageKeyboard(stors, tcbdata.Add{
+		Files:   data.Files,
+		AsBatch: len(data.Files) > 1,
+	})
+	if err != nil {
+		log.FromContext(ctx).Errorf("构建存储选择键盘失败: %s", err)
+		ctx.AnswerCallback(msgelem.AlertCallbackAnswer(queryID, "构建存储选择键盘失败: "+err.Error()))
+		return dispatcher.EndGroups
+	}
+	ctx.EditMessage(userID, &tg.MessagesEditMessageRequest{
+		ID:          update.CallbackQuery.GetMsgID(),
+		Message:     fmt.Sprintf("共 %d 个文件, 请选择存储位置", len(data.Files)),
+		ReplyMarkup: markup,
+	})
+	return dispatcher.EndGroups
+}
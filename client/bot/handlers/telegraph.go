@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"fmt"
+	"path"
 
 	"github.com/celestix/gotgproto/dispatcher"
 	"github.com/celestix/gotgproto/ext"
@@ -71,6 +72,6 @@ func handleSilentSaveTelegraph(ctx *ext.Context, update *ext.Update) error {
 		return err
 	}
 	userID := update.GetUserChat().GetID()
-	return shortcut.CreateAndAddTphTaskWithEdit(ctx, userID, result.Page, result.TphDir, result.Pics, stor, msg.ID)
+	return shortcut.CreateAndAddTphTaskWithEdit(ctx, userID, result.Page, path.Join(defaultDirFromContext(ctx), result.TphDir), result.Pics, stor, msg.ID)
 
 }
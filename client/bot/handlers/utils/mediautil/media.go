@@ -1,6 +1,9 @@
 package mediautil
 
-import "github.com/gotd/td/tg"
+import (
+	"github.com/gotd/td/tg"
+	"github.com/krau/SaveAny-Bot/pkg/ruleengine"
+)
 
 func IsSupported(media tg.MessageMediaClass) bool {
 	switch media.(type) {
@@ -10,3 +13,32 @@ func IsSupported(media tg.MessageMediaClass) bool {
 		return false
 	}
 }
+
+// MediaType returns the media type of the message media by its document attributes, e.g. ruleengine.MediaVideo
+func MediaType(media tg.MessageMediaClass) string {
+	switch m := media.(type) {
+	case *tg.MessageMediaPhoto:
+		return ruleengine.MediaPhoto
+	case *tg.MessageMediaDocument:
+		doc, ok := m.Document.AsNotEmpty()
+		if !ok {
+			return ruleengine.MediaDocument
+		}
+		mediaType := ruleengine.MediaDocument
+		for _, attr := range doc.Attributes {
+			switch a := attr.(type) {
+			case *tg.DocumentAttributeSticker:
+				return ruleengine.MediaSticker
+			case *tg.DocumentAttributeAudio:
+				if a.Voice {
+					return ruleengine.MediaVoice
+				}
+				mediaType = ruleengine.MediaAudio
+			case *tg.DocumentAttributeVideo:
+				mediaType = ruleengine.MediaVideo
+			}
+		}
+		return mediaType
+	}
+	return ""
+}
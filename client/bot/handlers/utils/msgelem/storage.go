@@ -115,6 +115,29 @@ func BuildSetDefaultStorageMarkup(ctx context.Context, userID int64, stors []sto
 	return markup, nil
 }
 
+// BuildChooseStorageMarkup builds the button to save the files of a queued task elsewhere
+func BuildChooseStorageMarkup(taskID string, files []tfile.TGFileMessage) (*tg.ReplyInlineMarkup, error) {
+	dataid := xid.New().String()
+	if err := cache.Set(dataid, tcbdata.Choose{
+		TaskID: taskID,
+		Files:  files,
+	}); err != nil {
+		return nil, err
+	}
+	return &tg.ReplyInlineMarkup{
+		Rows: []tg.KeyboardButtonRow{
+			{
+				Buttons: []tg.KeyboardButtonClass{
+					&tg.KeyboardButtonCallback{
+						Text: "选择其他存储",
+						Data: fmt.Appendf(nil, "%s %s", tcbdata.TypeChoose, dataid),
+					},
+				},
+			},
+		},
+	}, nil
+}
+
 func BuildSetDirKeyboard(dirs []database.Dir, dataid string) (*tg.ReplyInlineMarkup, error) {
 	data, ok := cache.Get[tcbdata.Add](dataid)
 	if !ok {
@@ -0,0 +1,208 @@
+package ruleutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/charmbracelet/log"
+	"github.com/krau/SaveAny-Bot/config"
+	"github.com/krau/SaveAny-Bot/database"
+	"github.com/krau/SaveAny-Bot/pkg/ruleengine"
+)
+
+// ruleSet is the engine of the rules in the config and the enabled rules in the database
+type ruleSet struct {
+	cfg    *config.Config
+	engine *ruleengine.Engine
+}
+
+var (
+	current atomic.Pointer[ruleSet]
+	buildMu sync.Mutex
+	hits    sync.Map // rule source -> *atomic.Int64
+)
+
+// BotRuleSource returns the source of a rule added with /ruleadd, e.g. db#3
+func BotRuleSource(id uint) string {
+	return fmt.Sprintf("db#%d", id)
+}
+
+// BotRuleDef converts a rule in the database to the definition of the engine,
+// its priority is moved into the band set by db_rule_priority
+func BotRuleDef(cfg *config.Config, r database.BotRule) ruleengine.Rule {
+	return ruleengine.Rule{
+		Name:         r.Name,
+		Type:         r.Type,
+		Value:        r.Value,
+		AllOf:        splitNames(r.AllOf),
+		AnyOf:        splitNames(r.AnyOf),
+		Storage:      r.Storage,
+		Path:         r.Path,
+		PathTemplate: r.PathTemplate,
+		Action:       r.Action,
+		Silent:       r.Silent,
+		Priority:     cfg.DBRulePriority + r.Priority,
+		UserID:       r.UserID,
+		Source:       BotRuleSource(r.ID),
+	}
+}
+
+// NewBotRule converts a parsed rule to be saved in the database
+func NewBotRule(def ruleengine.Rule, createdBy int64) database.BotRule {
+	return database.BotRule{
+		Name:         def.Name,
+		Type:         def.Type,
+		Value:        def.Value,
+		AllOf:        strings.Join(def.AllOf, ","),
+		AnyOf:        strings.Join(def.AnyOf, ","),
+		Storage:      def.Storage,
+		Path:         def.Path,
+		PathTemplate: def.PathTemplate,
+		Action:       def.Action,
+		Silent:       def.Silent,
+		Priority:     def.Priority,
+		UserID:       def.UserID,
+		Enabled:      true,
+		CreatedBy:    createdBy,
+	}
+}
+
+func splitNames(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// Engine returns the engine of the rules in the config and the enabled rules in the database.
+// It is rebuilt when the config is reloaded or InvalidateEngine is called.
+func Engine(ctx context.Context) *ruleengine.Engine {
+	cfg := config.C()
+	if set := current.Load(); set != nil && set.cfg == cfg {
+		return set.engine
+	}
+	buildMu.Lock()
+	defer buildMu.Unlock()
+	set := current.Load()
+	if set != nil && set.cfg == cfg {
+		return set.engine
+	}
+	if set != nil {
+		// the indexes of the rules in the config may have changed
+		hits.Range(func(key, _ any) bool {
+			if strings.HasPrefix(key.(string), "rules[") {
+				hits.Delete(key)
+			}
+			return true
+		})
+	}
+	logger := log.FromContext(ctx)
+	botRules, err := database.GetBotRules(ctx)
+	if err != nil {
+		logger.Errorf("Failed to get rules from database: %s", err)
+	}
+	engine, err := buildEngine(cfg, botRules)
+	if err != nil {
+		logger.Warnf("Some rules are invalid and ignored:\n%s", err)
+	}
+	current.Store(&ruleSet{cfg: cfg, engine: engine})
+	return engine
+}
+
+// InvalidateEngine makes the next call of Engine read the rules in the database again
+func InvalidateEngine() {
+	buildMu.Lock()
+	defer buildMu.Unlock()
+	current.Store(nil)
+}
+
+func buildEngine(cfg *config.Config, botRules []database.BotRule) (*ruleengine.Engine, error) {
+	var errs []error
+	defs := cfg.RuleDefs()
+	for _, br := range botRules {
+		if br.Enabled {
+			defs = append(defs, BotRuleDef(cfg, br))
+		}
+	}
+	rules := make([]*ruleengine.Rule, 0, len(defs))
+	for _, def := range defs {
+		r, err := ruleengine.NewRule(def)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s.%w", def.Source, err))
+			continue
+		}
+		rules = append(rules, r)
+	}
+	engine, err := ruleengine.New(rules)
+	return engine, errors.Join(append(errs, err)...)
+}
+
+// CheckBotRule reports why a new rule can not be added, together with the rules that already exist
+func CheckBotRule(ctx context.Context, def ruleengine.Rule) error {
+	cfg := config.C()
+	if _, err := ruleengine.NewRule(def); err != nil {
+		return err
+	}
+	if errs := cfg.CheckRule(def); len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	botRules, err := database.GetBotRules(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get rules from database: %w", err)
+	}
+	_, before := buildEngine(cfg, botRules)
+	newRule := NewBotRule(def, 0)
+	_, after := buildEngine(cfg, append(botRules, newRule))
+	if after == nil {
+		return nil
+	}
+	// only report the problems caused by the new rule
+	var known []string
+	if before != nil {
+		known = strings.Split(before.Error(), "\n")
+	}
+	var errs []error
+	for _, line := range strings.Split(after.Error(), "\n") {
+		if !slices.Contains(known, line) {
+			errs = append(errs, errors.New(strings.ReplaceAll(line, BotRuleSource(0), "new rule")))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// MatchRule returns the first rule matching the file that is available to the user, and counts the hit
+func MatchRule(ctx context.Context, in ruleengine.Input) (*ruleengine.Rule, bool) {
+	cfg := config.C()
+	r, ok := Engine(ctx).Match(in, func(r *ruleengine.Rule) bool {
+		return cfg.RuleAvailable(in.UserID, r)
+	})
+	if ok {
+		counter, _ := hits.LoadOrStore(r.Source, new(atomic.Int64))
+		counter.(*atomic.Int64).Add(1)
+	}
+	return r, ok
+}
+
+// MatchingRules returns all rules matching the file in evaluation order, regardless of the storages of the user
+func MatchingRules(ctx context.Context, in ruleengine.Input) []*ruleengine.Rule {
+	var matched []*ruleengine.Rule
+	for _, r := range Engine(ctx).Rules() {
+		if r.Routes() && r.Match(in) {
+			matched = append(matched, r)
+		}
+	}
+	return matched
+}
+
+// Hits returns how many files matched the rule since the bot started or the config was reloaded
+func Hits(r *ruleengine.Rule) int64 {
+	if counter, ok := hits.Load(r.Source); ok {
+		return counter.(*atomic.Int64).Load()
+	}
+	return 0
+}
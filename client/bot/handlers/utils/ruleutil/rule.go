@@ -2,14 +2,21 @@ package ruleutil
 
 import (
 	"context"
+	"time"
+
+	"github.com/celestix/gotgproto/ext"
+	"github.com/gotd/td/tg"
 
 	"github.com/duke-git/lancet/v2/convertor"
 
 	"github.com/charmbracelet/log"
+	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/mediautil"
+	"github.com/krau/SaveAny-Bot/common/utils/tgutil"
 	"github.com/krau/SaveAny-Bot/database"
 	"github.com/krau/SaveAny-Bot/pkg/consts"
 	ruleenum "github.com/krau/SaveAny-Bot/pkg/enums/rule"
 	"github.com/krau/SaveAny-Bot/pkg/rule"
+	"github.com/krau/SaveAny-Bot/pkg/ruleengine"
 	"github.com/krau/SaveAny-Bot/pkg/tfile"
 )
 
@@ -110,3 +117,73 @@ func ApplyRule(ctx context.Context, rules []database.Rule, inputs *ruleInput) (m
 	}
 	return
 }
+
+// NewEngineInput returns the input of the rules in the config for a file saved by the user
+func NewEngineInput(ctx *ext.Context, userID int64, file tfile.TGFileMessage) ruleengine.Input {
+	in := ruleengine.Input{
+		UserID:   userID,
+		FileName: file.Name(),
+		Size:     file.Size(),
+	}
+	msg := file.Message()
+	if msg == nil {
+		return in
+	}
+	if media, ok := msg.GetMedia(); ok {
+		in.MediaType = mediautil.MediaType(media)
+	}
+	in.Caption = msg.GetMessage()
+	in.Date = time.Unix(int64(msg.GetDate()), 0)
+	in.ChatID, in.SenderID = messageOrigin(msg)
+	if in.ChatID != 0 && in.ChatID != in.SenderID {
+		title, err := tgutil.GetChatTitle(ctx, in.ChatID)
+		if err != nil {
+			log.FromContext(ctx).Debugf("Failed to get title of chat %d: %s", in.ChatID, err)
+		}
+		in.ChatTitle = title
+	}
+	return in
+}
+
+// messageOrigin returns the chat and the sender the message comes from, the forward header is preferred.
+// For private chats the chat is the user.
+func messageOrigin(msg *tg.Message) (chatID, senderID int64) {
+	if fwd, ok := msg.GetFwdFrom(); ok {
+		if from, ok := fwd.GetFromID(); ok {
+			switch peer := from.(type) {
+			case *tg.PeerUser:
+				return peer.UserID, peer.UserID
+			case *tg.PeerChannel:
+				return peer.ChannelID, 0
+			case *tg.PeerChat:
+				return peer.ChatID, 0
+			}
+		}
+		// the sender hides the account, only the chat of a saved message can be known
+		if saved, ok := fwd.GetSavedFromPeer(); ok {
+			return peerID(saved), 0
+		}
+		return 0, 0
+	}
+	chatID = peerID(msg.PeerID)
+	if from, ok := msg.GetFromID(); ok {
+		if user, ok := from.(*tg.PeerUser); ok {
+			senderID = user.UserID
+		}
+	} else if user, ok := msg.PeerID.(*tg.PeerUser); ok {
+		senderID = user.UserID
+	}
+	return chatID, senderID
+}
+
+func peerID(peer tg.PeerClass) int64 {
+	switch p := peer.(type) {
+	case *tg.PeerUser:
+		return p.UserID
+	case *tg.PeerChat:
+		return p.ChatID
+	case *tg.PeerChannel:
+		return p.ChannelID
+	}
+	return 0
+}
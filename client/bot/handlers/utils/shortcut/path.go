@@ -0,0 +1,26 @@
+package shortcut
+
+import (
+	"path"
+
+	"github.com/celestix/gotgproto/ext"
+	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/ruleutil"
+	"github.com/krau/SaveAny-Bot/config"
+	"github.com/krau/SaveAny-Bot/pkg/ruleengine"
+	"github.com/krau/SaveAny-Bot/pkg/tfile"
+	"github.com/krau/SaveAny-Bot/storage"
+)
+
+// StoragePath returns the path the file is saved to in the storage: dirPath, then the directories
+// rendered from the path template of the storage if it has one, then name
+func StoragePath(ctx *ext.Context, userID int64, stor storage.Storage, dirPath string, file tfile.TGFileMessage, name string) (string, error) {
+	cfg := config.C().GetStorageByName(stor.Name())
+	if cfg == nil || !cfg.HasPathTemplate() {
+		return stor.JoinStoragePath(path.Join(dirPath, name)), nil
+	}
+	tmplPath, err := cfg.RenderPath(ruleengine.NewPathData(ruleutil.NewEngineInput(ctx, userID, file)))
+	if err != nil {
+		return "", err
+	}
+	return stor.JoinStoragePath(path.Join(dirPath, tmplPath, name)), nil
+}
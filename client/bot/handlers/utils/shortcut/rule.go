@@ -0,0 +1,291 @@
+package shortcut
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/celestix/gotgproto/dispatcher"
+	"github.com/celestix/gotgproto/ext"
+	"github.com/charmbracelet/log"
+	"github.com/gotd/td/tg"
+	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/ruleutil"
+	"github.com/krau/SaveAny-Bot/common/utils/tgutil"
+	"github.com/krau/SaveAny-Bot/core/batchtftask"
+	"github.com/krau/SaveAny-Bot/pkg/ruleengine"
+	"github.com/krau/SaveAny-Bot/pkg/tfile"
+	"github.com/krau/SaveAny-Bot/storage"
+	"github.com/rs/xid"
+)
+
+// RuledFile is a file routed by the rules in the config
+type RuledFile struct {
+	File    tfile.TGFileMessage
+	Storage storage.Storage // nil if the file is skipped
+	DirPath string
+	Rule    *ruleengine.Rule
+}
+
+// MatchConfigRules routes the files by the rules in the config.
+// Files matching a skip rule are dropped. If any of the other files matches no rule,
+// the user should choose the storage for all of them: they are returned in rest,
+// and the user is notified about the skipped files right away.
+func MatchConfigRules(ctx *ext.Context, userID int64, files []tfile.TGFileMessage) (ruled []RuledFile, rest []tfile.TGFileMessage) {
+	logger := log.FromContext(ctx)
+	var skipped, routed []RuledFile
+	allRouted := true
+	for _, file := range files {
+		in := ruleutil.NewEngineInput(ctx, userID, file)
+		r, matched := ruleutil.MatchRule(ctx, in)
+		if matched && r.Skips() {
+			logger.Debugf("File %s skipped by %s", file.Name(), r)
+			skipped = append(skipped, RuledFile{File: file, Rule: r})
+			continue
+		}
+		rest = append(rest, file)
+		if !matched || !allRouted {
+			allRouted = false
+			continue
+		}
+		rf, err := routeFile(ctx, userID, file, in, r)
+		if err != nil {
+			logger.Errorf("Failed to route file %s by %s: %s", file.Name(), r, err)
+			allRouted = false
+			continue
+		}
+		routed = append(routed, rf)
+	}
+	if allRouted {
+		return append(routed, skipped...), nil
+	}
+	notifySkipped(ctx, userID, skipped, 0)
+	return nil, rest
+}
+
+// routeConfigRules routes each file by the rules in the config on its own.
+// Files matching a skip rule are returned with a nil storage, files matching no rule in rest.
+func routeConfigRules(ctx *ext.Context, userID int64, files []tfile.TGFileMessage) (ruled []RuledFile, rest []tfile.TGFileMessage) {
+	logger := log.FromContext(ctx)
+	for _, file := range files {
+		in := ruleutil.NewEngineInput(ctx, userID, file)
+		r, matched := ruleutil.MatchRule(ctx, in)
+		if !matched {
+			rest = append(rest, file)
+			continue
+		}
+		if r.Skips() {
+			logger.Debugf("File %s skipped by %s", file.Name(), r)
+			ruled = append(ruled, RuledFile{File: file, Rule: r})
+			continue
+		}
+		rf, err := routeFile(ctx, userID, file, in, r)
+		if err != nil {
+			logger.Errorf("Failed to route file %s by %s: %s", file.Name(), r, err)
+			rest = append(rest, file)
+			continue
+		}
+		ruled = append(ruled, rf)
+	}
+	return ruled, rest
+}
+
+func routeFile(ctx *ext.Context, userID int64, file tfile.TGFileMessage, in ruleengine.Input, r *ruleengine.Rule) (RuledFile, error) {
+	stor, err := storage.GetStorageByUserIDAndName(ctx, userID, r.Storage)
+	if err != nil {
+		return RuledFile{}, fmt.Errorf("failed to get storage: %w", err)
+	}
+	dirPath, err := r.DirPath(in)
+	if err != nil {
+		return RuledFile{}, err
+	}
+	if r.Action == ruleengine.ActionKeepName {
+		if file, err = withOriginalName(ctx, file); err != nil {
+			return RuledFile{}, err
+		}
+	}
+	log.FromContext(ctx).Debugf("File %s matched %s", file.Name(), r)
+	return RuledFile{File: file, Storage: stor, DirPath: dirPath, Rule: r}, nil
+}
+
+// withOriginalName returns the file with the name it has in telegram, dropping a custom name
+func withOriginalName(ctx *ext.Context, file tfile.TGFileMessage) (tfile.TGFileMessage, error) {
+	msg := file.Message()
+	if msg == nil || msg.Media == nil {
+		return file, nil
+	}
+	return tfile.FromMediaMessage(msg.Media, ctx.Raw, msg,
+		tfile.WithNameIfEmpty(tgutil.GenFileNameFromMessage(*msg)))
+}
+
+// notifySkipped tells which files were skipped by the rules in chatID, by editing trackMsgID or in a new message if it is 0.
+// Files of silent rules are left out. It reports whether a message was sent.
+func notifySkipped(ctx *ext.Context, chatID int64, skipped []RuledFile, trackMsgID int) bool {
+	var sb strings.Builder
+	count := 0
+	for _, rf := range skipped {
+		if rf.Rule.Silent {
+			continue
+		}
+		count++
+		fmt.Fprintf(&sb, "\n- %s (%s)", rf.File.Name(), rf.Rule.Source)
+	}
+	if count == 0 {
+		return false
+	}
+	text := fmt.Sprintf("已按规则跳过 %d 个文件:", count) + sb.String()
+	if trackMsgID == 0 {
+		ctx.SendMessage(chatID, &tg.MessagesSendMessageRequest{Message: text})
+	} else {
+		ctx.EditMessage(chatID, &tg.MessagesEditMessageRequest{ID: trackMsgID, Message: text})
+	}
+	return true
+}
+
+// 直接保存文件, 不询问存储位置. 文件先按配置中的规则路由, 未匹配任何规则的文件再按用户通过 /rule 设置的规则
+// 保存, 其余文件保存到 stor 的 dirPath 下. 以编辑消息的方式反馈结果
+func CreateAndAddDefaultTaskWithEdit(ctx *ext.Context, userID int64, stor storage.Storage, dirPath string, files []tfile.TGFileMessage, trackMsgID int) error {
+	return CreateAndAddDefaultTaskInChat(ctx, userID, userID, stor, dirPath, files, trackMsgID)
+}
+
+// 同 CreateAndAddDefaultTaskWithEdit, 但在 chatID 中反馈结果, 任务属于 userID. trackMsgID 为 0 时不反馈
+func CreateAndAddDefaultTaskInChat(ctx *ext.Context, chatID, userID int64, stor storage.Storage, dirPath string, files []tfile.TGFileMessage, trackMsgID int) error {
+	return addDefaultTask(ctx, chatID, userID, stor, dirPath, files, trackMsgID, true)
+}
+
+// 同 CreateAndAddDefaultTaskInChat, 但不应用 userID 通过 /rule 设置的规则, 用于群组中发送的文件:
+// 发送者只用于检查存储权限, 未匹配配置中规则的文件总是保存到群组的存储
+func CreateAndAddGroupTaskInChat(ctx *ext.Context, chatID, userID int64, stor storage.Storage, dirPath string, files []tfile.TGFileMessage, trackMsgID int) error {
+	return addDefaultTask(ctx, chatID, userID, stor, dirPath, files, trackMsgID, false)
+}
+
+func addDefaultTask(ctx *ext.Context, chatID, userID int64, stor storage.Storage, dirPath string, files []tfile.TGFileMessage, trackMsgID int, userRules bool) error {
+	ruled, rest := routeConfigRules(ctx, userID, files)
+	if len(ruled) > 0 {
+		ruledMsgID := trackMsgID
+		if len(rest) > 0 && trackMsgID != 0 {
+			// the files saved to stor keep the tracking message, as their storage can still be changed
+			ruledMsgID = 0
+			msg, err := ctx.SendMessage(chatID, &tg.MessagesSendMessageRequest{Message: "正在保存文件..."})
+			if err != nil {
+				log.FromContext(ctx).Errorf("Failed to send message: %s", err)
+			} else {
+				ruledMsgID = msg.ID
+			}
+		}
+		CreateAndAddRuledTaskInChat(ctx, chatID, userID, ruled, ruledMsgID)
+	}
+	switch {
+	case len(rest) == 0:
+		return dispatcher.EndGroups
+	case !userRules && len(rest) == 1:
+		return addTGFileTask(ctx, chatID, userID, stor, dirPath, rest[0], trackMsgID)
+	case !userRules:
+		return addBatchTGFileTask(ctx, chatID, userID, stor, dirPath, rest, nil, trackMsgID)
+	case len(rest) == 1:
+		return CreateAndAddTGFileTaskInChat(ctx, chatID, userID, stor, dirPath, rest[0], trackMsgID)
+	}
+	return CreateAndAddBatchTGFileTaskInChat(ctx, chatID, userID, stor, dirPath, rest, trackMsgID)
+}
+
+type ruledKey struct{}
+
+// savedByDefault reports whether the files are saved to the storage in the context without asking,
+// so that another storage can still be chosen. It is false for the files routed by the rules.
+func savedByDefault(ctx context.Context) bool {
+	return storage.FromContext(ctx) != nil && ctx.Value(ruledKey{}) == nil
+}
+
+// 将按规则匹配的文件添加到任务队列中, 以编辑消息的方式反馈结果
+func CreateAndAddRuledTaskWithEdit(ctx *ext.Context, userID int64, ruled []RuledFile, trackMsgID int) error {
+	return CreateAndAddRuledTaskInChat(ctx, userID, userID, ruled, trackMsgID)
+}
+
+// 同 CreateAndAddRuledTaskWithEdit, 但在 chatID 中反馈结果, 任务属于 userID. trackMsgID 为 0 时不反馈.
+// 配置中的规则优先于用户通过 /rule 设置的规则, 后者不会再改变这些文件的存储和路径
+func CreateAndAddRuledTaskInChat(ctx *ext.Context, chatID, userID int64, ruled []RuledFile, trackMsgID int) error {
+	rctx := *ctx
+	rctx.Context = context.WithValue(ctx.Context, ruledKey{}, true)
+	ctx = &rctx
+	edit := editInChat(ctx, chatID, trackMsgID)
+	var skipped []RuledFile
+	ruled = slices.DeleteFunc(slices.Clone(ruled), func(rf RuledFile) bool {
+		if rf.Storage == nil {
+			skipped = append(skipped, rf)
+			return true
+		}
+		return false
+	})
+	if trackMsgID != 0 {
+		if len(ruled) > 0 {
+			notifySkipped(ctx, chatID, skipped, 0)
+		} else if !notifySkipped(ctx, chatID, skipped, trackMsgID) {
+			ctx.DeleteMessages(chatID, []int{trackMsgID})
+		}
+	}
+	switch len(ruled) {
+	case 0:
+		return dispatcher.EndGroups
+	case 1:
+		return addTGFileTask(ctx, chatID, userID, ruled[0].Storage, ruled[0].DirPath, ruled[0].File, trackMsgID)
+	}
+	logger := log.FromContext(ctx)
+	elems := make([]batchtftask.TaskElement, 0, len(ruled))
+	var tooLarge []string
+	for _, rf := range ruled {
+		storPath, err := StoragePath(ctx, userID, rf.Storage, rf.DirPath, rf.File, rf.File.Name())
+		if err != nil {
+			logger.Errorf("Failed to render storage path: %s", err)
+			edit(&tg.MessagesEditMessageRequest{
+				ID:      trackMsgID,
+				Message: "生成存储路径失败: " + err.Error(),
+			})
+			return dispatcher.EndGroups
+		}
+		elem, err := batchtftask.NewTaskElement(rf.Storage, storPath, rf.File)
+		if errors.Is(err, storage.ErrFileTooLarge) {
+			tooLarge = append(tooLarge, fmt.Sprintf("%s: %s", rf.File.Name(), err))
+			continue
+		}
+		if err != nil {
+			logger.Errorf("Failed to create task element: %s", err)
+			edit(&tg.MessagesEditMessageRequest{
+				ID:      trackMsgID,
+				Message: "任务创建失败: " + err.Error(),
+			})
+			return dispatcher.EndGroups
+		}
+		elems = append(elems, *elem)
+	}
+	if len(elems) == 0 {
+		edit(&tg.MessagesEditMessageRequest{
+			ID:      trackMsgID,
+			Message: tooLargeText(tooLarge),
+		})
+		return dispatcher.EndGroups
+	}
+	injectCtx := tgutil.ExtWithContext(ctx.Context, ctx)
+	var progress batchtftask.ProgressTracker
+	if trackMsgID != 0 {
+		progress = batchtftask.NewProgressTracker(trackMsgID, chatID)
+	}
+	task := batchtftask.NewBatchTGFileTask(xid.New().String(), injectCtx, elems, progress, true)
+	if err := addTask(ctx, injectCtx, userID, task); err != nil {
+		logger.Errorf("Failed to add batch task: %s", err)
+		edit(&tg.MessagesEditMessageRequest{
+			ID:      trackMsgID,
+			Message: "批量任务添加失败: " + err.Error(),
+		})
+		return dispatcher.EndGroups
+	}
+	text := fmt.Sprintf("已按规则添加批量任务, 共 %d 个文件", len(elems))
+	if len(tooLarge) > 0 {
+		text += "\n\n" + tooLargeText(tooLarge)
+	}
+	edit(&tg.MessagesEditMessageRequest{
+		ID:      trackMsgID,
+		Message: text,
+	})
+	return dispatcher.EndGroups
+}
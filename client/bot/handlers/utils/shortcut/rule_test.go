@@ -0,0 +1,215 @@
+package shortcut
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/celestix/gotgproto/ext"
+	"github.com/gotd/td/telegram/downloader"
+	"github.com/gotd/td/tg"
+	"github.com/krau/SaveAny-Bot/config"
+	"github.com/krau/SaveAny-Bot/core"
+	"github.com/krau/SaveAny-Bot/core/batchtftask"
+	"github.com/krau/SaveAny-Bot/core/tftask"
+	"github.com/krau/SaveAny-Bot/database"
+	"github.com/krau/SaveAny-Bot/pkg/tfile"
+	"github.com/krau/SaveAny-Bot/storage"
+)
+
+const testUserID = 1001
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "shortcut")
+	if err != nil {
+		panic(err)
+	}
+	code := func() int {
+		defer os.RemoveAll(dir)
+		configFile := filepath.Join(dir, "config.toml")
+		toml := fmt.Sprintf(`
+workers = 1
+
+[telegram]
+token = "123:abc"
+
+[db]
+path = %[1]q
+session = %[2]q
+
+[temp]
+base_path = %[3]q
+
+[[storages]]
+name = "ruled"
+type = "local"
+enable = true
+base_path = %[4]q
+
+[[storages]]
+name = "legacy"
+type = "local"
+enable = true
+base_path = %[5]q
+
+[[storages]]
+name = "default"
+type = "local"
+enable = true
+base_path = %[6]q
+
+[[users]]
+id = %[7]d
+storages = []
+blacklist = true
+
+[[rules]]
+type = "extension"
+rule = "mp4"
+storage = "ruled"
+path = "videos"
+`, filepath.Join(dir, "data", "saveany.db"), filepath.Join(dir, "data", "session.db"), filepath.Join(dir, "cache"),
+			filepath.Join(dir, "ruled"), filepath.Join(dir, "legacy"), filepath.Join(dir, "default"), testUserID)
+		if err := os.WriteFile(configFile, []byte(toml), 0o644); err != nil {
+			panic(err)
+		}
+		ctx := context.Background()
+		config.SetConfigFile(configFile)
+		if err := config.Init(ctx); err != nil {
+			panic(err)
+		}
+		database.Init(ctx)
+		storage.LoadStorages(ctx)
+		return m.Run()
+	}()
+	os.Exit(code)
+}
+
+type testFile struct {
+	name string
+	msg  *tg.Message
+}
+
+func (f *testFile) Location() tg.InputFileLocationClass { return &tg.InputDocumentFileLocation{} }
+func (f *testFile) Dler() downloader.Client             { return nil }
+func (f *testFile) Size() int64                         { return 1024 }
+func (f *testFile) Name() string                        { return f.name }
+func (f *testFile) Message() *tg.Message                { return f.msg }
+
+// newAlbum returns files sent together as an album
+func newAlbum(names ...string) []tfile.TGFileMessage {
+	files := make([]tfile.TGFileMessage, 0, len(names))
+	for i, name := range names {
+		msg := &tg.Message{ID: i + 1, PeerID: &tg.PeerUser{UserID: testUserID}}
+		msg.SetGroupedID(42)
+		files = append(files, &testFile{name: name, msg: msg})
+	}
+	return files
+}
+
+// captureTasks replaces addTask for the test and returns where each queued file would be saved, as storage:path
+func captureTasks(t *testing.T) map[string]string {
+	t.Helper()
+	saved := addTask
+	got := make(map[string]string)
+	addTask = func(ctx *ext.Context, injectCtx context.Context, userID int64, task core.Exectable) error {
+		switch task := task.(type) {
+		case *tftask.Task:
+			got[task.FileName()] = task.StorageName() + ":" + task.StoragePath()
+		case *batchtftask.Task:
+			for _, elem := range task.Elems {
+				got[elem.File.Name()] = elem.Storage.Name() + ":" + elem.Path
+			}
+		default:
+			t.Fatalf("unexpected task %T", task)
+		}
+		return nil
+	}
+	t.Cleanup(func() { addTask = saved })
+	return got
+}
+
+// applyUserRule turns on apply_rule for the test user with a /rule rule sending every file to the legacy storage
+func applyUserRule(t *testing.T) {
+	t.Helper()
+	ctx := context.Background()
+	user, err := database.GetUserByChatID(ctx, testUserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rule := &database.Rule{UserID: user.ID, Type: "FILENAME-REGEX", Data: ".*", StorageName: "legacy", DirPath: "mine"}
+	if err := database.CreateRule(ctx, rule); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.UpdateUserApplyRule(ctx, testUserID, true); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		database.DeleteRule(ctx, rule.ID)
+		database.UpdateUserApplyRule(ctx, testUserID, false)
+	})
+}
+
+func storagePath(t *testing.T, name, p string) string {
+	t.Helper()
+	stor, err := storage.GetStorageByUserIDAndName(context.Background(), testUserID, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return name + ":" + stor.JoinStoragePath(p)
+}
+
+func TestDefaultTaskConfigRulesFirst(t *testing.T) {
+	applyUserRule(t)
+	stor, err := storage.GetStorageByUserIDAndName(context.Background(), testUserID, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := &ext.Context{Context: context.Background()}
+	ruled := storagePath(t, "ruled", "videos/clip.mp4")
+
+	t.Run("single file", func(t *testing.T) {
+		got := captureTasks(t)
+		CreateAndAddDefaultTaskInChat(ctx, testUserID, testUserID, stor, "", newAlbum("clip.mp4")[:1], 0)
+		if got["clip.mp4"] != ruled {
+			t.Errorf("clip.mp4 saved to %q, want %q", got["clip.mp4"], ruled)
+		}
+	})
+
+	t.Run("album", func(t *testing.T) {
+		got := captureTasks(t)
+		CreateAndAddDefaultTaskInChat(ctx, testUserID, testUserID, stor, "", newAlbum("clip.mp4", "intro.mp4"), 0)
+		if got["clip.mp4"] != ruled {
+			t.Errorf("clip.mp4 saved to %q, want %q", got["clip.mp4"], ruled)
+		}
+		if want := storagePath(t, "ruled", "videos/intro.mp4"); got["intro.mp4"] != want {
+			t.Errorf("intro.mp4 saved to %q, want %q", got["intro.mp4"], want)
+		}
+	})
+
+	t.Run("no config rule", func(t *testing.T) {
+		// the /rule rules of the user apply to the files no rule in the config matches
+		got := captureTasks(t)
+		CreateAndAddDefaultTaskInChat(ctx, testUserID, testUserID, stor, "", newAlbum("notes.txt")[:1], 0)
+		if want := storagePath(t, "legacy", "mine/notes.txt"); got["notes.txt"] != want {
+			t.Errorf("notes.txt saved to %q, want %q", got["notes.txt"], want)
+		}
+	})
+
+	t.Run("group", func(t *testing.T) {
+		// files sent to a group are not sent elsewhere by the /rule rules of the sender
+		got := captureTasks(t)
+		CreateAndAddGroupTaskInChat(ctx, -1001234567890, testUserID, stor, "group", newAlbum("notes.txt", "clip.mp4", "readme.md"), 0)
+		for name, want := range map[string]string{
+			"notes.txt": storagePath(t, "default", "group/notes.txt"),
+			"readme.md": storagePath(t, "default", "group/readme.md"),
+			"clip.mp4":  ruled,
+		} {
+			if got[name] != want {
+				t.Errorf("%s saved to %q, want %q", name, got[name], want)
+			}
+		}
+	})
+}
@@ -1,9 +1,12 @@
 package shortcut
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/celestix/gotgproto/dispatcher"
 	"github.com/celestix/gotgproto/ext"
@@ -23,11 +26,17 @@ import (
 
 // 创建一个 tftask.TGFileTask 并添加到任务队列中, 以编辑消息的方式反馈结果
 func CreateAndAddTGFileTaskWithEdit(ctx *ext.Context, userID int64, stor storage.Storage, dirPath string, file tfile.TGFileMessage, trackMsgID int) error {
+	return CreateAndAddTGFileTaskInChat(ctx, userID, userID, stor, dirPath, file, trackMsgID)
+}
+
+// 同 CreateAndAddTGFileTaskWithEdit, 但在 chatID 中反馈结果, 任务属于 userID. trackMsgID 为 0 时不反馈
+func CreateAndAddTGFileTaskInChat(ctx *ext.Context, chatID, userID int64, stor storage.Storage, dirPath string, file tfile.TGFileMessage, trackMsgID int) error {
 	logger := log.FromContext(ctx)
+	edit := editInChat(ctx, chatID, trackMsgID)
 	user, err := database.GetUserByChatID(ctx, userID)
 	if err != nil {
 		logger.Errorf("Failed to get user by chat ID: %s", err)
-		ctx.EditMessage(userID, &tg.MessagesEditMessageRequest{
+		edit(&tg.MessagesEditMessageRequest{
 			ID:      trackMsgID,
 			Message: "获取用户失败: " + err.Error(),
 		})
@@ -40,7 +49,7 @@ func CreateAndAddTGFileTaskWithEdit(ctx *ext.Context, userID int64, stor storage
 			stor, err = storage.GetStorageByUserIDAndName(ctx, user.ChatID, matchedStorageName.String())
 			if err != nil {
 				logger.Errorf("Failed to get storage by user ID and name: %s", err)
-				ctx.EditMessage(userID, &tg.MessagesEditMessageRequest{
+				edit(&tg.MessagesEditMessageRequest{
 					ID:      trackMsgID,
 					Message: "获取存储失败: " + err.Error(),
 				})
@@ -48,60 +57,91 @@ func CreateAndAddTGFileTaskWithEdit(ctx *ext.Context, userID int64, stor storage
 			}
 		}
 	}
+	return addTGFileTask(ctx, chatID, userID, stor, dirPath, file, trackMsgID)
+}
 
-	storagePath := stor.JoinStoragePath(path.Join(dirPath, file.Name()))
+// addTGFileTask queues the file to be saved to dirPath in stor, which are not changed by any rule
+func addTGFileTask(ctx *ext.Context, chatID, userID int64, stor storage.Storage, dirPath string, file tfile.TGFileMessage, trackMsgID int) error {
+	logger := log.FromContext(ctx)
+	edit := editInChat(ctx, chatID, trackMsgID)
+	storagePath, err := StoragePath(ctx, userID, stor, dirPath, file, file.Name())
+	if err != nil {
+		logger.Errorf("Failed to render storage path: %s", err)
+		edit(&tg.MessagesEditMessageRequest{
+			ID:      trackMsgID,
+			Message: "生成存储路径失败: " + err.Error(),
+		})
+		return dispatcher.EndGroups
+	}
 	injectCtx := tgutil.ExtWithContext(ctx.Context, ctx)
 	taskid := xid.New().String()
-	task, err := tftask.NewTGFileTask(taskid, injectCtx, file, stor, storagePath,
-		tftask.NewProgressTrack(
-			trackMsgID,
-			userID))
+	var progress tftask.ProgressTracker
+	if trackMsgID != 0 {
+		progress = tftask.NewProgressTrack(trackMsgID, chatID)
+	}
+	task, err := tftask.NewTGFileTask(taskid, injectCtx, file, stor, storagePath, progress)
 	if err != nil {
 		logger.Errorf("create task failed: %s", err)
-		ctx.EditMessage(userID, &tg.MessagesEditMessageRequest{
+		edit(&tg.MessagesEditMessageRequest{
 			ID:      trackMsgID,
 			Message: "创建任务失败: " + err.Error(),
 		})
 		return dispatcher.EndGroups
 	}
-	if err := core.AddTask(injectCtx, task); err != nil {
+	if err := addTask(ctx, injectCtx, userID, task); err != nil {
 		logger.Errorf("add task failed: %s", err)
-		ctx.EditMessage(userID, &tg.MessagesEditMessageRequest{
+		edit(&tg.MessagesEditMessageRequest{
 			ID:      trackMsgID,
 			Message: "添加任务失败: " + err.Error(),
 		})
 		return dispatcher.EndGroups
 	}
 	text, entities := msgelem.BuildTaskAddedEntities(ctx, file.Name(), core.GetLength(injectCtx))
-	ctx.EditMessage(userID, &tg.MessagesEditMessageRequest{
+	req := &tg.MessagesEditMessageRequest{
 		ID:       trackMsgID,
 		Message:  text,
 		Entities: entities,
-	})
+	}
+	setChooseMarkup(ctx, req, taskid, []tfile.TGFileMessage{file})
+	edit(req)
 
 	return dispatcher.EndGroups
 }
 
 // 创建一个 batchtftask.BatchTGFileTask 并添加到任务队列中, 以编辑消息的方式反馈结果
 func CreateAndAddBatchTGFileTaskWithEdit(ctx *ext.Context, userID int64, stor storage.Storage, dirPath string, files []tfile.TGFileMessage, trackMsgID int) error {
+	return CreateAndAddBatchTGFileTaskInChat(ctx, userID, userID, stor, dirPath, files, trackMsgID)
+}
+
+// 同 CreateAndAddBatchTGFileTaskWithEdit, 但在 chatID 中反馈结果, 任务属于 userID. trackMsgID 为 0 时不反馈
+func CreateAndAddBatchTGFileTaskInChat(ctx *ext.Context, chatID, userID int64, stor storage.Storage, dirPath string, files []tfile.TGFileMessage, trackMsgID int) error {
 	logger := log.FromContext(ctx)
+	edit := editInChat(ctx, chatID, trackMsgID)
 	user, err := database.GetUserByChatID(ctx, userID)
 	if err != nil {
 		logger.Errorf("Failed to get user by chat ID: %s", err)
-		ctx.EditMessage(userID, &tg.MessagesEditMessageRequest{
+		edit(&tg.MessagesEditMessageRequest{
 			ID:      trackMsgID,
 			Message: "获取用户失败: " + err.Error(),
 		})
 		return dispatcher.EndGroups
 	}
+	var rules []database.Rule
+	if user.ApplyRule {
+		rules = user.Rules
+	}
+	return addBatchTGFileTask(ctx, chatID, userID, stor, dirPath, files, rules, trackMsgID)
+}
 
-	useRule := user.ApplyRule && user.Rules != nil
-
+// addBatchTGFileTask queues the files to be saved to dirPath in stor, or where the /rule rules of the user in rules send them
+func addBatchTGFileTask(ctx *ext.Context, chatID, userID int64, stor storage.Storage, dirPath string, files []tfile.TGFileMessage, rules []database.Rule, trackMsgID int) error {
+	logger := log.FromContext(ctx)
+	edit := editInChat(ctx, chatID, trackMsgID)
 	applyRule := func(file tfile.TGFileMessage) (string, ruleutil.MatchedDirPath) {
-		if !useRule {
+		if len(rules) == 0 {
 			return stor.Name(), ruleutil.MatchedDirPath(dirPath)
 		}
-		storName, dirP := ruleutil.ApplyRule(ctx, user.Rules, ruleutil.NewInput(file))
+		storName, dirP := ruleutil.ApplyRule(ctx, rules, ruleutil.NewInput(file))
 
 		storname := storName.String()
 		if !storName.IsUsable() {
@@ -116,14 +156,16 @@ func CreateAndAddBatchTGFileTaskWithEdit(ctx *ext.Context, userID int64, stor st
 		storage storage.Storage
 	}
 	albumFiles := make(map[int64][]albumFile, 0)
+	var tooLarge []string
 	for _, file := range files {
 		storName, dirPath := applyRule(file)
 		fileStor := stor
 		if storName != stor.Name() && storName != "" {
-			fileStor, err = storage.GetStorageByUserIDAndName(ctx, user.ChatID, storName)
+			var err error
+			fileStor, err = storage.GetStorageByUserIDAndName(ctx, userID, storName)
 			if err != nil {
 				logger.Errorf("Failed to get storage by user ID and name: %s", err)
-				ctx.EditMessage(userID, &tg.MessagesEditMessageRequest{
+				edit(&tg.MessagesEditMessageRequest{
 					ID:      trackMsgID,
 					Message: "获取存储失败: " + err.Error(),
 				})
@@ -131,11 +173,23 @@ func CreateAndAddBatchTGFileTaskWithEdit(ctx *ext.Context, userID int64, stor st
 			}
 		}
 		if !dirPath.NeedNewForAlbum() {
-			storPath := fileStor.JoinStoragePath(path.Join(dirPath.String(), file.Name()))
+			storPath, err := StoragePath(ctx, userID, fileStor, dirPath.String(), file, file.Name())
+			if err != nil {
+				logger.Errorf("Failed to render storage path: %s", err)
+				edit(&tg.MessagesEditMessageRequest{
+					ID:      trackMsgID,
+					Message: "生成存储路径失败: " + err.Error(),
+				})
+				return dispatcher.EndGroups
+			}
 			elem, err := batchtftask.NewTaskElement(fileStor, storPath, file)
+			if errors.Is(err, storage.ErrFileTooLarge) {
+				tooLarge = append(tooLarge, fmt.Sprintf("%s: %s", file.Name(), err))
+				continue
+			}
 			if err != nil {
 				logger.Errorf("Failed to create task element: %s", err)
-				ctx.EditMessage(userID, &tg.MessagesEditMessageRequest{
+				edit(&tg.MessagesEditMessageRequest{
 					ID:      trackMsgID,
 					Message: "任务创建失败: " + err.Error(),
 				})
@@ -166,11 +220,24 @@ func CreateAndAddBatchTGFileTaskWithEdit(ctx *ext.Context, userID int64, stor st
 		albumDir := strings.TrimSuffix(path.Base(afiles[0].file.Name()), path.Ext(afiles[0].file.Name()))
 		albumStor := afiles[0].storage
 		for _, af := range afiles {
-			afstorPath := af.storage.JoinStoragePath(path.Join(dirPath, albumDir, af.file.Name()))
+			afstorPath, err := StoragePath(ctx, userID, af.storage, dirPath, af.file, path.Join(albumDir, af.file.Name()))
+			if err != nil {
+				logger.Errorf("Failed to render storage path: %s", err)
+				edit(&tg.MessagesEditMessageRequest{
+					ID:      trackMsgID,
+					Message: "生成存储路径失败: " + err.Error(),
+				})
+				return dispatcher.EndGroups
+			}
 			elem, err := batchtftask.NewTaskElement(albumStor, afstorPath, af.file)
+			if errors.Is(err, storage.ErrFileTooLarge) {
+				// 只跳过超出大小限制的文件, 相册中的其他文件照常保存
+				tooLarge = append(tooLarge, fmt.Sprintf("%s: %s", af.file.Name(), err))
+				continue
+			}
 			if err != nil {
 				logger.Errorf("Failed to create task element for album file: %s", err)
-				ctx.EditMessage(userID, &tg.MessagesEditMessageRequest{
+				edit(&tg.MessagesEditMessageRequest{
 					ID:      trackMsgID,
 					Message: "任务创建失败: " + err.Error(),
 				})
@@ -179,22 +246,85 @@ func CreateAndAddBatchTGFileTaskWithEdit(ctx *ext.Context, userID int64, stor st
 			elems = append(elems, *elem)
 		}
 	}
+	if len(elems) == 0 && len(tooLarge) > 0 {
+		edit(&tg.MessagesEditMessageRequest{
+			ID:      trackMsgID,
+			Message: tooLargeText(tooLarge),
+		})
+		return dispatcher.EndGroups
+	}
 
 	injectCtx := tgutil.ExtWithContext(ctx.Context, ctx)
 	taskid := xid.New().String()
-	task := batchtftask.NewBatchTGFileTask(taskid, injectCtx, elems, batchtftask.NewProgressTracker(trackMsgID, userID), true)
-	if err := core.AddTask(injectCtx, task); err != nil {
+	var progress batchtftask.ProgressTracker
+	if trackMsgID != 0 {
+		progress = batchtftask.NewProgressTracker(trackMsgID, chatID)
+	}
+	task := batchtftask.NewBatchTGFileTask(taskid, injectCtx, elems, progress, true)
+	if err := addTask(ctx, injectCtx, userID, task); err != nil {
 		logger.Errorf("Failed to add batch task: %s", err)
-		ctx.EditMessage(userID, &tg.MessagesEditMessageRequest{
+		edit(&tg.MessagesEditMessageRequest{
 			ID:      trackMsgID,
 			Message: "批量任务添加失败: " + err.Error(),
 		})
 		return dispatcher.EndGroups
 	}
-	ctx.EditMessage(userID, &tg.MessagesEditMessageRequest{
-		ID:          trackMsgID,
-		Message:     fmt.Sprintf("已添加批量任务, 共 %d 个文件", len(files)),
-		ReplyMarkup: nil,
-	})
+	text := fmt.Sprintf("已添加批量任务, 共 %d 个文件", len(elems))
+	if len(tooLarge) > 0 {
+		text += "\n\n" + tooLargeText(tooLarge)
+	}
+	req := &tg.MessagesEditMessageRequest{
+		ID:      trackMsgID,
+		Message: text,
+	}
+	setChooseMarkup(ctx, req, taskid, files)
+	edit(req)
 	return dispatcher.EndGroups
 }
+
+// tooLargeText lists the files skipped because they exceed the max file size
+func tooLargeText(tooLarge []string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "已跳过 %d 个超过大小限制的文件:", len(tooLarge))
+	for _, line := range tooLarge {
+		sb.WriteString("\n- " + line)
+	}
+	return sb.String()
+}
+
+// chooseWindow is how long a task saved to the default storage without asking is held
+// before it is queued, so that another storage can still be chosen
+const chooseWindow = 15 * time.Second
+
+// addTask queues the task, or holds it for chooseWindow if it is saved to the default storage without asking.
+// Tests replace it to see the tasks
+var addTask = func(ctx *ext.Context, injectCtx context.Context, userID int64, task core.Exectable) error {
+	if !savedByDefault(ctx) {
+		return core.AddTask(injectCtx, userID, task)
+	}
+	return core.HoldTask(injectCtx, userID, task, chooseWindow)
+}
+
+// setChooseMarkup offers to pick another storage when the files were saved to the default one without asking
+func setChooseMarkup(ctx *ext.Context, req *tg.MessagesEditMessageRequest, taskID string, files []tfile.TGFileMessage) {
+	if !savedByDefault(ctx) {
+		return
+	}
+	markup, err := msgelem.BuildChooseStorageMarkup(taskID, files)
+	if err != nil {
+		log.FromContext(ctx).Errorf("Failed to build choose storage markup: %s", err)
+		return
+	}
+	req.Message += fmt.Sprintf("\n\n%d 秒后开始, 在此之前可选择其他存储", int(chooseWindow.Seconds()))
+	req.SetReplyMarkup(markup)
+}
+
+// editInChat returns a function that edits the tracking message in the chat, it does nothing if trackMsgID is 0
+func editInChat(ctx *ext.Context, chatID int64, trackMsgID int) func(req *tg.MessagesEditMessageRequest) {
+	return func(req *tg.MessagesEditMessageRequest) {
+		if trackMsgID == 0 {
+			return
+		}
+		ctx.EditMessage(chatID, req)
+	}
+}
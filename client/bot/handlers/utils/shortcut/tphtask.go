@@ -32,7 +32,7 @@ func CreateAndAddTphTaskWithEdit(ctx *ext.Context,
 		tphutil.DefaultClient(),
 		tphtask.NewProgress(trackMsgID, userID),
 	)
-	if err := core.AddTask(injectCtx, task); err != nil {
+	if err := core.AddTask(injectCtx, userID, task); err != nil {
 		log.FromContext(ctx).Errorf("Failed to add task: %s", err)
 		ctx.EditMessage(userID, &tg.MessagesEditMessageRequest{
 			ID:      trackMsgID,
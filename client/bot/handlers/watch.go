@@ -9,6 +9,7 @@ import (
 	"github.com/charmbracelet/log"
 	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/msgelem"
 	"github.com/krau/SaveAny-Bot/common/utils/tgutil"
+	"github.com/krau/SaveAny-Bot/config"
 	"github.com/krau/SaveAny-Bot/database"
 )
 
@@ -26,8 +27,8 @@ func handleWatchCmd(ctx *ext.Context, update *ext.Update) error {
 		ctx.Reply(update, ext.ReplyTextString("获取用户失败"), nil)
 		return dispatcher.EndGroups
 	}
-	if user.DefaultStorage == "" {
-		ctx.Reply(update, ext.ReplyTextString("请先设置默认存储, 使用 /storage 命令"), nil)
+	if storName, _ := watchDefault(user); storName == "" {
+		ctx.Reply(update, ext.ReplyTextString("请先设置默认存储, 使用 /storage 或 /setdefault 命令"), nil)
 		return dispatcher.EndGroups
 	}
 	chatArg := args[1]
@@ -108,3 +109,15 @@ func handleUnwatchCmd(ctx *ext.Context, update *ext.Update) error {
 	ctx.Reply(update, ext.ReplyTextString("已取消监听聊天: "+chatArg), nil)
 	return dispatcher.EndGroups
 }
+
+// watchDefault returns the storage and path files from watched chats are saved to:
+// the default set with /setdefault or /storage, or else the one in the config
+func watchDefault(user *database.User) (storageName, dirPath string) {
+	if user.DefaultSaveStorage != "" {
+		return user.DefaultSaveStorage, user.DefaultSavePath
+	}
+	if user.DefaultStorage != "" {
+		return user.DefaultStorage, ""
+	}
+	return config.C().GetUserDefault(user.ChatID)
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/celestix/gotgproto/dispatcher"
+	"github.com/celestix/gotgproto/ext"
+	"github.com/charmbracelet/log"
+	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/mediautil"
+	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/ruleutil"
+	"github.com/krau/SaveAny-Bot/common/utils/tgutil"
+	"github.com/krau/SaveAny-Bot/config"
+	"github.com/krau/SaveAny-Bot/pkg/ruleengine"
+	"github.com/krau/SaveAny-Bot/pkg/tfile"
+)
+
+// handleWhichRuleCmd shows which rule in the config the replied file would match
+func handleWhichRuleCmd(ctx *ext.Context, update *ext.Update) error {
+	in, ok := repliedRuleInput(ctx, update)
+	if !ok {
+		return dispatcher.EndGroups
+	}
+	ctx.Reply(update, ext.ReplyTextString(explainRules(ctx, in)), nil)
+	return dispatcher.EndGroups
+}
+
+// repliedRuleInput returns the rule input of the file in the replied message, it replies the error to the user if there is none
+func repliedRuleInput(ctx *ext.Context, update *ext.Update) (ruleengine.Input, bool) {
+	replyTo := update.EffectiveMessage.ReplyToMessage
+	if replyTo == nil || replyTo.Message == nil {
+		ctx.Reply(update, ext.ReplyTextString("请回复一条包含文件的消息"), nil)
+		return ruleengine.Input{}, false
+	}
+	message := replyTo.Message
+	if !mediautil.IsSupported(message.Media) {
+		ctx.Reply(update, ext.ReplyTextString("不支持的消息类型"), nil)
+		return ruleengine.Input{}, false
+	}
+	file, err := tfile.FromMediaMessage(message.Media, ctx.Raw, message,
+		tfile.WithMessage(message),
+		tfile.WithNameIfEmpty(tgutil.GenFileNameFromMessage(*message)))
+	if err != nil {
+		log.FromContext(ctx).Errorf("Failed to get file from media: %s", err)
+		ctx.Reply(update, ext.ReplyTextString("获取文件失败: "+err.Error()), nil)
+		return ruleengine.Input{}, false
+	}
+	return ruleutil.NewEngineInput(ctx, update.GetUserChat().GetID(), file), true
+}
+
+// describeInput writes the attributes of the file the rules are matched against
+func describeInput(sb *strings.Builder, in ruleengine.Input) {
+	fmt.Fprintf(sb, "文件名: %s\n", in.FileName)
+	fmt.Fprintf(sb, "大小: %.2f MB\n", float64(in.Size)/(1024*1024))
+	if in.MediaType != "" {
+		fmt.Fprintf(sb, "类型: %s\n", in.MediaType)
+	}
+	if in.ChatID != 0 {
+		fmt.Fprintf(sb, "来源聊天: %d", in.ChatID)
+		if in.ChatTitle != "" {
+			fmt.Fprintf(sb, " (%s)", in.ChatTitle)
+		}
+		sb.WriteString("\n")
+	}
+	if in.SenderID != 0 {
+		fmt.Fprintf(sb, "发送者: %d\n", in.SenderID)
+	}
+}
+
+// explainRules describes the input and the rules matching it
+func explainRules(ctx *ext.Context, in ruleengine.Input) string {
+	var sb strings.Builder
+	describeInput(&sb, in)
+	sb.WriteString("\n")
+
+	cfg := config.C()
+	var fired *ruleengine.Rule
+	for _, r := range ruleutil.MatchingRules(ctx, in) {
+		switch {
+		case fired != nil:
+			fmt.Fprintf(&sb, "也匹配, 但优先级较低: %s\n", r)
+		case !cfg.RuleAvailable(in.UserID, r):
+			fmt.Fprintf(&sb, "匹配, 但你无权使用存储 %s: %s\n", r.Storage, r)
+		default:
+			fired = r
+			fmt.Fprintf(&sb, "命中规则: %s\n", r)
+			if r.Skips() {
+				sb.WriteString("该文件将被跳过\n")
+			} else if dirPath, err := r.DirPath(in); err == nil {
+				fmt.Fprintf(&sb, "将保存到: %s:%s\n", r.Storage, dirPath)
+			}
+		}
+	}
+	if fired == nil {
+		sb.WriteString("没有命中任何规则, 保存时将询问存储位置")
+	}
+	return strings.TrimSpace(sb.String())
+}
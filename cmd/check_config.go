@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/krau/SaveAny-Bot/config"
+	storcfg "github.com/krau/SaveAny-Bot/config/storage"
+	"github.com/krau/SaveAny-Bot/storage"
+	"github.com/spf13/cobra"
+)
+
+var checkConfigCmd = &cobra.Command{
+	Use:   "check-config",
+	Short: "Validate the config and check that every enabled storage is usable",
+	Long: `Validate the config and check that every enabled storage is usable.
+
+Each storage is initialized and probed with its own timeout: local storages write a test file,
+webdav sends a PROPFIND, minio checks the bucket, alist calls /api/me, telegram calls getMe,
+ftp logs in, smb mounts the share, gdrive gets the root folder, onedrive gets the drive,
+dropbox gets the current account and b2 authorizes the account and looks up the bucket.
+Exits with status 1 if the config is invalid or any storage fails.`,
+	Run: checkConfig,
+}
+
+func init() {
+	checkConfigCmd.Flags().StringP("config", "c", "", "config file path, defaults to config.{toml,yaml,yml,json} in . or /etc/saveany/")
+	checkConfigCmd.Flags().Duration("timeout", 10*time.Second, "timeout of each storage check")
+	rootCmd.AddCommand(checkConfigCmd)
+}
+
+type probeResult struct {
+	name, typ string
+	err       error
+}
+
+func checkConfig(cmd *cobra.Command, _ []string) {
+	logger := log.NewWithOptions(os.Stderr, log.Options{Level: log.WarnLevel})
+	ctx := log.WithContext(cmd.Context(), logger)
+	applyFlags(cmd)
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+
+	if err := config.Init(ctx); err != nil {
+		fmt.Println("FAIL config:", err)
+		os.Exit(1)
+	}
+
+	storages := config.C().Storages
+	results := make([]probeResult, len(storages))
+	var wg sync.WaitGroup
+	for i, cfg := range storages {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = probeResult{
+				name: cfg.GetName(),
+				typ:  string(cfg.GetType()),
+				err:  probeStorage(ctx, cfg, timeout),
+			}
+		}()
+	}
+	wg.Wait()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTYPE\tSTATUS\tERROR")
+	fmt.Fprintln(w, "config\t-\tOK\t")
+	failed := false
+	for _, r := range results {
+		if r.err != nil {
+			failed = true
+			fmt.Fprintf(w, "%s\t%s\tFAIL\t%s\n", r.name, r.typ, strings.ReplaceAll(r.err.Error(), "\n", "; "))
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\tOK\t\n", r.name, r.typ)
+	}
+	w.Flush()
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// probeStorage initializes the storage and probes it. It returns once the timeout is reached,
+// even if the storage does not respect ctx
+func probeStorage(ctx context.Context, cfg storcfg.StorageConfig, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		stor, err := storage.NewStorage(ctx, cfg)
+		if err != nil {
+			done <- err
+			return
+		}
+		if prober, ok := stor.(storage.StorageProber); ok {
+			err = prober.Probe(ctx)
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+}
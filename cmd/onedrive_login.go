@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/krau/SaveAny-Bot/storage/onedrive"
+	"github.com/spf13/cobra"
+	"golang.org/x/oauth2"
+)
+
+var onedriveLoginCmd = &cobra.Command{
+	Use:   "onedrive-login",
+	Short: "Sign in to OneDrive with a device code and print the refresh token for the onedrive storage",
+	Long: `Sign in to OneDrive with a device code and print the refresh token for the onedrive storage.
+
+The app registration must allow public client flows. Open the printed url, enter the code
+and sign in, then copy the refresh token into the config.`,
+	Run: onedriveLogin,
+}
+
+func init() {
+	onedriveLoginCmd.Flags().String("client-id", "", "application (client) id of the app registration")
+	onedriveLoginCmd.Flags().String("drive-type", "personal", "personal, business or sharepoint")
+	onedriveLoginCmd.Flags().String("tenant", "", "tenant, defaults to consumers for personal and organizations otherwise")
+	onedriveLoginCmd.MarkFlagRequired("client-id")
+	rootCmd.AddCommand(onedriveLoginCmd)
+}
+
+func onedriveLogin(cmd *cobra.Command, _ []string) {
+	flags := cmd.Flags()
+	clientID, _ := flags.GetString("client-id")
+	driveType, _ := flags.GetString("drive-type")
+	tenant, _ := flags.GetString("tenant")
+	if tenant == "" {
+		tenant = "organizations"
+		if driveType == "personal" {
+			tenant = "consumers"
+		}
+	}
+	conf := &oauth2.Config{
+		ClientID: clientID,
+		Endpoint: onedrive.Endpoint(tenant),
+		Scopes:   onedrive.Scopes(driveType),
+	}
+	ctx := cmd.Context()
+	da, err := conf.DeviceAuth(ctx)
+	if err != nil {
+		fmt.Println("Failed to start device login:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Open %s and enter the code %s\n", da.VerificationURI, da.UserCode)
+	token, err := conf.DeviceAccessToken(ctx, da)
+	if err != nil {
+		fmt.Println("Failed to sign in:", err)
+		os.Exit(1)
+	}
+	if token.RefreshToken == "" {
+		fmt.Println("No refresh token was returned, check that the offline_access scope is allowed")
+		os.Exit(1)
+	}
+	fmt.Printf("Signed in, add this to the onedrive storage:\n\nclient_id = %q\ntenant = %q\nrefresh_token = %q\n", clientID, tenant, token.RefreshToken)
+}
@@ -13,6 +13,12 @@ var rootCmd = &cobra.Command{
 	Run:   Run,
 }
 
+func init() {
+	rootCmd.Flags().StringP("config", "c", "", "config file path, defaults to config.{toml,yaml,yml,json} in . or /etc/saveany/")
+	rootCmd.Flags().Int("workers", 0, "number of tasks to process at the same time, overrides the config")
+	rootCmd.Flags().String("lang", "", "language, overrides the config")
+}
+
 func Execute(ctx context.Context) {
 	if err := rootCmd.ExecuteContext(ctx); err != nil {
 		fmt.Println(err)
@@ -33,8 +33,12 @@ func Run(cmd *cobra.Command, _ []string) {
 	})
 	ctx = log.WithContext(ctx, logger)
 
+	applyFlags(cmd)
 	initAll(ctx)
 	core.Run(ctx)
+	if err := config.Watch(ctx); err != nil {
+		logger.Warnf("Config hot reload is disabled: %s", err)
+	}
 
 	<-ctx.Done()
 	logger.Info(i18n.T(i18nk.Exiting))
@@ -42,6 +46,19 @@ func Run(cmd *cobra.Command, _ []string) {
 	cleanCache()
 }
 
+// applyFlags passes the flags given on the command line to the config
+func applyFlags(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	if path, _ := flags.GetString("config"); path != "" {
+		config.SetConfigFile(path)
+	}
+	for _, key := range []string{"workers", "lang"} {
+		if flags.Changed(key) {
+			config.SetFlag(key, flags.Lookup(key).Value.String())
+		}
+	}
+}
+
 func initAll(ctx context.Context) {
 	if err := config.Init(ctx); err != nil {
 		fmt.Println("Failed to load config:", err)
@@ -49,11 +66,11 @@ func initAll(ctx context.Context) {
 	}
 	cache.Init()
 	logger := log.FromContext(ctx)
-	i18n.Init(config.Cfg.Lang)
+	i18n.Init(config.C().Lang)
 	logger.Info(i18n.T(i18nk.Initing))
 	database.Init(ctx)
 	storage.LoadStorages(ctx)
-	if config.Cfg.Telegram.Userbot.Enable {
+	if config.C().Telegram.Userbot.Enable {
 		_, err := userclient.Login(ctx)
 		if err != nil {
 			logger.Fatalf("User client login failed: %s", err)
@@ -63,13 +80,13 @@ func initAll(ctx context.Context) {
 }
 
 func cleanCache() {
-	if config.Cfg.NoCleanCache {
+	if config.C().NoCleanCache {
 		return
 	}
-	if config.Cfg.Temp.BasePath != "" && !config.Cfg.Stream {
-		if slices.Contains([]string{"/", ".", "\\", ".."}, filepath.Clean(config.Cfg.Temp.BasePath)) {
+	if config.C().Temp.BasePath != "" && !config.C().Stream {
+		if slices.Contains([]string{"/", ".", "\\", ".."}, filepath.Clean(config.C().Temp.BasePath)) {
 			log.Error(i18n.T(i18nk.InvalidCacheDir, map[string]any{
-				"Path": config.Cfg.Temp.BasePath,
+				"Path": config.C().Temp.BasePath,
 			}))
 			return
 		}
@@ -80,7 +97,7 @@ func cleanCache() {
 			}))
 			return
 		}
-		cachePath := filepath.Join(currentDir, config.Cfg.Temp.BasePath)
+		cachePath := filepath.Join(currentDir, config.C().Temp.BasePath)
 		cachePath, err = filepath.Abs(cachePath)
 		if err != nil {
 			log.Error(i18n.T(i18nk.GetCacheAbsPathFailed, map[string]any{
@@ -0,0 +1,44 @@
+package fsutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/krau/SaveAny-Bot/pkg/storerr"
+)
+
+// AvailableSpace returns the bytes available to the current user on the filesystem of path.
+// If path does not exist yet, its nearest existing parent is used
+func AvailableSpace(path string) (uint64, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return 0, err
+	}
+	return availableSpace(existingParent(path))
+}
+
+// existingParent returns the absolute path itself if it exists, or else its nearest existing parent
+func existingParent(path string) string {
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
+
+// CheckFreeSpace returns an error wrapping storerr.ErrNoSpace if less than need bytes are available
+// on the filesystem of path. The check is skipped if the free space can not be determined
+func CheckFreeSpace(path string, need int64) error {
+	avail, err := AvailableSpace(path)
+	if err != nil || need <= 0 || avail >= uint64(need) {
+		return nil
+	}
+	return fmt.Errorf("%w: 磁盘空间不足, %s 剩余 %.2f MB, 需要 %.2f MB",
+		storerr.ErrNoSpace, path, float64(avail)/(1024*1024), float64(need)/(1024*1024))
+}
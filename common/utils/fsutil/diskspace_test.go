@@ -0,0 +1,44 @@
+package fsutil
+
+import (
+	"errors"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/krau/SaveAny-Bot/pkg/storerr"
+)
+
+func TestExistingParent(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		path string
+		want string
+	}{
+		{dir, dir},
+		{filepath.Join(dir, "missing"), dir},
+		{filepath.Join(dir, "missing", "deeper", "file.bin"), dir},
+	}
+	for _, tt := range tests {
+		if got := existingParent(tt.path); got != tt.want {
+			t.Errorf("existingParent(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestCheckFreeSpace(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "not", "created")
+	for _, need := range []int64{-1, 0, 1} {
+		if err := CheckFreeSpace(dir, need); err != nil {
+			t.Errorf("CheckFreeSpace(%d) = %v, want nil", need, err)
+		}
+	}
+	if avail, err := AvailableSpace(dir); err != nil {
+		t.Skipf("free space is not available: %v", err)
+	} else if avail >= math.MaxInt64 {
+		t.Skip("free space is too large to exceed")
+	}
+	if err := CheckFreeSpace(dir, math.MaxInt64); !errors.Is(err, storerr.ErrNoSpace) {
+		t.Errorf("CheckFreeSpace(MaxInt64) = %v, want ErrNoSpace", err)
+	}
+}
@@ -4,11 +4,16 @@ import (
 	"crypto/md5"
 	"encoding/hex"
 	"fmt"
+	"path"
 	"regexp"
 	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/duke-git/lancet/v2/slice"
+	"github.com/duke-git/lancet/v2/validator"
+	"golang.org/x/text/unicode/norm"
 )
 
 func HashString(s string) string {
@@ -48,3 +53,61 @@ func ParseIntStrRange(input string, sep string) (int64, int64, error) {
 	}
 	return min, max, nil
 }
+
+// CleanFolderName replaces the characters that are invalid or awkward in file and folder names with '_'
+// and collapses the repeated ones
+func CleanFolderName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7F {
+			return '_'
+		}
+		switch r {
+		// invalid characters
+		case '/', '\\',
+			':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		// empty
+		case ' ', '\t', '\r', '\n':
+			return '_'
+		}
+		if validator.IsPrintable(string(r)) {
+			return r
+		}
+		return '_'
+	}, name)
+	return strings.Join(strings.FieldsFunc(name, func(r rune) bool {
+		return r == '_' || r == ' '
+	}), "_")
+}
+
+// ToASCII transliterates name for servers that reject non-ASCII names. Accents are stripped and the
+// other non-ASCII characters are replaced with '_', in which case a short hash of name is appended
+// so that different names stay different
+func ToASCII(name string) string {
+	var b strings.Builder
+	lossy, replaced := false, false
+	for _, r := range norm.NFKD.String(name) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+		case r < utf8.RuneSelf:
+			b.WriteRune(r)
+			replaced = false
+		default:
+			lossy = true
+			if !replaced {
+				b.WriteRune('_')
+			}
+			replaced = true
+		}
+	}
+	out := b.String()
+	if !lossy {
+		return out
+	}
+	ext := path.Ext(out)
+	base := strings.Trim(strings.TrimSuffix(out, ext), "_ ")
+	if base != "" {
+		base += "_"
+	}
+	return base + HashString(name)[:8] + ext
+}
@@ -11,7 +11,6 @@ import (
 	"github.com/duke-git/lancet/v2/mathutil"
 	"github.com/duke-git/lancet/v2/slice"
 	lcstrutil "github.com/duke-git/lancet/v2/strutil"
-	"github.com/duke-git/lancet/v2/validator"
 	"github.com/gabriel-vasile/mimetype"
 	"github.com/gotd/td/tg"
 	"github.com/krau/SaveAny-Bot/common/cache"
@@ -60,28 +59,7 @@ func GenFileNameFromMessage(message tg.Message) string {
 			tagStr := string(tagStrRunes)
 			return fmt.Sprintf("%s_%s", tagStr, strconv.Itoa(message.GetID()))
 		}
-		text = lcstrutil.Substring(strings.Map(func(r rune) rune {
-			if r < 0x20 || r == 0x7F {
-				return '_'
-			}
-			switch r {
-			// invalid characters
-			case '/', '\\',
-				':', '*', '?', '"', '<', '>', '|':
-				return '_'
-			// empty
-			case ' ', '\t', '\r', '\n':
-				return '_'
-			}
-			if validator.IsPrintable(string(r)) {
-				return r
-			}
-			return '_'
-		}, text), 0, 64)
-		text = strings.Join(strings.FieldsFunc(text, func(r rune) bool {
-			return r == '_' || r == ' '
-		}), "_")
-		return text
+		return strutil.CleanFolderName(lcstrutil.Substring(text, 0, 64))
 	}()
 
 	if filename == "" {
@@ -9,6 +9,7 @@ import (
 	"github.com/celestix/gotgproto/ext"
 	"github.com/duke-git/lancet/v2/validator"
 	"github.com/gotd/td/tg"
+	"github.com/krau/SaveAny-Bot/common/cache"
 )
 
 func ParseChatID(ctx *ext.Context, idOrUsername string) (int64, error) {
@@ -117,3 +118,43 @@ func ParseMessageLink(ctx *ext.Context, link string) (int64, int, error) {
 	}
 	return 0, 0, fmt.Errorf("invalid message link format: %s", link)
 }
+
+// GetChatTitle returns the title of a group or channel, the result is cached
+func GetChatTitle(ctx *ext.Context, chatID int64) (string, error) {
+	key := fmt.Sprintf("chattitle:%d:%d", ctx.Self.ID, chatID)
+	if title, ok := cache.Get[string](key); ok {
+		return title, nil
+	}
+	var (
+		chats tg.MessagesChatsClass
+		err   error
+	)
+	switch peer := ctx.PeerStorage.GetInputPeerById(chatID).(type) {
+	case *tg.InputPeerChannel:
+		chats, err = ctx.Raw.ChannelsGetChannels(ctx, []tg.InputChannelClass{&tg.InputChannel{
+			ChannelID:  peer.ChannelID,
+			AccessHash: peer.AccessHash,
+		}})
+	case *tg.InputPeerChat:
+		chats, err = ctx.Raw.MessagesGetChats(ctx, []int64{peer.ChatID})
+	default:
+		return "", fmt.Errorf("chat %d is not a known group or channel", chatID)
+	}
+	if err != nil {
+		return "", err
+	}
+	for _, chat := range chats.GetChats() {
+		var title string
+		switch c := chat.(type) {
+		case *tg.Channel:
+			title = c.Title
+		case *tg.Chat:
+			title = c.Title
+		default:
+			continue
+		}
+		cache.Set(key, title)
+		return title, nil
+	}
+	return "", fmt.Errorf("chat %d not found", chatID)
+}
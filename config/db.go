@@ -1,6 +1,6 @@
 package config
 
 type dbConfig struct {
-	Path    string `toml:"path" mapstructure:"path"`
-	Session string `toml:"session" mapstructure:"session"`
+	Path    string `toml:"path" mapstructure:"path" json:"path"`
+	Session string `toml:"session" mapstructure:"session" json:"session"`
 }
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// expandEnv replaces ${VAR} and ${VAR:-default} in all string values of the settings tree.
+// $${VAR} is kept as the literal ${VAR}.
+func expandEnv(settings map[string]any) error {
+	var errs []error
+	for key, value := range settings {
+		settings[key] = expandEnvValue(key, value, &errs)
+	}
+	return errors.Join(errs...)
+}
+
+func expandEnvValue(path string, value any, errs *[]error) any {
+	switch v := value.(type) {
+	case string:
+		expanded, err := expandEnvString(v)
+		if err != nil {
+			*errs = append(*errs, fmt.Errorf("%s: %w", path, err))
+			return v
+		}
+		return expanded
+	case map[string]any:
+		for key, item := range v {
+			v[key] = expandEnvValue(path+"."+key, item, errs)
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = expandEnvValue(fmt.Sprintf("%s[%d]", path, i), item, errs)
+		}
+		return v
+	case []map[string]any:
+		for i, item := range v {
+			v[i] = expandEnvValue(fmt.Sprintf("%s[%d]", path, i), item, errs).(map[string]any)
+		}
+		return v
+	case []string:
+		for i, item := range v {
+			v[i] = expandEnvValue(fmt.Sprintf("%s[%d]", path, i), item, errs).(string)
+		}
+		return v
+	}
+	return value
+}
+
+func expandEnvString(s string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	var sb strings.Builder
+	var missing []string
+	for i := 0; i < len(s); {
+		if strings.HasPrefix(s[i:], "$${") {
+			sb.WriteString("${")
+			i += 3
+			continue
+		}
+		if !strings.HasPrefix(s[i:], "${") {
+			sb.WriteByte(s[i])
+			i++
+			continue
+		}
+		end := strings.IndexByte(s[i+2:], '}')
+		if end < 0 {
+			// unterminated, keep it as is
+			sb.WriteString(s[i:])
+			break
+		}
+		expr := s[i+2 : i+2+end]
+		i += end + 3
+		name, def, hasDef := strings.Cut(expr, ":-")
+		if val, ok := os.LookupEnv(name); ok && (val != "" || !hasDef) {
+			sb.WriteString(val)
+			continue
+		}
+		if hasDef {
+			sb.WriteString(def)
+			continue
+		}
+		missing = append(missing, name)
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable %s is not set", strings.Join(missing, ", "))
+	}
+	return sb.String(), nil
+}
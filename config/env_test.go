@@ -0,0 +1,140 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestExpandEnvString(t *testing.T) {
+	t.Setenv("SAVEANY_TEST_TOKEN", "123:abc")
+	t.Setenv("SAVEANY_TEST_EMPTY", "")
+
+	testCases := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"plain", "plain", false},
+		{"${SAVEANY_TEST_TOKEN}", "123:abc", false},
+		{"bot${SAVEANY_TEST_TOKEN}/x", "bot123:abc/x", false},
+		{"${SAVEANY_TEST_UNSET:-fallback}", "fallback", false},
+		{"${SAVEANY_TEST_EMPTY:-fallback}", "fallback", false},
+		{"${SAVEANY_TEST_EMPTY}", "", false},
+		{"${SAVEANY_TEST_UNSET:-}", "", false},
+		{"$${SAVEANY_TEST_TOKEN}", "${SAVEANY_TEST_TOKEN}", false},
+		{"pa$$word$", "pa$$word$", false},
+		{"${SAVEANY_TEST_TOKEN", "${SAVEANY_TEST_TOKEN", false},
+		{"${SAVEANY_TEST_UNSET}", "", true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := expandEnvString(tc.input)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestExpandEnvNested(t *testing.T) {
+	t.Setenv("SAVEANY_TEST_PASSWORD", "secret")
+
+	settings := map[string]any{
+		"telegram": map[string]any{
+			"token": "${SAVEANY_TEST_UNSET_TOKEN:-default}",
+			"proxy": map[string]any{
+				"url": "socks5://${SAVEANY_TEST_PASSWORD}@127.0.0.1:7890",
+			},
+		},
+		"storages": []any{
+			map[string]any{"name": "local", "base_path": "./downloads"},
+			map[string]any{"name": "dav", "password": "${SAVEANY_TEST_PASSWORD}"},
+			map[string]any{"name": "s3", "secret_access_key": "${SAVEANY_TEST_UNSET_KEY}"},
+		},
+		"users": []any{
+			map[string]any{"id": 1, "storages": []any{"local", "${SAVEANY_TEST_PASSWORD}"}},
+		},
+		"workers": 3,
+	}
+	err := expandEnv(settings)
+	if err == nil {
+		t.Fatal("expected error for unset variable")
+	}
+	if !strings.Contains(err.Error(), "storages[2].secret_access_key") {
+		t.Fatalf("error should name the field path, got: %v", err)
+	}
+
+	telegram := settings["telegram"].(map[string]any)
+	if telegram["token"] != "default" {
+		t.Fatalf("telegram.token = %v", telegram["token"])
+	}
+	if url := telegram["proxy"].(map[string]any)["url"]; url != "socks5://secret@127.0.0.1:7890" {
+		t.Fatalf("telegram.proxy.url = %v", url)
+	}
+	storages := settings["storages"].([]any)
+	if pwd := storages[1].(map[string]any)["password"]; pwd != "secret" {
+		t.Fatalf("storages[1].password = %v", pwd)
+	}
+	users := settings["users"].([]any)
+	if s := users[0].(map[string]any)["storages"].([]any)[1]; s != "secret" {
+		t.Fatalf("users[0].storages[1] = %v", s)
+	}
+	if settings["workers"] != 3 {
+		t.Fatalf("non-string values must be kept, got %v", settings["workers"])
+	}
+}
+
+func TestLoadKeepsViperSettings(t *testing.T) {
+	t.Setenv("SAVEANY_TEST_TOKEN", "123:abc")
+	t.Setenv("SAVEANY_TEST_PASSWORD", "secret")
+
+	v := viper.New()
+	v.SetConfigType("toml")
+	if err := v.ReadConfig(strings.NewReader(`
+workers = 3
+retry = 3
+threads = 4
+[cache]
+ttl = "24h"
+[telegram]
+token = "${SAVEANY_TEST_TOKEN}"
+app_id = 1
+app_hash = "hash"
+[[storages]]
+name = "dav"
+type = "webdav"
+enable = true
+url = "https://dav.example.com"
+base_path = "/saveany"
+username = "user"
+password = "${SAVEANY_TEST_PASSWORD}"
+[[users]]
+id = 1
+storages = ["dav"]
+`)); err != nil {
+		t.Fatal(err)
+	}
+	before := copySettings(v.AllSettings())
+	c, err := load(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Telegram.Token != "123:abc" {
+		t.Fatalf("telegram.token = %q", c.Telegram.Token)
+	}
+	if after := v.AllSettings(); !reflect.DeepEqual(before, after) {
+		t.Fatalf("load changed the viper settings:\nbefore: %v\nafter:  %v", before, after)
+	}
+}
@@ -0,0 +1,25 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+var (
+	configPaths = []string{".", "/etc/saveany/"}
+	// in order of precedence when several exist in the same directory
+	configExts = []string{"toml", "yaml", "yml", "json"}
+)
+
+// findConfigFile returns the first existing config.<ext> in the config paths, or "" if there is none.
+func findConfigFile() string {
+	for _, dir := range configPaths {
+		for _, ext := range configExts {
+			path := filepath.Join(dir, "config."+ext)
+			if stat, err := os.Stat(path); err == nil && !stat.IsDir() {
+				return path
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/krau/SaveAny-Bot/config/storage"
+	"github.com/spf13/viper"
+)
+
+func TestLoadFormats(t *testing.T) {
+	files := map[string]string{
+		"config.toml": `
+workers = 2
+retry = 3
+threads = 4
+[cache]
+ttl = "1m"
+[telegram]
+token = "123:abc"
+app_id = 1
+app_hash = "hash"
+[[storages]]
+name = "tg"
+type = "telegram"
+enable = true
+chat_id = -1001234567890
+[[users]]
+id = 777000
+storages = ["tg"]
+`,
+		"config.yaml": `
+workers: 2
+retry: 3
+threads: 4
+cache:
+  ttl: 1m
+telegram:
+  token: "123:abc"
+  app_id: 1
+  app_hash: hash
+storages:
+  - name: tg
+    type: telegram
+    enable: true
+    chat_id: -1001234567890
+users:
+  - id: 777000
+    storages: [tg]
+`,
+		"config.json": `{
+  "workers": 2, "retry": 3, "threads": 4,
+  "cache": {"ttl": "1m"},
+  "telegram": {"token": "123:abc", "app_id": 1, "app_hash": "hash"},
+  "storages": [{"name": "tg", "type": "telegram", "enable": true, "chat_id": -1001234567890}],
+  "users": [{"id": 777000, "storages": ["tg"]}]
+}`,
+	}
+	dir := t.TempDir()
+	var want *Config
+	for _, name := range []string{"config.toml", "config.yaml", "config.json"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(files[name]), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		v := viper.New()
+		v.SetConfigFile(path)
+		if err := v.ReadInConfig(); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		got, err := load(v)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		// the raw values keep the number types of each format, only the decoded fields matter
+		got.Storages[0].(*storage.TelegramStorageConfig).RawConfig = nil
+		got.sources = nil
+		if want == nil {
+			want = got
+			continue
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("%s decodes differently from config.toml:\n got %+v\nwant %+v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package config
+
+import (
+	"github.com/duke-git/lancet/v2/slice"
+	"github.com/krau/SaveAny-Bot/pkg/ruleengine"
+)
+
+type groupConfig struct {
+	ID    int64   `toml:"id" mapstructure:"id" json:"id"`          // 群组 id, 可带 -100 前缀
+	Users []int64 `toml:"users" mapstructure:"users" json:"users"` // 允许在群组中使用 Bot 的用户, 为空时允许所有用户
+	// 群组中的文件保存到的存储及路径
+	DefaultStorage string `toml:"default_storage" mapstructure:"default_storage" json:"default_storage"`
+	DefaultPath    string `toml:"default_path" mapstructure:"default_path" json:"default_path"`
+	Silent         bool   `toml:"silent" mapstructure:"silent" json:"silent"` // 不在群组中回复及发送进度
+}
+
+// AllowUser reports whether the user may save files in the group
+func (g groupConfig) AllowUser(userID int64) bool {
+	return len(g.Users) == 0 || slice.Contain(g.Users, userID)
+}
+
+// GetGroup returns the config of the group chat, chatID is the id without the -100 prefix
+func (c *Config) GetGroup(chatID int64) (groupConfig, bool) {
+	for _, group := range c.Groups {
+		if ruleengine.NormalizeChatID(group.ID) == chatID {
+			return group, true
+		}
+	}
+	return groupConfig{}, false
+}
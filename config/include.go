@@ -0,0 +1,128 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/spf13/viper"
+)
+
+// applyIncludes merges the files listed in "include" into settings.
+// Arrays are appended, other values set by an earlier file are overridden with a warning.
+// Patterns are relative to the file that includes them.
+// It returns the file that set each key taken from an included file
+// and the absolute include patterns, which the config watcher also watches.
+func applyIncludes(v *viper.Viper, settings map[string]any) (map[string]string, []string, error) {
+	mainFile := v.ConfigFileUsed()
+	if mainFile == "" {
+		return nil, nil, nil
+	}
+	mainFile, err := filepath.Abs(mainFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	m := &includeMerger{
+		main:  v,
+		setBy: make(map[string]string),
+	}
+	if err := m.include(mainFile, v.GetStringSlice("include"), []string{mainFile}, settings); err != nil {
+		return nil, nil, err
+	}
+	return m.setBy, m.patterns, nil
+}
+
+type includeMerger struct {
+	main *viper.Viper
+	// config key => the included file that last set it
+	setBy    map[string]string
+	patterns []string
+}
+
+func (m *includeMerger) include(from string, patterns []string, stack []string, settings map[string]any) error {
+	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(from), pattern)
+		}
+		m.patterns = append(m.patterns, filepath.Clean(pattern))
+		files, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("%s: invalid include pattern %q: %w", from, pattern, err)
+		}
+		if len(files) == 0 && !strings.ContainsAny(pattern, "*?[") {
+			return fmt.Errorf("%s: included file %s does not exist", from, pattern)
+		}
+		sort.Strings(files)
+		for _, file := range files {
+			if slices.Contains(stack, file) {
+				return fmt.Errorf("include cycle: %s -> %s", strings.Join(stack, " -> "), file)
+			}
+			iv := viper.New()
+			iv.SetConfigFile(file)
+			if err := iv.ReadInConfig(); err != nil {
+				return fmt.Errorf("%s: error reading included file: %w", file, err)
+			}
+			nested := iv.GetStringSlice("include")
+			sub := iv.AllSettings()
+			delete(sub, "include")
+			m.merge("", settings, sub, file)
+			if len(nested) > 0 {
+				if err := m.include(file, nested, append(stack, file), settings); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (m *includeMerger) merge(prefix string, dst, src map[string]any, file string) {
+	for key, value := range src {
+		path := joinSettingPath(prefix, key)
+		existing, ok := dst[key]
+		if !ok {
+			dst[key] = value
+			m.setBy[path] = file
+			continue
+		}
+		if dm, ok := existing.(map[string]any); ok {
+			if sm, ok := value.(map[string]any); ok {
+				m.merge(path, dm, sm, file)
+				continue
+			}
+		}
+		if da, ok := toSlice(existing); ok {
+			if sa, ok := toSlice(value); ok {
+				dst[key] = append(da, sa...)
+				continue
+			}
+		}
+		if !reflect.DeepEqual(existing, value) {
+			if prev, ok := m.setBy[path]; ok {
+				log.Warnf("Config key %s set in %s is overridden by %s", path, prev, file)
+			} else if m.main.InConfig(path) {
+				log.Warnf("Config key %s set in %s is overridden by %s", path, m.main.ConfigFileUsed(), file)
+			}
+		}
+		dst[key] = value
+		m.setBy[path] = file
+	}
+}
+
+func toSlice(value any) ([]any, bool) {
+	switch v := value.(type) {
+	case []any:
+		return v, true
+	case []map[string]any:
+		s := make([]any, len(v))
+		for i, item := range v {
+			s[i] = item
+		}
+		return s, true
+	}
+	return nil, false
+}
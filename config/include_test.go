@@ -0,0 +1,115 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readConfig(t *testing.T, path string) *viper.Viper {
+	t.Helper()
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestLoadInclude(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"config.toml": `
+include = ["storages.toml", "users.d/*.toml"]
+workers = 3
+retry = 3
+threads = 4
+[cache]
+ttl = "24h"
+[telegram]
+app_id = 1
+app_hash = "hash"
+[[storages]]
+name = "local"
+type = "local"
+enable = true
+base_path = "./downloads"
+`,
+		"storages.toml": `
+workers = 5
+[telegram]
+token = "123:abc"
+[[storages]]
+name = "tg"
+type = "telegram"
+enable = true
+chat_id = -100123
+`,
+		"users.d/a.toml": `
+[[users]]
+id = 1
+storages = ["local"]
+`,
+		"users.d/b.yaml": `
+users:
+  - id: 2
+`,
+		"users.d/c.toml": `
+[[users]]
+id = 3
+storages = ["tg"]
+`,
+	})
+	c, err := load(readConfig(t, filepath.Join(dir, "config.toml")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Workers != 5 || c.Telegram.Token != "123:abc" || c.Telegram.AppID != 1 {
+		t.Errorf("scalars not merged: workers=%d token=%q app_id=%d", c.Workers, c.Telegram.Token, c.Telegram.AppID)
+	}
+	if len(c.Storages) != 2 || c.Storages[0].GetName() != "local" || c.Storages[1].GetName() != "tg" {
+		t.Errorf("storages not appended: %v", c.Storages)
+	}
+	var ids []int64
+	for _, u := range c.Users {
+		ids = append(ids, u.ID)
+	}
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 3 {
+		t.Errorf("users = %v, want [1 3]", ids)
+	}
+	// watched for changes by Watch
+	wantIncludes := []string{filepath.Join(dir, "storages.toml"), filepath.Join(dir, "users.d", "*.toml")}
+	if !slices.Equal(c.includes, wantIncludes) {
+		t.Errorf("includes = %v, want %v", c.includes, wantIncludes)
+	}
+}
+
+func TestLoadIncludeCycle(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"config.toml": `include = ["a.toml"]`,
+		"a.toml":      `include = ["sub/b.toml"]`,
+		"sub/b.toml":  `include = ["../config.toml"]`,
+	})
+	_, err := load(readConfig(t, filepath.Join(dir, "config.toml")))
+	if err == nil || !strings.Contains(err.Error(), "include cycle") {
+		t.Fatalf("expected an include cycle error, got %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/log"
+	"github.com/spf13/viper"
+)
+
+const envPrefix = "SAVEANY"
+
+// where the effective value of a config key comes from, in order of precedence
+const (
+	sourceDefault = "default"
+	sourceFile    = "file"
+	sourceEnv     = "env"
+	sourceFlag    = "flag"
+)
+
+var (
+	configFileFlag string
+	flagsMu        sync.RWMutex
+	// config key => value given on the command line
+	flagOverrides = make(map[string]string)
+)
+
+// SetConfigFile uses path as the config file instead of searching for one.
+func SetConfigFile(path string) {
+	configFileFlag = path
+}
+
+// SetFlag overrides the config key with a value given on the command line.
+// Flags take precedence over the environment, the config file and defaults.
+func SetFlag(key, value string) {
+	flagsMu.Lock()
+	defer flagsMu.Unlock()
+	flagOverrides[key] = value
+}
+
+// configKeys returns the keys of all scalar fields of Config, derived from the mapstructure tags.
+// Lists like users and storages can not be set from the environment or flags.
+func configKeys() []string {
+	var keys []string
+	var walk func(prefix string, t reflect.Type)
+	walk = func(prefix string, t reflect.Type) {
+		for i := range t.NumField() {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := strings.Split(field.Tag.Get("mapstructure"), ",")[0]
+			if name == "" || name == "-" {
+				continue
+			}
+			key := joinSettingPath(prefix, name)
+			switch field.Type.Kind() {
+			case reflect.Struct:
+				walk(key, field.Type)
+			case reflect.Slice, reflect.Map, reflect.Interface, reflect.Pointer:
+			default:
+				keys = append(keys, key)
+			}
+		}
+	}
+	walk("", reflect.TypeOf(Config{}))
+	return keys
+}
+
+// envName returns the environment variable for a config key, e.g. SAVEANY_TELEGRAM_PROXY_URL for telegram.proxy.url.
+func envName(key string) string {
+	return envPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// applyOverrides sets the config keys given in the environment or on the command line
+// and returns where the effective value of every key comes from.
+// fileKeys holds the keys set by included files.
+func applyOverrides(v *viper.Viper, settings map[string]any, fileKeys map[string]string) map[string]string {
+	flagsMu.RLock()
+	defer flagsMu.RUnlock()
+	sources := make(map[string]string)
+	for _, key := range configKeys() {
+		sources[key] = sourceDefault
+		if _, ok := fileKeys[key]; ok || v.InConfig(key) {
+			sources[key] = sourceFile
+		}
+		if value, ok := os.LookupEnv(envName(key)); ok {
+			setSetting(settings, key, value)
+			sources[key] = sourceEnv
+		}
+		if value, ok := flagOverrides[key]; ok {
+			setSetting(settings, key, value)
+			sources[key] = sourceFlag
+		}
+	}
+	return sources
+}
+
+func setSetting(settings map[string]any, key string, value any) {
+	parts := strings.Split(key, ".")
+	m := settings
+	for _, part := range parts[:len(parts)-1] {
+		sub, ok := m[part].(map[string]any)
+		if !ok {
+			sub = make(map[string]any)
+			m[part] = sub
+		}
+		m = sub
+	}
+	m[parts[len(parts)-1]] = value
+}
+
+func isSensitiveKey(key string) bool {
+	parts := strings.Split(key, ".")
+	last := parts[len(parts)-1]
+	return slices.Contains(secretKeys, last) || last == "app_hash"
+}
+
+type valueSource struct {
+	value  any
+	source string
+}
+
+// logSources logs the effective value of every config key and where it comes from.
+func logSources(logger *log.Logger, c *Config) {
+	keys := make([]string, 0, len(c.sources))
+	for key := range c.sources {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		vs := c.sources[key]
+		value := vs.value
+		if isSensitiveKey(key) && value != nil && value != "" {
+			value = "******"
+		}
+		logger.Debugf("Config %s = %v (%s)", key, value, vs.source)
+	}
+}
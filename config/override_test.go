@@ -0,0 +1,76 @@
+package config
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestConfigKeys(t *testing.T) {
+	keys := configKeys()
+	for _, want := range []string{"workers", "lang", "telegram.token", "telegram.proxy.url", "cache.ttl", "hook.exec.task_success"} {
+		if !slices.Contains(keys, want) {
+			t.Errorf("configKeys() is missing %q", want)
+		}
+	}
+	for _, unwanted := range []string{"users", "storages", "include"} {
+		if slices.Contains(keys, unwanted) {
+			t.Errorf("configKeys() should not contain %q", unwanted)
+		}
+	}
+	if got := envName("telegram.proxy.url"); got != "SAVEANY_TELEGRAM_PROXY_URL" {
+		t.Errorf("envName() = %q", got)
+	}
+}
+
+func TestLoadOverrides(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"config.toml": `
+workers = 3
+retry = 3
+threads = 4
+lang = "zh-Hans"
+[cache]
+ttl = "24h"
+[telegram]
+token = "from-file"
+app_id = 1
+app_hash = "hash"
+`,
+	})
+	t.Setenv("SAVEANY_TELEGRAM_PROXY_URL", "socks5://127.0.0.1:1080")
+	t.Setenv("SAVEANY_TELEGRAM_TOKEN", "from-env")
+	t.Setenv("SAVEANY_WORKERS", "5")
+	t.Setenv("SAVEANY_CACHE_TTL", "1h")
+	SetFlag("workers", "8")
+	t.Cleanup(func() { delete(flagOverrides, "workers") })
+
+	c, err := load(readConfig(t, filepath.Join(dir, "config.toml")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Telegram.Proxy.URL != "socks5://127.0.0.1:1080" {
+		t.Errorf("telegram.proxy.url = %q", c.Telegram.Proxy.URL)
+	}
+	if c.Telegram.Token != "from-env" {
+		t.Errorf("telegram.token = %q, want the env value", c.Telegram.Token)
+	}
+	if c.Workers != 8 {
+		t.Errorf("workers = %d, want the flag value", c.Workers)
+	}
+	if c.Cache.TTL.String() != "1h0m0s" {
+		t.Errorf("cache.ttl = %s", c.Cache.TTL)
+	}
+	for key, want := range map[string]string{
+		"workers":            sourceFlag,
+		"telegram.token":     sourceEnv,
+		"lang":               sourceFile,
+		"no_clean_cache":     sourceDefault,
+		"telegram.proxy.url": sourceEnv,
+	} {
+		if got := c.sources[key].source; got != want {
+			t.Errorf("source of %s = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// ReloadHook is called after a new config has been validated and swapped in.
+type ReloadHook func(ctx context.Context, oldCfg, newCfg *Config)
+
+var (
+	reloadMu    sync.Mutex
+	reloadHooks []ReloadHook
+)
+
+// sections that are only read once at startup
+var restartRequiredSections = []string{"lang", "workers", "cache", "db", "telegram"}
+
+// OnReload registers a hook for subsystems that support live config updates.
+func OnReload(hook ReloadHook) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	reloadHooks = append(reloadHooks, hook)
+}
+
+// Reload re-reads the config file and swaps it in if it is valid.
+// On error the current config stays active.
+func Reload(ctx context.Context) error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	logger := log.FromContext(ctx)
+
+	if err := viper.ReadInConfig(); err != nil {
+		return fmt.Errorf("error reading config file: %w", err)
+	}
+	newCfg, err := load(viper.GetViper())
+	if err != nil {
+		return err
+	}
+	logSources(logger, newCfg)
+	oldCfg := cfg.Swap(newCfg)
+
+	changed := changedSections(oldCfg, newCfg)
+	if len(changed) == 0 {
+		logger.Info("Config reloaded, nothing changed")
+		return nil
+	}
+	logger.Infof("Config reloaded, changed: %s", strings.Join(changed, ", "))
+	for _, section := range changed {
+		for _, rs := range restartRequiredSections {
+			if section == rs {
+				logger.Warnf("Config [%s] changed, a restart is required for it to take effect", section)
+			}
+		}
+	}
+	for _, hook := range reloadHooks {
+		hook(ctx, oldCfg, newCfg)
+	}
+	return nil
+}
+
+// Watch reloads the config when the config file or one of its included files changes
+// or SIGHUP is received, until ctx is done.
+func Watch(ctx context.Context) error {
+	logger := log.FromContext(ctx)
+	configFile, err := filepath.Abs(viper.ConfigFileUsed())
+	if err != nil {
+		return fmt.Errorf("failed to get config file path: %w", err)
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create config watcher: %w", err)
+	}
+	// watch the directories, editors and k8s configmaps often replace the file instead of writing to it
+	if err := watcher.Add(filepath.Dir(configFile)); err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch config directory: %w", err)
+	}
+	watched := map[string]bool{filepath.Dir(configFile): true}
+	// the config file and the include patterns of the active config
+	var patterns []string
+	watchIncludes := func() {
+		patterns = append([]string{configFile}, C().includes...)
+		for _, pattern := range patterns[1:] {
+			for _, dir := range includeDirs(pattern) {
+				if watched[dir] {
+					continue
+				}
+				if err := watcher.Add(dir); err != nil {
+					logger.Warnf("Failed to watch included config directory %s: %s", dir, err)
+					continue
+				}
+				watched[dir] = true
+			}
+		}
+	}
+	watchIncludes()
+	matches := func(name string) bool {
+		for _, pattern := range patterns {
+			if ok, _ := filepath.Match(pattern, name); ok || name == pattern {
+				return true
+			}
+		}
+		return false
+	}
+
+	reload := func(reason string) {
+		logger.Infof("Reloading config (%s)", reason)
+		if err := Reload(ctx); err != nil {
+			logger.Errorf("Invalid config, keeping the current one: %s", err)
+			return
+		}
+		// the include list may have changed
+		watchIncludes()
+	}
+
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+
+	go func() {
+		defer watcher.Close()
+		defer signal.Stop(sighup)
+		var debounce *time.Timer
+		changed := make(chan struct{}, 1)
+		for {
+			select {
+			case <-ctx.Done():
+				if debounce != nil {
+					debounce.Stop()
+				}
+				return
+			case <-sighup:
+				reload("SIGHUP")
+			case <-changed:
+				reload("file changed")
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if !matches(filepath.Clean(event.Name)) || !event.Has(fsnotify.Write|fsnotify.Create|fsnotify.Remove) {
+					continue
+				}
+				if debounce != nil {
+					debounce.Stop()
+				}
+				debounce = time.AfterFunc(500*time.Millisecond, func() {
+					select {
+					case changed <- struct{}{}:
+					default:
+					}
+				})
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logger.Errorf("Config watcher error: %s", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// includeDirs returns the directories to watch for an include pattern:
+// its own directory, or the directories of the matched files if the directory is a pattern too
+func includeDirs(pattern string) []string {
+	dir := filepath.Dir(pattern)
+	if !strings.ContainsAny(dir, "*?[") {
+		return []string{dir}
+	}
+	files, _ := filepath.Glob(pattern)
+	dirs := make([]string, 0, len(files))
+	for _, file := range files {
+		dirs = append(dirs, filepath.Dir(file))
+	}
+	return dirs
+}
+
+func changedSections(oldCfg, newCfg *Config) []string {
+	var changed []string
+	ov, nv := reflect.ValueOf(*oldCfg), reflect.ValueOf(*newCfg)
+	t := ov.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if reflect.DeepEqual(ov.Field(i).Interface(), nv.Field(i).Interface()) {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("mapstructure"), ",")[0]
+		if name == "" || name == "-" {
+			name = strings.Split(field.Tag.Get("json"), ",")[0]
+		}
+		changed = append(changed, name)
+	}
+	return changed
+}
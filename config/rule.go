@@ -0,0 +1,100 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/duke-git/lancet/v2/slice"
+
+	"github.com/krau/SaveAny-Bot/pkg/ruleengine"
+)
+
+// 存储规则, 匹配的文件不再询问存储位置, 直接保存到规则指定的存储及路径
+type ruleConfig struct {
+	Name         string   `toml:"name" mapstructure:"name" json:"name"`          // 供其他规则的 all_of, any_of 引用
+	AllOf        []string `toml:"all_of" mapstructure:"all_of" json:"all_of"`    // 引用的规则全部匹配
+	AnyOf        []string `toml:"any_of" mapstructure:"any_of" json:"any_of"`    // 引用的规则至少一个匹配
+	Type         string   `toml:"type" mapstructure:"type" json:"type"`          // 见 ruleengine.Types, 仅组合其他规则时可为空
+	Rule         string   `toml:"rule" mapstructure:"rule" json:"rule"`          // 正则表达式, 以逗号分隔的列表或大小范围, 取决于 type
+	Storage      string   `toml:"storage" mapstructure:"storage" json:"storage"` // 为空时该规则仅供引用
+	Path         string   `toml:"path" mapstructure:"path" json:"path"`
+	PathTemplate string   `toml:"path_template" mapstructure:"path_template" json:"path_template"` // 如 {{.ChatTitle}}/{{.Year}}/{{.Month}}, 设置后替代 path
+	Action       string   `toml:"action" mapstructure:"action" json:"action"`                      // save, skip, keep-name, 默认为 save
+	Silent       bool     `toml:"silent" mapstructure:"silent" json:"silent"`                      // 跳过文件时不通知用户
+	Priority     int      `toml:"priority" mapstructure:"priority" json:"priority"`                // 越小越先匹配
+	UserID       int64    `toml:"user_id" mapstructure:"user_id" json:"user_id"`                   // 0 表示对所有用户生效
+}
+
+// RuleDefs returns the rules in the config, they are compiled with ruleengine.NewRule
+func (c *Config) RuleDefs() []ruleengine.Rule {
+	defs := make([]ruleengine.Rule, 0, len(c.Rules))
+	for i, rc := range c.Rules {
+		defs = append(defs, ruleengine.Rule{
+			Name:         rc.Name,
+			AllOf:        rc.AllOf,
+			AnyOf:        rc.AnyOf,
+			Type:         rc.Type,
+			Value:        rc.Rule,
+			Storage:      rc.Storage,
+			Path:         rc.Path,
+			PathTemplate: rc.PathTemplate,
+			Action:       rc.Action,
+			Silent:       rc.Silent,
+			Priority:     rc.Priority,
+			UserID:       rc.UserID,
+			Source:       fmt.Sprintf("rules[%d]", i),
+		})
+	}
+	return defs
+}
+
+// checkRules compiles the rules in the config and resolves their references,
+// errors are prefixed with the path of the rule
+func (c *Config) checkRules() error {
+	var errs []error
+	defs := c.RuleDefs()
+	rules := make([]*ruleengine.Rule, 0, len(defs))
+	for i, def := range defs {
+		r, err := ruleengine.NewRule(def)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rules[%d].%w", i, err))
+			continue
+		}
+		rules = append(rules, r)
+	}
+	_, err := ruleengine.New(rules)
+	return errors.Join(append(errs, err)...)
+}
+
+// CheckRule reports the problems of a rule that depend on the rest of the config,
+// such as undefined storages, each prefixed with the name of the field
+func (c *Config) CheckRule(r ruleengine.Rule) []error {
+	var errs []error
+	add := func(field, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+	}
+	if strings.ToLower(r.Action) == ruleengine.ActionSkip {
+		if r.Storage != "" || r.Path != "" || r.PathTemplate != "" {
+			add("action", "skip rules must not have a storage or path")
+		}
+	} else if r.Storage == "" {
+		if r.Name == "" {
+			add("storage", "storage is required unless the rule has a name to be referenced")
+		}
+	} else if c.GetStorageByName(r.Storage) == nil {
+		add("storage", "storage %q is not defined or not enabled", r.Storage)
+	}
+	if r.Path != "" && r.PathTemplate != "" {
+		add("path_template", "path and path_template must not both be set")
+	}
+	if r.UserID != 0 && !slice.Contain(c.userIDs, r.UserID) {
+		add("user_id", "user %d is not defined in users", r.UserID)
+	}
+	return errs
+}
+
+// RuleAvailable reports whether the user can use the storage of the rule, skip rules are available to everyone
+func (c *Config) RuleAvailable(userID int64, r *ruleengine.Rule) bool {
+	return r.Skips() || c.HasStorage(userID, r.Storage)
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/log"
+)
+
+// keys whose value can also be read from the file given in the "<key>_file" sibling,
+// e.g. telegram.token_file or storages[1].password_file
+var secretKeys = []string{"token", "password", "access_key_id", "secret_access_key", "client_secret", "refresh_token", "access_token", "app_secret", "application_key"}
+
+const secretFileSuffix = "_file"
+
+// loadSecretFiles reads the "<key>_file" entries of the settings tree into "<key>".
+// The file content wins over an inline value.
+func loadSecretFiles(settings map[string]any) error {
+	var errs []error
+	loadSecretFilesValue("", settings, &errs)
+	return errors.Join(errs...)
+}
+
+func loadSecretFilesValue(path string, value any, errs *[]error) {
+	switch v := value.(type) {
+	case map[string]any:
+		for _, key := range secretKeys {
+			fileKey := key + secretFileSuffix
+			file, ok := v[fileKey]
+			if !ok {
+				continue
+			}
+			delete(v, fileKey)
+			fieldPath := joinSettingPath(path, fileKey)
+			filePath, ok := file.(string)
+			if !ok {
+				*errs = append(*errs, fmt.Errorf("%s: must be a string", fieldPath))
+				continue
+			}
+			if filePath == "" {
+				continue
+			}
+			data, err := os.ReadFile(filePath)
+			if err != nil {
+				*errs = append(*errs, fmt.Errorf("%s: %w", fieldPath, err))
+				continue
+			}
+			if inline, ok := v[key].(string); ok && inline != "" {
+				log.Warnf("Both %s and %s are set, using the value from %s", joinSettingPath(path, key), fieldPath, filePath)
+			}
+			v[key] = strings.TrimRight(string(data), "\r\n")
+		}
+		for key, item := range v {
+			loadSecretFilesValue(joinSettingPath(path, key), item, errs)
+		}
+	case []any:
+		for i, item := range v {
+			loadSecretFilesValue(fmt.Sprintf("%s[%d]", path, i), item, errs)
+		}
+	case []map[string]any:
+		for i, item := range v {
+			loadSecretFilesValue(fmt.Sprintf("%s[%d]", path, i), item, errs)
+		}
+	}
+}
+
+func joinSettingPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestLoadSecretFiles(t *testing.T) {
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	if err := os.WriteFile(tokenFile, []byte("123:abc\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	settings := map[string]any{
+		"telegram": map[string]any{"token": "inline", "token_file": tokenFile},
+		"storages": []any{
+			map[string]any{"name": "a"},
+			map[string]any{"name": "b", "password_file": filepath.Join(dir, "missing")},
+		},
+	}
+	err := loadSecretFiles(settings)
+	if err == nil || !strings.Contains(err.Error(), "storages[1].password_file") {
+		t.Fatalf("expected error naming storages[1].password_file, got %v", err)
+	}
+	tg := settings["telegram"].(map[string]any)
+	if tg["token"] != "123:abc" {
+		t.Errorf("token = %q, want %q", tg["token"], "123:abc")
+	}
+	if _, ok := tg["token_file"]; ok {
+		t.Error("token_file should be removed after loading")
+	}
+}
+
+func TestLoadKeepsSecretFilesInViper(t *testing.T) {
+	dir := t.TempDir()
+	passwordFile := filepath.Join(dir, "password")
+	if err := os.WriteFile(passwordFile, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	v := viper.New()
+	v.SetConfigType("toml")
+	if err := v.ReadConfig(strings.NewReader(`
+workers = 3
+retry = 3
+threads = 4
+[cache]
+ttl = "24h"
+[telegram]
+token = "123:abc"
+app_id = 1
+app_hash = "hash"
+[[storages]]
+name = "dav"
+type = "webdav"
+enable = true
+url = "https://dav.example.com"
+base_path = "/saveany"
+username = "user"
+password_file = "` + filepath.ToSlash(passwordFile) + `"
+`)); err != nil {
+		t.Fatal(err)
+	}
+	c, err := load(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Storages) != 1 {
+		t.Fatalf("storages = %v", c.Storages)
+	}
+	// WriteConfig must not persist the plaintext secret
+	storage := v.Get("storages").([]any)[0].(map[string]any)
+	if _, ok := storage["password"]; ok {
+		t.Error("password was written back into viper")
+	}
+	if storage["password_file"] != filepath.ToSlash(passwordFile) {
+		t.Errorf("password_file = %v, want it kept", storage["password_file"])
+	}
+}
@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/krau/SaveAny-Bot/config/types"
+	storenum "github.com/krau/SaveAny-Bot/pkg/enums/storage"
+)
+
+// limits of the parts of a large file in B2
+const (
+	b2MinPartSize = 5 * 1000 * 1000
+	b2MaxPartSize = 5 * 1000 * 1000 * 1000
+)
+
+type B2StorageConfig struct {
+	BaseConfig
+	KeyID          string     `toml:"key_id" mapstructure:"key_id" json:"key_id"`
+	ApplicationKey string     `toml:"application_key" mapstructure:"application_key" json:"application_key"`
+	Bucket         string     `toml:"bucket" mapstructure:"bucket" json:"bucket"`
+	BasePath       string     `toml:"base_path" mapstructure:"base_path" json:"base_path"`
+	PartSize       types.Size `toml:"part_size" mapstructure:"part_size" json:"part_size"`       // 大文件分片的大小, 也是直接上传的文件大小上限, 默认为 32MiB
+	Concurrency    int        `toml:"concurrency" mapstructure:"concurrency" json:"concurrency"` // 同时上传的分片数, 默认为 4
+}
+
+func (b *B2StorageConfig) Validate() error {
+	var errs []error
+	if b.KeyID == "" {
+		errs = append(errs, fmt.Errorf("key_id is required for b2 storage"))
+	}
+	if b.ApplicationKey == "" {
+		errs = append(errs, fmt.Errorf("application_key is required for b2 storage"))
+	}
+	if b.Bucket == "" {
+		errs = append(errs, fmt.Errorf("bucket is required for b2 storage"))
+	}
+	if b.PartSize != 0 && (b.PartSize.Bytes() < b2MinPartSize || b.PartSize.Bytes() > b2MaxPartSize) {
+		errs = append(errs, fmt.Errorf("part_size must be between 5MB and 5GB for b2 storage, got %s", b.PartSize))
+	}
+	if b.Concurrency < 0 {
+		errs = append(errs, fmt.Errorf("concurrency must not be negative for b2 storage"))
+	}
+	return errors.Join(errs...)
+}
+
+func (b *B2StorageConfig) GetType() storenum.StorageType {
+	return storenum.B2
+}
+
+func (b *B2StorageConfig) GetName() string {
+	return b.Name
+}
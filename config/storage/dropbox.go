@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/krau/SaveAny-Bot/config/types"
+	storenum "github.com/krau/SaveAny-Bot/pkg/enums/storage"
+)
+
+// dropboxMaxChunk is the limit of a single upload request
+const dropboxMaxChunk = 150 * 1024 * 1024
+
+type DropboxStorageConfig struct {
+	BaseConfig
+	AccessToken  string     `toml:"access_token" mapstructure:"access_token" json:"access_token"`
+	AppKey       string     `toml:"app_key" mapstructure:"app_key" json:"app_key"`
+	AppSecret    string     `toml:"app_secret" mapstructure:"app_secret" json:"app_secret"` // 使用 PKCE 获取的刷新令牌不需要
+	RefreshToken string     `toml:"refresh_token" mapstructure:"refresh_token" json:"refresh_token"`
+	BasePath     string     `toml:"base_path" mapstructure:"base_path" json:"base_path"`
+	ChunkSize    types.Size `toml:"chunk_size" mapstructure:"chunk_size" json:"chunk_size"` // 分块上传的块大小, 默认为 8MiB
+}
+
+func (d *DropboxStorageConfig) Validate() error {
+	var errs []error
+	if d.AccessToken == "" && d.RefreshToken == "" {
+		errs = append(errs, fmt.Errorf("access_token or refresh_token is required for dropbox storage"))
+	}
+	if d.RefreshToken != "" && d.AppKey == "" {
+		errs = append(errs, fmt.Errorf("app_key is required for dropbox storage with refresh_token"))
+	}
+	if d.ChunkSize < 0 || d.ChunkSize.Bytes() > dropboxMaxChunk {
+		errs = append(errs, fmt.Errorf("chunk_size must be at most 150MiB for dropbox storage, got %s", d.ChunkSize))
+	}
+	return errors.Join(errs...)
+}
+
+func (d *DropboxStorageConfig) GetType() storenum.StorageType {
+	return storenum.Dropbox
+}
+
+func (d *DropboxStorageConfig) GetName() string {
+	return d.Name
+}
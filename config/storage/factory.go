@@ -1,29 +1,47 @@
 package storage
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 
+	vmapstructure "github.com/go-viper/mapstructure/v2"
+	"github.com/krau/SaveAny-Bot/config/types"
 	storenum "github.com/krau/SaveAny-Bot/pkg/enums/storage"
 	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
 )
 
-var storageFactories = map[storenum.StorageType]func(cfg *BaseConfig) (StorageConfig, error){
+var storageFactories = map[storenum.StorageType]func(cfg *BaseConfig, md *mapstructure.Metadata) (StorageConfig, error){
 	storenum.Local:    createStorageConfig(&LocalStorageConfig{}),
 	storenum.Alist:    createStorageConfig(&AlistStorageConfig{}),
 	storenum.Webdav:   createStorageConfig(&WebdavStorageConfig{}),
 	storenum.Minio:    createStorageConfig(&MinioStorageConfig{}),
 	storenum.Telegram: createStorageConfig(&TelegramStorageConfig{}),
+	storenum.Ftp:      createStorageConfig(&FTPStorageConfig{}),
+	storenum.Smb:      createStorageConfig(&SMBStorageConfig{}),
+	storenum.Gdrive:   createStorageConfig(&GDriveStorageConfig{}),
+	storenum.Onedrive: createStorageConfig(&OneDriveStorageConfig{}),
+	storenum.Dropbox:  createStorageConfig(&DropboxStorageConfig{}),
+	storenum.B2:       createStorageConfig(&B2StorageConfig{}),
 }
 
-func createStorageConfig(configType StorageConfig) func(cfg *BaseConfig) (StorageConfig, error) {
-	return func(cfg *BaseConfig) (StorageConfig, error) {
+func createStorageConfig(configType StorageConfig) func(cfg *BaseConfig, md *mapstructure.Metadata) (StorageConfig, error) {
+	return func(cfg *BaseConfig, md *mapstructure.Metadata) (StorageConfig, error) {
 		configValue := reflect.New(reflect.TypeOf(configType).Elem()).Interface().(StorageConfig)
 
 		reflect.ValueOf(configValue).Elem().FieldByName("BaseConfig").Set(reflect.ValueOf(*cfg))
 
-		if err := mapstructure.Decode(cfg.RawConfig, configValue); err != nil {
+		decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+			Metadata:   md,
+			DecodeHook: types.DecodeHook,
+			Result:     configValue,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := decoder.Decode(cfg.RawConfig); err != nil {
 			return nil, fmt.Errorf("failed to decode %s storage config: %w", cfg.Type, err)
 		}
 
@@ -31,38 +49,100 @@ func createStorageConfig(configType StorageConfig) func(cfg *BaseConfig) (Storag
 	}
 }
 
-func LoadStorageConfigs(v *viper.Viper) ([]StorageConfig, error) {
-	var baseConfigs []BaseConfig
-	if err := v.UnmarshalKey("storages", &baseConfigs); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal storage configs: %w", err)
+// LoadStorageConfigs decodes and validates the enabled storages.
+// All invalid entries are reported, prefixed with their path in the config file.
+// The returned unknown keys also cover disabled storages, as a typo in "enable" disables one.
+func LoadStorageConfigs(v *viper.Viper) (configs []StorageConfig, unknownKeys []string, err error) {
+	baseConfigs, err := unmarshalBaseConfigs(v)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal storage configs: %w", err)
 	}
 
-	var configs []StorageConfig
-	for _, baseCfg := range baseConfigs {
+	var errs []error
+	for i, baseCfg := range baseConfigs {
+		path := fmt.Sprintf("storages[%d]", i)
+		st, typeErr := storenum.ParseStorageType(baseCfg.Type)
+		factory, ok := storageFactories[st]
+		var cfg StorageConfig
+		var decodeErr error
+		if typeErr == nil && ok {
+			var md mapstructure.Metadata
+			cfg, decodeErr = factory(&baseCfg, &md)
+			for _, key := range md.Unused {
+				unknownKeys = append(unknownKeys, path+"."+key)
+			}
+		}
 		if !baseCfg.Enable {
 			continue
 		}
-		st, err := storenum.ParseStorageType(baseCfg.Type)
-		if err != nil {
-			return nil, fmt.Errorf("invalid storage type %s for %s: %w", baseCfg.Type, baseCfg.Name, err)
+		if baseCfg.Name == "" {
+			errs = append(errs, fmt.Errorf("%s.name: storage name is required", path))
+			continue
+		}
+		path = fmt.Sprintf("%s (%s)", path, baseCfg.Name)
+		if typeErr != nil {
+			errs = append(errs, fmt.Errorf("%s.type: unknown storage type %q, must be one of: %s",
+				path, baseCfg.Type, strings.Join(storenum.StorageTypeNames(), ", ")))
+			continue
 		}
-
-		factory, ok := storageFactories[st]
 		if !ok {
-			return nil, fmt.Errorf("unsupported storage type: %s", baseCfg.Type)
+			errs = append(errs, fmt.Errorf("%s.type: unsupported storage type: %s", path, baseCfg.Type))
+			continue
 		}
 
-		cfg, err := factory(&baseCfg)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create storage config for %s: %w", baseCfg.Name, err)
+		if decodeErr != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, decodeErr))
+			continue
 		}
 
 		if err := cfg.Validate(); err != nil {
-			return nil, fmt.Errorf("invalid storage config for %s: %w", baseCfg.Name, err)
+			for _, e := range unwrapJoined(err) {
+				errs = append(errs, fmt.Errorf("%s: %w", path, e))
+			}
+			continue
+		}
+		if err := cfg.(interface{ parsePathTemplate() error }).parsePathTemplate(); err != nil {
+			errs = append(errs, fmt.Errorf("%s.path_template: %w", path, err))
+			continue
+		}
+		if baseCfg.MaxFileSize < 0 {
+			errs = append(errs, fmt.Errorf("%s.max_file_size: must not be negative, got %s", path, baseCfg.MaxFileSize))
+			continue
 		}
 
 		configs = append(configs, cfg)
 	}
 
-	return configs, nil
+	return configs, unknownKeys, errors.Join(errs...)
+}
+
+// DisabledStorageNames returns the names of the storages that are declared but not enabled.
+func DisabledStorageNames(v *viper.Viper) []string {
+	baseConfigs, err := unmarshalBaseConfigs(v)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, baseCfg := range baseConfigs {
+		if !baseCfg.Enable && baseCfg.Name != "" {
+			names = append(names, baseCfg.Name)
+		}
+	}
+	return names
+}
+
+// unmarshalBaseConfigs decodes the fields shared by all storages
+func unmarshalBaseConfigs(v *viper.Viper) ([]BaseConfig, error) {
+	var baseConfigs []BaseConfig
+	err := v.UnmarshalKey("storages", &baseConfigs, func(dc *vmapstructure.DecoderConfig) {
+		dc.DecodeHook = vmapstructure.ComposeDecodeHookFunc(types.DecodeHook, dc.DecodeHook)
+	})
+	return baseConfigs, err
+}
+
+func unwrapJoined(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
 }
@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+
+	storenum "github.com/krau/SaveAny-Bot/pkg/enums/storage"
+)
+
+type FTPStorageConfig struct {
+	BaseConfig
+	Host           string `toml:"host" mapstructure:"host" json:"host"`
+	Port           int    `toml:"port" mapstructure:"port" json:"port"` // 默认为 21
+	Username       string `toml:"username" mapstructure:"username" json:"username"`
+	Password       string `toml:"password" mapstructure:"password" json:"password"`
+	BasePath       string `toml:"base_path" mapstructure:"base_path" json:"base_path"`
+	ExplicitTLS    bool   `toml:"explicit_tls" mapstructure:"explicit_tls" json:"explicit_tls"`          // 使用 AUTH TLS 加密连接 (FTPS)
+	SkipTLSVerify  bool   `toml:"skip_tls_verify" mapstructure:"skip_tls_verify" json:"skip_tls_verify"` // 不校验服务器证书
+	DisableEPSV    bool   `toml:"disable_epsv" mapstructure:"disable_epsv" json:"disable_epsv"`          // 被动模式只使用 PASV, 用于不支持 EPSV 的旧服务器
+	ASCIINames     bool   `toml:"ascii_names" mapstructure:"ascii_names" json:"ascii_names"`             // 总是将文件名转换为 ASCII
+	MaxConnections int    `toml:"max_connections" mapstructure:"max_connections" json:"max_connections"` // 同时打开的最大连接数, 默认为 2
+}
+
+func (f *FTPStorageConfig) Validate() error {
+	var errs []error
+	if f.Host == "" {
+		errs = append(errs, fmt.Errorf("host is required for ftp storage"))
+	}
+	if f.Port < 0 || f.Port > 65535 {
+		errs = append(errs, fmt.Errorf("port must be between 1 and 65535 for ftp storage"))
+	}
+	if f.BasePath == "" {
+		errs = append(errs, fmt.Errorf("base_path is required for ftp storage"))
+	}
+	if f.MaxConnections < 0 {
+		errs = append(errs, fmt.Errorf("max_connections must not be negative for ftp storage"))
+	}
+	return errors.Join(errs...)
+}
+
+func (f *FTPStorageConfig) GetType() storenum.StorageType {
+	return storenum.Ftp
+}
+
+func (f *FTPStorageConfig) GetName() string {
+	return f.Name
+}
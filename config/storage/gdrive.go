@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/krau/SaveAny-Bot/config/types"
+	storenum "github.com/krau/SaveAny-Bot/pkg/enums/storage"
+)
+
+// gdriveChunkAlign is the size the chunks of a resumable upload must be a multiple of
+const gdriveChunkAlign = 256 * 1024
+
+type GDriveStorageConfig struct {
+	BaseConfig
+	ServiceAccountFile string     `toml:"service_account_file" mapstructure:"service_account_file" json:"service_account_file"` // 服务账号的 JSON 密钥文件
+	ClientID           string     `toml:"client_id" mapstructure:"client_id" json:"client_id"`
+	ClientSecret       string     `toml:"client_secret" mapstructure:"client_secret" json:"client_secret"`
+	RefreshToken       string     `toml:"refresh_token" mapstructure:"refresh_token" json:"refresh_token"`
+	FolderID           string     `toml:"folder_id" mapstructure:"folder_id" json:"folder_id"` // base_path 所在的文件夹, 默认为我的云端硬盘或共享云端硬盘的根目录
+	DriveID            string     `toml:"drive_id" mapstructure:"drive_id" json:"drive_id"`    // 共享云端硬盘的 ID
+	BasePath           string     `toml:"base_path" mapstructure:"base_path" json:"base_path"`
+	ChunkSize          types.Size `toml:"chunk_size" mapstructure:"chunk_size" json:"chunk_size"` // 分块上传的块大小, 默认为 8MiB
+}
+
+func (g *GDriveStorageConfig) Validate() error {
+	var errs []error
+	oauth := g.ClientID != "" || g.ClientSecret != "" || g.RefreshToken != ""
+	switch {
+	case g.ServiceAccountFile != "" && oauth:
+		errs = append(errs, fmt.Errorf("service_account_file and client_id/client_secret/refresh_token can't be used together for gdrive storage"))
+	case g.ServiceAccountFile == "" && !oauth:
+		errs = append(errs, fmt.Errorf("service_account_file or client_id, client_secret and refresh_token is required for gdrive storage"))
+	case oauth && (g.ClientID == "" || g.ClientSecret == "" || g.RefreshToken == ""):
+		errs = append(errs, fmt.Errorf("client_id, client_secret and refresh_token are all required for gdrive storage"))
+	}
+	if g.ChunkSize < 0 || g.ChunkSize.Bytes()%gdriveChunkAlign != 0 {
+		errs = append(errs, fmt.Errorf("chunk_size must be a multiple of 256KiB for gdrive storage, got %s", g.ChunkSize))
+	}
+	return errors.Join(errs...)
+}
+
+func (g *GDriveStorageConfig) GetType() storenum.StorageType {
+	return storenum.Gdrive
+}
+
+func (g *GDriveStorageConfig) GetName() string {
+	return g.Name
+}
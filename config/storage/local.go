@@ -1,21 +1,65 @@
 package storage
 
 import (
+	"errors"
 	"fmt"
+	"os/user"
+	"strconv"
 
+	"github.com/krau/SaveAny-Bot/config/types"
 	storenum "github.com/krau/SaveAny-Bot/pkg/enums/storage"
 )
 
 type LocalStorageConfig struct {
 	BaseConfig
-	BasePath string `toml:"base_path" mapstructure:"base_path" json:"base_path"`
+	BasePath string         `toml:"base_path" mapstructure:"base_path" json:"base_path"`
+	FileMode types.FileMode `toml:"file_mode" mapstructure:"file_mode" json:"file_mode"` // 新建文件的权限, 如 "0640", 不设置时由 umask 决定
+	DirMode  types.FileMode `toml:"dir_mode" mapstructure:"dir_mode" json:"dir_mode"`    // 新建目录的权限, 如 "0750", 不设置时由 umask 决定
+	Owner    string         `toml:"owner" mapstructure:"owner" json:"owner"`             // 新建文件和目录的所有者, 用户名或 uid
+	Group    string         `toml:"group" mapstructure:"group" json:"group"`             // 新建文件和目录的所属组, 组名或 gid
 }
 
 func (l *LocalStorageConfig) Validate() error {
+	var errs []error
 	if l.BasePath == "" {
-		return fmt.Errorf("path is required for local storage")
+		errs = append(errs, fmt.Errorf("base_path is required for local storage"))
 	}
-	return nil
+	if _, _, err := l.Ownership(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Ownership returns the uid and gid of owner and group, -1 for the ones that are not set
+func (l *LocalStorageConfig) Ownership() (uid, gid int, err error) {
+	uid, gid = -1, -1
+	if l.Owner != "" {
+		id := l.Owner
+		if _, err := strconv.Atoi(id); err != nil {
+			u, err := user.Lookup(l.Owner)
+			if err != nil {
+				return 0, 0, fmt.Errorf("owner: %w", err)
+			}
+			id = u.Uid
+		}
+		if uid, err = strconv.Atoi(id); err != nil {
+			return 0, 0, fmt.Errorf("owner: uid %q is not numeric", id)
+		}
+	}
+	if l.Group != "" {
+		id := l.Group
+		if _, err := strconv.Atoi(id); err != nil {
+			g, err := user.LookupGroup(l.Group)
+			if err != nil {
+				return 0, 0, fmt.Errorf("group: %w", err)
+			}
+			id = g.Gid
+		}
+		if gid, err = strconv.Atoi(id); err != nil {
+			return 0, 0, fmt.Errorf("group: gid %q is not numeric", id)
+		}
+	}
+	return uid, gid, nil
 }
 
 func (l *LocalStorageConfig) GetType() storenum.StorageType {
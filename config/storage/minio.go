@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"errors"
 	"fmt"
 
 	storenum "github.com/krau/SaveAny-Bot/pkg/enums/storage"
@@ -17,19 +18,20 @@ type MinioStorageConfig struct {
 }
 
 func (m *MinioStorageConfig) Validate() error {
+	var errs []error
 	if m.Endpoint == "" {
-		return fmt.Errorf("endpoint is required for minio storage")
+		errs = append(errs, fmt.Errorf("endpoint is required for minio storage"))
 	}
 	if m.AccessKeyID == "" || m.SecretAccessKey == "" {
-		return fmt.Errorf("access_key_id and secret_access_key are required for minio storage")
+		errs = append(errs, fmt.Errorf("access_key_id and secret_access_key are required for minio storage"))
 	}
 	if m.BucketName == "" {
-		return fmt.Errorf("bucket_name is required for minio storage")
+		errs = append(errs, fmt.Errorf("bucket_name is required for minio storage"))
 	}
 	if m.BasePath == "" {
-		return fmt.Errorf("base_path is required for minio storage")
+		errs = append(errs, fmt.Errorf("base_path is required for minio storage"))
 	}
-	return nil
+	return errors.Join(errs...)
 }
 
 func (m *MinioStorageConfig) GetType() storenum.StorageType {
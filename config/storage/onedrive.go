@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/krau/SaveAny-Bot/config/types"
+	storenum "github.com/krau/SaveAny-Bot/pkg/enums/storage"
+)
+
+const (
+	// onedriveChunkAlign is the size the chunks of an upload session must be a multiple of
+	onedriveChunkAlign = 320 * 1024
+	onedriveMaxChunk   = 60 * 1024 * 1024
+)
+
+var onedriveDriveTypes = []string{"personal", "business", "sharepoint"}
+
+type OneDriveStorageConfig struct {
+	BaseConfig
+	ClientID     string     `toml:"client_id" mapstructure:"client_id" json:"client_id"`
+	ClientSecret string     `toml:"client_secret" mapstructure:"client_secret" json:"client_secret"` // 公共客户端不需要
+	RefreshToken string     `toml:"refresh_token" mapstructure:"refresh_token" json:"refresh_token"`
+	Tenant       string     `toml:"tenant" mapstructure:"tenant" json:"tenant"`             // 默认 personal 为 consumers, 其他为 organizations
+	DriveType    string     `toml:"drive_type" mapstructure:"drive_type" json:"drive_type"` // personal, business 或 sharepoint, 默认为 personal
+	SiteID       string     `toml:"site_id" mapstructure:"site_id" json:"site_id"`          // SharePoint 站点 ID
+	DriveID      string     `toml:"drive_id" mapstructure:"drive_id" json:"drive_id"`       // 使用指定的驱动器而不是默认的驱动器
+	BasePath     string     `toml:"base_path" mapstructure:"base_path" json:"base_path"`
+	ChunkSize    types.Size `toml:"chunk_size" mapstructure:"chunk_size" json:"chunk_size"` // 分块上传的块大小, 默认为 10MiB
+}
+
+func (o *OneDriveStorageConfig) Validate() error {
+	var errs []error
+	if o.ClientID == "" {
+		errs = append(errs, fmt.Errorf("client_id is required for onedrive storage"))
+	}
+	if o.RefreshToken == "" {
+		errs = append(errs, fmt.Errorf("refresh_token is required for onedrive storage, it can be obtained with the onedrive-login command"))
+	}
+	if o.DriveType != "" && !slices.Contains(onedriveDriveTypes, o.DriveType) {
+		errs = append(errs, fmt.Errorf("drive_type must be one of %v for onedrive storage, got %q", onedriveDriveTypes, o.DriveType))
+	}
+	if o.DriveType == "sharepoint" && o.SiteID == "" && o.DriveID == "" {
+		errs = append(errs, fmt.Errorf("site_id or drive_id is required for onedrive storage with drive_type sharepoint"))
+	}
+	if o.ChunkSize < 0 || o.ChunkSize.Bytes()%onedriveChunkAlign != 0 || o.ChunkSize.Bytes() > onedriveMaxChunk {
+		errs = append(errs, fmt.Errorf("chunk_size must be a multiple of 320KiB and at most 60MiB for onedrive storage, got %s", o.ChunkSize))
+	}
+	return errors.Join(errs...)
+}
+
+func (o *OneDriveStorageConfig) GetType() storenum.StorageType {
+	return storenum.Onedrive
+}
+
+func (o *OneDriveStorageConfig) GetName() string {
+	return o.Name
+}
@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+
+	storenum "github.com/krau/SaveAny-Bot/pkg/enums/storage"
+)
+
+type SMBStorageConfig struct {
+	BaseConfig
+	Address  string `toml:"address" mapstructure:"address" json:"address"` // host 或 host:port, 默认端口为 445
+	Share    string `toml:"share" mapstructure:"share" json:"share"`
+	BasePath string `toml:"base_path" mapstructure:"base_path" json:"base_path"` // 共享中的路径, 为空时为共享的根目录
+	Username string `toml:"username" mapstructure:"username" json:"username"`
+	Password string `toml:"password" mapstructure:"password" json:"password"`
+	Domain   string `toml:"domain" mapstructure:"domain" json:"domain"`
+	Guest    bool   `toml:"guest" mapstructure:"guest" json:"guest"` // 使用来宾账户登录
+}
+
+func (s *SMBStorageConfig) Validate() error {
+	var errs []error
+	if s.Address == "" {
+		errs = append(errs, fmt.Errorf("address is required for smb storage"))
+	}
+	if s.Share == "" {
+		errs = append(errs, fmt.Errorf("share is required for smb storage"))
+	}
+	if s.Username == "" && !s.Guest {
+		errs = append(errs, fmt.Errorf("username or guest is required for smb storage"))
+	}
+	return errors.Join(errs...)
+}
+
+func (s *SMBStorageConfig) GetType() storenum.StorageType {
+	return storenum.Smb
+}
+
+func (s *SMBStorageConfig) GetName() string {
+	return s.Name
+}
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"errors"
 	"fmt"
 
 	storenum "github.com/krau/SaveAny-Bot/pkg/enums/storage"
@@ -14,13 +15,14 @@ type TelegramStorageConfig struct {
 }
 
 func (m *TelegramStorageConfig) Validate() error {
+	var errs []error
 	if m.ChatID == 0 {
-		return fmt.Errorf("chat_id is required for telegram storage")
+		errs = append(errs, fmt.Errorf("chat_id is required for telegram storage"))
 	}
 	if m.RateLimit < 0 || m.RateBurst < 0 {
-		return fmt.Errorf("rate_limit and rate_burst must be greater than 0 for telegram storage")
+		errs = append(errs, fmt.Errorf("rate_limit and rate_burst must not be negative for telegram storage"))
 	}
-	return nil
+	return errors.Join(errs...)
 }
 
 func (m *TelegramStorageConfig) GetType() storenum.StorageType {
@@ -1,18 +1,63 @@
 package storage
 
 import (
+	"text/template"
+
+	"github.com/krau/SaveAny-Bot/config/types"
 	storenum "github.com/krau/SaveAny-Bot/pkg/enums/storage"
+	"github.com/krau/SaveAny-Bot/pkg/ruleengine"
 )
 
 type StorageConfig interface {
 	Validate() error
 	GetType() storenum.StorageType
 	GetName() string
+	GetMaxFileSize() int64
+	HasPathTemplate() bool
+	RenderPath(data ruleengine.PathData) (string, error)
 }
 
 type BaseConfig struct {
-	Name      string         `toml:"name" mapstructure:"name" json:"name"`
-	Type      string         `toml:"type" mapstructure:"type" json:"type"`
-	Enable    bool           `toml:"enable" mapstructure:"enable" json:"enable"`
-	RawConfig map[string]any `toml:"-" mapstructure:",remain"`
+	Name         string         `toml:"name" mapstructure:"name" json:"name"`
+	Type         string         `toml:"type" mapstructure:"type" json:"type"`
+	Enable       bool           `toml:"enable" mapstructure:"enable" json:"enable"`
+	PathTemplate string         `toml:"path_template" mapstructure:"path_template" json:"path_template"` // 文件在存储中的目录模板, 为空时不使用
+	PathFallback string         `toml:"path_fallback" mapstructure:"path_fallback" json:"path_fallback"` // 缺失的占位符的替代值, 默认为 unknown
+	MaxFileSize  types.Size     `toml:"max_file_size" mapstructure:"max_file_size" json:"max_file_size"` // 保存到该存储的最大文件大小, 0 为不限制
+	RawConfig    map[string]any `toml:"-" mapstructure:",remain"`
+
+	pathTmpl *template.Template
+}
+
+func (c *BaseConfig) GetMaxFileSize() int64 {
+	return c.MaxFileSize.Bytes()
+}
+
+func (c *BaseConfig) parsePathTemplate() error {
+	if c.PathTemplate == "" {
+		return nil
+	}
+	tmpl, err := ruleengine.ParsePathTemplate(c.PathTemplate)
+	if err != nil {
+		return err
+	}
+	c.pathTmpl = tmpl
+	return nil
+}
+
+func (c *BaseConfig) HasPathTemplate() bool {
+	return c.pathTmpl != nil
+}
+
+// RenderPath renders the path template of the storage, the placeholders are cleaned like folder names.
+// It returns an empty path if the storage has no template
+func (c *BaseConfig) RenderPath(data ruleengine.PathData) (string, error) {
+	if c.pathTmpl == nil {
+		return "", nil
+	}
+	fallback := c.PathFallback
+	if fallback == "" {
+		fallback = ruleengine.DefaultPathFallback
+	}
+	return ruleengine.RenderPath(c.pathTmpl, data.Sanitize(fallback))
 }
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"errors"
 	"fmt"
 
 	storenum "github.com/krau/SaveAny-Bot/pkg/enums/storage"
@@ -15,16 +16,17 @@ type WebdavStorageConfig struct {
 }
 
 func (w *WebdavStorageConfig) Validate() error {
+	var errs []error
 	if w.URL == "" {
-		return fmt.Errorf("url is required for webdav storage")
+		errs = append(errs, fmt.Errorf("url is required for webdav storage"))
 	}
 	if w.Username == "" || w.Password == "" {
-		return fmt.Errorf("username and password is required for webdav storage")
+		errs = append(errs, fmt.Errorf("username and password is required for webdav storage"))
 	}
 	if w.BasePath == "" {
-		return fmt.Errorf("base_path is required for webdav storage")
+		errs = append(errs, fmt.Errorf("base_path is required for webdav storage"))
 	}
-	return nil
+	return errors.Join(errs...)
 }
 
 func (w *WebdavStorageConfig) GetType() storenum.StorageType {
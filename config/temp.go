@@ -1,5 +1,7 @@
 package config
 
 type tempConfig struct {
-	BasePath string `toml:"base_path" mapstructure:"base_path" json:"base_path"`
+	BasePath string   `toml:"base_path" mapstructure:"base_path" json:"base_path"`
+	MaxSize  Size     `toml:"max_size" mapstructure:"max_size" json:"max_size"` // 超出时从最早的任务目录开始清理, 0 为不限制
+	MaxAge   Duration `toml:"max_age" mapstructure:"max_age" json:"max_age"`    // 超过该时间未更新的任务目录会被清理, 0 为不限制
 }
@@ -1,20 +1,20 @@
 package config
 
 type telegramConfig struct {
-	Token    string        `toml:"token" mapstructure:"token"`
+	Token    string        `toml:"token" mapstructure:"token" json:"token"`
 	AppID    int           `toml:"app_id" mapstructure:"app_id" json:"app_id"`
 	AppHash  string        `toml:"app_hash" mapstructure:"app_hash" json:"app_hash"`
-	Proxy    tgProxyConfig `toml:"proxy" mapstructure:"proxy"`
+	Proxy    tgProxyConfig `toml:"proxy" mapstructure:"proxy" json:"proxy"`
 	RpcRetry int           `toml:"rpc_retry" mapstructure:"rpc_retry" json:"rpc_retry"`
 	Userbot  userbotConfig `toml:"userbot" mapstructure:"userbot" json:"userbot"` // [TODO]
 }
 
 type userbotConfig struct {
-	Enable  bool   `toml:"enable" mapstructure:"enable"`
-	Session string `toml:"session" mapstructure:"session"`
+	Enable  bool   `toml:"enable" mapstructure:"enable" json:"enable"`
+	Session string `toml:"session" mapstructure:"session" json:"session"`
 }
 
 type tgProxyConfig struct {
-	Enable bool   `toml:"enable" mapstructure:"enable"`
-	URL    string `toml:"url" mapstructure:"url"`
+	Enable bool   `toml:"enable" mapstructure:"enable" json:"enable"`
+	URL    string `toml:"url" mapstructure:"url" json:"url"`
 }
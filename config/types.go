@@ -0,0 +1,9 @@
+package config
+
+import "github.com/krau/SaveAny-Bot/config/types"
+
+// Duration and Size accept human-friendly values like "90s" or "1.5GiB" in the config file.
+type (
+	Duration = types.Duration
+	Size     = types.Size
+)
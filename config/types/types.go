@@ -0,0 +1,189 @@
+// Package types provides config value types that can be written in a human-friendly form.
+package types
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// Duration is a time.Duration written as "90s", "2m30s" or "1h".
+// A plain number is read as seconds, which is deprecated.
+type Duration time.Duration
+
+func (d Duration) Duration() time.Duration {
+	return time.Duration(d)
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+	if n, err := strconv.ParseFloat(s, 64); err == nil {
+		*d = durationFromSeconds(n)
+		return nil
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q, use a value like \"90s\" or \"2m30s\"", s)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func durationFromSeconds(n float64) Duration {
+	d := Duration(n * float64(time.Second))
+	log.Warnf("Duration %v without a unit is read as seconds, this is deprecated, write it as %q instead", n, d.String())
+	return d
+}
+
+// Size is a number of bytes written as "500MB", "1.5GiB" or "4096".
+// Decimal units (KB, MB, GB, TB) are powers of 1000, binary units (KiB, MiB, GiB, TiB) powers of 1024.
+type Size int64
+
+var sizeUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"kb":  1e3,
+	"mb":  1e6,
+	"gb":  1e9,
+	"tb":  1e12,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+}
+
+func (s Size) Bytes() int64 {
+	return int64(s)
+}
+
+func (s Size) String() string {
+	for _, unit := range []string{"TiB", "GiB", "MiB", "KiB"} {
+		mul := int64(sizeUnits[strings.ToLower(unit)])
+		if s != 0 && int64(s)%mul == 0 {
+			return fmt.Sprintf("%d%s", int64(s)/mul, unit)
+		}
+	}
+	return fmt.Sprintf("%dB", int64(s))
+}
+
+func (s Size) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Size) UnmarshalText(text []byte) error {
+	str := strings.TrimSpace(string(text))
+	i := strings.IndexFunc(str, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(str)
+	}
+	num, unit := str[:i], strings.ToLower(strings.TrimSpace(str[i:]))
+	n, err := strconv.ParseFloat(num, 64)
+	mul, ok := sizeUnits[unit]
+	if err != nil || !ok {
+		return fmt.Errorf("invalid size %q, use a value like \"500MB\" or \"1.5GiB\"", str)
+	}
+	bytes := n * mul
+	if bytes > math.MaxInt64 {
+		return fmt.Errorf("size %q is too large", str)
+	}
+	*s = Size(bytes)
+	return nil
+}
+
+// FileMode is a permission written as an octal string like "0640" or "750".
+// A plain number is read as the permission bits, e.g. 0o640 in TOML, which is deprecated
+// because 640 would silently be a different mode.
+type FileMode uint32
+
+func (m FileMode) Mode() os.FileMode {
+	return os.FileMode(m) & os.ModePerm
+}
+
+func (m FileMode) String() string {
+	return fmt.Sprintf("%04o", uint32(m))
+}
+
+func (m FileMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *FileMode) UnmarshalText(text []byte) error {
+	str := strings.TrimSpace(string(text))
+	n, err := strconv.ParseUint(strings.TrimPrefix(str, "0o"), 8, 32)
+	if err != nil || n > uint64(os.ModePerm) {
+		return fmt.Errorf("invalid file mode %q, use an octal value like \"0640\"", str)
+	}
+	*m = FileMode(n)
+	return nil
+}
+
+func fileModeFromNumber(n float64) (FileMode, error) {
+	if n < 0 || n > float64(os.ModePerm) || n != math.Trunc(n) {
+		return 0, fmt.Errorf("invalid file mode %v, use an octal string like \"0640\"", n)
+	}
+	m := FileMode(n)
+	log.Warnf("File mode %v written as a number is deprecated, write it as %q instead", n, m.String())
+	return m, nil
+}
+
+var (
+	durationType = reflect.TypeOf(Duration(0))
+	sizeType     = reflect.TypeOf(Size(0))
+	fileModeType = reflect.TypeOf(FileMode(0))
+)
+
+// DecodeHook converts strings and plain numbers into Duration, Size and FileMode values.
+// It has the plain hook function signature, so it works with any mapstructure version.
+func DecodeHook(from, to reflect.Type, data any) (any, error) {
+	if to != durationType && to != sizeType && to != fileModeType {
+		return data, nil
+	}
+	var n float64
+	switch v := reflect.ValueOf(data); v.Kind() {
+	case reflect.String:
+		switch to {
+		case durationType:
+			var d Duration
+			err := d.UnmarshalText([]byte(v.String()))
+			return d, err
+		case fileModeType:
+			var m FileMode
+			err := m.UnmarshalText([]byte(v.String()))
+			return m, err
+		}
+		var s Size
+		err := s.UnmarshalText([]byte(v.String()))
+		return s, err
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = v.Float()
+	default:
+		return data, nil
+	}
+	switch to {
+	case durationType:
+		return durationFromSeconds(n), nil
+	case fileModeType:
+		return fileModeFromNumber(n)
+	}
+	return Size(n), nil
+}
@@ -0,0 +1,111 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDurationUnmarshalText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"90s", 90 * time.Second},
+		{"2m30s", 150 * time.Second},
+		{"1.5h", 90 * time.Minute},
+		{"3600", time.Hour},
+	}
+	for _, tt := range tests {
+		var d Duration
+		if err := d.UnmarshalText([]byte(tt.in)); err != nil {
+			t.Errorf("%q: %v", tt.in, err)
+			continue
+		}
+		if d.Duration() != tt.want {
+			t.Errorf("%q = %s, want %s", tt.in, d, tt.want)
+		}
+	}
+	var d Duration
+	if err := d.UnmarshalText([]byte("5 minutes")); err == nil {
+		t.Error("expected an error for an invalid duration")
+	}
+}
+
+func TestSizeUnmarshalText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"4096", 4096},
+		{"500MB", 500_000_000},
+		{"1.5GiB", 1536 << 20},
+		{"10 kib", 10 << 10},
+		{"2TB", 2_000_000_000_000},
+	}
+	for _, tt := range tests {
+		var s Size
+		if err := s.UnmarshalText([]byte(tt.in)); err != nil {
+			t.Errorf("%q: %v", tt.in, err)
+			continue
+		}
+		if s.Bytes() != tt.want {
+			t.Errorf("%q = %d, want %d", tt.in, s.Bytes(), tt.want)
+		}
+	}
+	for _, in := range []string{"", "MB", "10XB", "1.2.3GB"} {
+		var s Size
+		if err := s.UnmarshalText([]byte(in)); err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+}
+
+func TestFileModeUnmarshalText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want uint32
+	}{
+		{"0640", 0o640},
+		{"750", 0o750},
+		{"0o755", 0o755},
+	}
+	for _, tt := range tests {
+		var m FileMode
+		if err := m.UnmarshalText([]byte(tt.in)); err != nil {
+			t.Errorf("%q: %v", tt.in, err)
+			continue
+		}
+		if uint32(m) != tt.want {
+			t.Errorf("%q = %s, want %04o", tt.in, m, tt.want)
+		}
+	}
+	for _, in := range []string{"", "rwx", "0890", "2775"} {
+		var m FileMode
+		if err := m.UnmarshalText([]byte(in)); err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+}
+
+func TestDecodeHook(t *testing.T) {
+	got, err := DecodeHook(reflect.TypeOf(0), durationType, 30)
+	if err != nil || got != Duration(30*time.Second) {
+		t.Errorf("int seconds: got %v, %v", got, err)
+	}
+	got, err = DecodeHook(reflect.TypeOf(""), sizeType, "1KiB")
+	if err != nil || got != Size(1024) {
+		t.Errorf("size string: got %v, %v", got, err)
+	}
+	got, err = DecodeHook(reflect.TypeOf(0), fileModeType, 0o640)
+	if err != nil || got != FileMode(0o640) {
+		t.Errorf("file mode number: got %v, %v", got, err)
+	}
+	if _, err = DecodeHook(reflect.TypeOf(0), fileModeType, 640); err == nil {
+		t.Error("decimal 640 is not a valid file mode")
+	}
+	got, err = DecodeHook(reflect.TypeOf(""), reflect.TypeOf(""), "1KiB")
+	if err != nil || got != "1KiB" {
+		t.Errorf("other types should pass through: got %v, %v", got, err)
+	}
+}
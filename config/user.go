@@ -4,18 +4,76 @@ import (
 	"github.com/duke-git/lancet/v2/slice"
 )
 
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
 type userConfig struct {
 	ID        int64    `toml:"id" mapstructure:"id" json:"id"`                      // telegram user id
 	Storages  []string `toml:"storages" mapstructure:"storages" json:"storages"`    // storage names
 	Blacklist bool     `toml:"blacklist" mapstructure:"blacklist" json:"blacklist"` // 黑名单模式, storage names 中的存储将不会被使用, 默认为白名单模式
+	// 默认存储及路径, 设置后保存文件时不再询问存储位置, 可被 /setdefault 覆盖
+	DefaultStorage string `toml:"default_storage" mapstructure:"default_storage" json:"default_storage"`
+	DefaultPath    string `toml:"default_path" mapstructure:"default_path" json:"default_path"`
+	// admin 或 user, 默认为 user. 管理员可以使用重载配置, 取消所有任务等全局命令
+	Role string `toml:"role" mapstructure:"role" json:"role"`
+	// 限制, 0 表示不限制
+	MaxConcurrentTasks int  `toml:"max_concurrent_tasks" mapstructure:"max_concurrent_tasks" json:"max_concurrent_tasks"` // 同时执行的任务数, 超出的任务排队等待
+	MaxQueueSize       int  `toml:"max_queue_size" mapstructure:"max_queue_size" json:"max_queue_size"`                   // 排队及执行中的任务数, 超出时拒绝添加
+	DailyTrafficLimit  Size `toml:"daily_traffic_limit" mapstructure:"daily_traffic_limit" json:"daily_traffic_limit"`    // 每日下载流量, 达到后拒绝添加
+}
+
+// UserLimits are the task limits of a user, zero values mean unlimited
+type UserLimits struct {
+	MaxConcurrentTasks int
+	MaxQueueSize       int
+	DailyTrafficLimit  int64
+}
+
+// GetUserDefault returns the default storage name and path of the user from the config
+func (c *Config) GetUserDefault(userID int64) (storageName, dirPath string) {
+	for _, user := range c.Users {
+		if user.ID == userID {
+			return user.DefaultStorage, user.DefaultPath
+		}
+	}
+	return "", ""
 }
 
-var userIDs []int64
-var storages []string
-var userStorages = make(map[int64][]string)
+// GetUserLimits returns the task limits of the user from the config
+func (c *Config) GetUserLimits(userID int64) UserLimits {
+	for _, user := range c.Users {
+		if user.ID == userID {
+			return UserLimits{
+				MaxConcurrentTasks: user.MaxConcurrentTasks,
+				MaxQueueSize:       user.MaxQueueSize,
+				DailyTrafficLimit:  user.DailyTrafficLimit.Bytes(),
+			}
+		}
+	}
+	return UserLimits{}
+}
+
+// IsAdmin reports whether the user has the admin role
+func (c *Config) IsAdmin(userID int64) bool {
+	for _, user := range c.Users {
+		if user.ID == userID {
+			return user.Role == RoleAdmin
+		}
+	}
+	return false
+}
+
+// HasAdmin reports whether at least one user has the admin role
+func (c *Config) HasAdmin() bool {
+	return slice.ContainBy(c.Users, func(user userConfig) bool {
+		return user.Role == RoleAdmin
+	})
+}
 
 func (c *Config) GetStorageNamesByUserID(userID int64) []string {
-	us, ok := userStorages[userID]
+	us, ok := c.userStorages[userID]
 	if ok {
 		return us
 	}
@@ -23,11 +81,11 @@ func (c *Config) GetStorageNamesByUserID(userID int64) []string {
 }
 
 func (c *Config) GetUsersID() []int64 {
-	return userIDs
+	return c.userIDs
 }
 
 func (c *Config) HasStorage(userID int64, storageName string) bool {
-	us, ok := userStorages[userID]
+	us, ok := c.userStorages[userID]
 	if !ok {
 		return false
 	}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/duke-git/lancet/v2/slice"
+	"github.com/krau/SaveAny-Bot/common/i18n"
+	"github.com/krau/SaveAny-Bot/common/i18n/i18nk"
+	"github.com/krau/SaveAny-Bot/pkg/ruleengine"
+)
+
+// Validate checks the decoded config and reports all problems at once,
+// each prefixed with its path in the config file.
+func (c *Config) Validate() error {
+	var errs []error
+	add := func(path, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", path, fmt.Sprintf(format, args...)))
+	}
+
+	if c.Workers < 1 || c.Retry < 1 {
+		errs = append(errs, errors.New(i18n.TWithoutInit(c.Lang, i18nk.ConfigInvalidWorkersOrRetry, map[string]any{
+			"Workers": c.Workers,
+			"Retry":   c.Retry,
+		})))
+	}
+	if c.Threads < 1 {
+		add("threads", "must be greater than 0, got %d", c.Threads)
+	}
+	if c.MaxFileSize < 0 {
+		add("max_file_size", "must not be negative, got %s", c.MaxFileSize)
+	}
+	if c.FreeSpaceMargin < 0 {
+		add("free_space_margin", "must not be negative, got %s", c.FreeSpaceMargin)
+	}
+	if c.Temp.MaxSize < 0 {
+		add("temp.max_size", "must not be negative, got %s", c.Temp.MaxSize)
+	}
+	if c.Temp.MaxAge < 0 {
+		add("temp.max_age", "must not be negative, got %s", c.Temp.MaxAge)
+	}
+	if c.Cache.TTL <= 0 {
+		add("cache.ttl", "must be greater than 0, got %s", c.Cache.TTL)
+	}
+
+	if c.Telegram.Token == "" {
+		add("telegram.token", "bot token is required")
+	}
+	if c.Telegram.AppID == 0 || c.Telegram.AppHash == "" {
+		add("telegram.app_id", "app_id and app_hash are required")
+	}
+	if c.Telegram.RpcRetry < 0 {
+		add("telegram.rpc_retry", "must not be negative, got %d", c.Telegram.RpcRetry)
+	}
+	if c.Telegram.Proxy.Enable && c.Telegram.Proxy.URL == "" {
+		add("telegram.proxy.url", "proxy url is required when the proxy is enabled")
+	}
+
+	storageNames := make(map[string]struct{})
+	for _, storage := range c.Storages {
+		if _, ok := storageNames[storage.GetName()]; ok {
+			errs = append(errs, errors.New(i18n.TWithoutInit(c.Lang, i18nk.ConfigInvalidDuplicateStorageName, map[string]any{
+				"Name": storage.GetName(),
+			})))
+		}
+		storageNames[storage.GetName()] = struct{}{}
+	}
+
+	userIDs := make(map[int64]struct{})
+	for i, user := range c.Users {
+		path := fmt.Sprintf("users[%d]", i)
+		if user.ID == 0 {
+			add(path+".id", "user id is required")
+		} else if _, ok := userIDs[user.ID]; ok {
+			add(path+".id", "duplicate user id %d", user.ID)
+		}
+		userIDs[user.ID] = struct{}{}
+		for j, name := range user.Storages {
+			if _, ok := storageNames[name]; ok || slice.Contain(c.disabledStorages, name) {
+				continue
+			}
+			add(fmt.Sprintf("%s.storages[%d]", path, j), "storage %q is not defined", name)
+		}
+		if user.Role != "" && user.Role != RoleAdmin && user.Role != RoleUser {
+			add(path+".role", "unknown role %q, must be one of: %s, %s", user.Role, RoleAdmin, RoleUser)
+		}
+		if user.MaxConcurrentTasks < 0 {
+			add(path+".max_concurrent_tasks", "must not be negative, got %d", user.MaxConcurrentTasks)
+		}
+		if user.MaxQueueSize < 0 {
+			add(path+".max_queue_size", "must not be negative, got %d", user.MaxQueueSize)
+		}
+		if user.DailyTrafficLimit < 0 {
+			add(path+".daily_traffic_limit", "must not be negative, got %s", user.DailyTrafficLimit)
+		}
+		if user.DefaultStorage != "" {
+			if _, ok := storageNames[user.DefaultStorage]; !ok {
+				add(path+".default_storage", "storage %q is not defined or not enabled", user.DefaultStorage)
+			} else if available := c.userStorages[user.ID]; !slice.Contain(available, user.DefaultStorage) {
+				add(path+".default_storage", "storage %q is not available to user %d", user.DefaultStorage, user.ID)
+			}
+		}
+	}
+
+	for i, def := range c.RuleDefs() {
+		for _, err := range c.CheckRule(def) {
+			errs = append(errs, fmt.Errorf("rules[%d].%w", i, err))
+		}
+	}
+
+	groupIDs := make(map[int64]struct{})
+	for i, group := range c.Groups {
+		path := fmt.Sprintf("groups[%d]", i)
+		id := ruleengine.NormalizeChatID(group.ID)
+		if id == 0 {
+			add(path+".id", "group id is required")
+		} else if _, ok := groupIDs[id]; ok {
+			add(path+".id", "duplicate group id %d", group.ID)
+		}
+		groupIDs[id] = struct{}{}
+		if group.DefaultStorage == "" {
+			add(path+".default_storage", "default storage is required")
+		} else if _, ok := storageNames[group.DefaultStorage]; !ok {
+			add(path+".default_storage", "storage %q is not defined or not enabled", group.DefaultStorage)
+		}
+		for j, userID := range group.Users {
+			if _, ok := userIDs[userID]; !ok {
+				add(fmt.Sprintf("%s.users[%d]", path, j), "user %d is not defined in users", userID)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,179 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestLoadReportsAllErrors(t *testing.T) {
+	v := viper.New()
+	v.SetConfigType("toml")
+	err := v.ReadConfig(strings.NewReader(`
+workers = 3
+retry = 3
+threads = 4
+[cache]
+ttl = "24h"
+[telegram]
+token = "123:abc"
+app_id = 1
+app_hash = "hash"
+[[storages]]
+name = "local"
+type = "local"
+enable = true
+base_path = "./downloads"
+[[storages]]
+name = "dav"
+type = "webdev"
+enable = true
+[[storages]]
+name = "dated"
+type = "local"
+enable = true
+base_path = "./dated"
+path_template = "{{.Date}}"
+[[storages]]
+name = "off"
+type = "webdav"
+enable = false
+[[users]]
+id = 1
+storages = ["local", "off", "missing"]
+[[users]]
+id = 1
+role = "owner"
+max_queue_size = -1
+[[groups]]
+id = -1001234567890
+users = [1, 2]
+[[rules]]
+type = "filename-regex"
+rule = "S\\d+E("
+storage = "local"
+[[rules]]
+type = "size"
+rule = "1GB-10MB"
+storage = "local"
+[[rules]]
+name = "loop"
+all_of = ["loop"]
+storage = "local"
+[[rules]]
+type = "media-type"
+rule = "sticker"
+action = "skip"
+storage = "local"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = load(v)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{
+		"storages[1] (dav).type",
+		"storages[2] (dated).path_template",
+		"users[0].storages[2]",
+		"users[1].id: duplicate user id 1",
+		`users[1].role: unknown role "owner"`,
+		"users[1].max_queue_size: must not be negative",
+		"groups[0].default_storage: default storage is required",
+		"groups[0].users[1]: user 2 is not defined",
+		"rules[0].rule: invalid regex",
+		`rules[1].rule: invalid size expression "1GB-10MB"`,
+		"rules[2]: reference cycle: loop -> loop",
+		"rules[3].action: skip rules must not have a storage or path",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not mention %q:\n%s", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), `"off"`) {
+		t.Errorf("disabled storage should not be reported:\n%s", err)
+	}
+}
+
+func TestLoadStrictUnknownKeys(t *testing.T) {
+	v := viper.New()
+	v.SetConfigType("toml")
+	err := v.ReadConfig(strings.NewReader(`
+strict_config = true
+workers = 3
+retry = 3
+threads = 4
+max_token = 200
+[cache]
+ttl = "24h"
+[telegram]
+token = "123:abc"
+app_id = 1
+app_hash = "hash"
+[[storages]]
+name = "local"
+type = "local"
+enabel = true
+base_path = "./downloads"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = load(v)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{"max_token", "storages[0].enabel"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not mention %q: %s", want, err)
+		}
+	}
+}
+
+func TestGetMaxFileSize(t *testing.T) {
+	v := viper.New()
+	v.SetConfigType("toml")
+	err := v.ReadConfig(strings.NewReader(`
+workers = 3
+retry = 3
+threads = 4
+max_file_size = "2GB"
+[cache]
+ttl = "24h"
+[telegram]
+token = "123:abc"
+app_id = 1
+app_hash = "hash"
+[[storages]]
+name = "local"
+type = "local"
+enable = true
+base_path = "./downloads"
+[[storages]]
+name = "small"
+type = "local"
+enable = true
+base_path = "./small"
+max_file_size = "500MB"
+[[storages]]
+name = "large"
+type = "local"
+enable = true
+base_path = "./large"
+max_file_size = "4GB"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := load(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]int64{"local": 2e9, "small": 500e6, "large": 2e9} {
+		if got := c.GetMaxFileSize(name); got != want {
+			t.Errorf("GetMaxFileSize(%q) = %d, want %d", name, got, want)
+		}
+	}
+}
@@ -5,33 +5,64 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
+	"sync/atomic"
 
+	"github.com/charmbracelet/log"
 	"github.com/duke-git/lancet/v2/slice"
+	"github.com/go-viper/mapstructure/v2"
 	"github.com/krau/SaveAny-Bot/common/i18n"
 	"github.com/krau/SaveAny-Bot/common/i18n/i18nk"
 	"github.com/krau/SaveAny-Bot/config/storage"
+	"github.com/krau/SaveAny-Bot/config/types"
 	"github.com/spf13/viper"
 )
 
 type Config struct {
 	Lang         string `toml:"lang" mapstructure:"lang" json:"lang"`
-	Workers      int    `toml:"workers" mapstructure:"workers"`
-	Retry        int    `toml:"retry" mapstructure:"retry"`
+	Workers      int    `toml:"workers" mapstructure:"workers" json:"workers"`
+	Retry        int    `toml:"retry" mapstructure:"retry" json:"retry"`
 	NoCleanCache bool   `toml:"no_clean_cache" mapstructure:"no_clean_cache" json:"no_clean_cache"`
 	Threads      int    `toml:"threads" mapstructure:"threads" json:"threads"`
 	Stream       bool   `toml:"stream" mapstructure:"stream" json:"stream"`
+	StrictConfig bool   `toml:"strict_config" mapstructure:"strict_config" json:"strict_config"` // 存在未知配置项时拒绝加载
+
+	Include []string `toml:"include" mapstructure:"include" json:"include"` // 合并到本配置中的其他配置文件, 支持通配符
+
+	DBRulePriority int `toml:"db_rule_priority" mapstructure:"db_rule_priority" json:"db_rule_priority"` // 加到通过 /ruleadd 添加的规则的优先级上
+
+	MaxFileSize     Size `toml:"max_file_size" mapstructure:"max_file_size" json:"max_file_size"`             // 允许保存的最大文件大小, 0 为不限制
+	FreeSpaceMargin Size `toml:"free_space_margin" mapstructure:"free_space_margin" json:"free_space_margin"` // 下载前要求磁盘在容纳文件后至少剩余的空间
 
 	Cache    cacheConfig             `toml:"cache" mapstructure:"cache" json:"cache"`
 	Users    []userConfig            `toml:"users" mapstructure:"users" json:"users"`
-	Temp     tempConfig              `toml:"temp" mapstructure:"temp"`
-	DB       dbConfig                `toml:"db" mapstructure:"db"`
-	Telegram telegramConfig          `toml:"telegram" mapstructure:"telegram"`
+	Groups   []groupConfig           `toml:"groups" mapstructure:"groups" json:"groups"`
+	Rules    []ruleConfig            `toml:"rules" mapstructure:"rules" json:"rules"`
+	Temp     tempConfig              `toml:"temp" mapstructure:"temp" json:"temp"`
+	DB       dbConfig                `toml:"db" mapstructure:"db" json:"db"`
+	Telegram telegramConfig          `toml:"telegram" mapstructure:"telegram" json:"telegram"`
 	Storages []storage.StorageConfig `toml:"-" mapstructure:"-" json:"storages"`
 	Hook     hookConfig              `toml:"hook" mapstructure:"hook" json:"hook"`
+
+	sources          map[string]valueSource
+	userIDs          []int64
+	userStorages     map[int64][]string
+	disabledStorages []string
+	includes         []string
+}
+
+var cfg atomic.Pointer[Config]
+
+func init() {
+	cfg.Store(&Config{})
 }
 
-var Cfg *Config = &Config{}
+// C returns the active config. It is replaced as a whole on reload, so callers
+// should not keep the returned pointer around for longer than they need it.
+func C() *Config {
+	return cfg.Load()
+}
 
 func (c Config) GetStorageByName(name string) storage.StorageConfig {
 	for _, storage := range c.Storages {
@@ -42,12 +73,20 @@ func (c Config) GetStorageByName(name string) storage.StorageConfig {
 	return nil
 }
 
+// GetMaxFileSize returns the max size in bytes of the files saved to the storage,
+// the smaller one of the global and the storage limit. 0 means unlimited
+func (c Config) GetMaxFileSize(storageName string) int64 {
+	limit := c.MaxFileSize.Bytes()
+	if stor := c.GetStorageByName(storageName); stor != nil {
+		if s := stor.GetMaxFileSize(); s > 0 && (limit == 0 || s < limit) {
+			limit = s
+		}
+	}
+	return limit
+}
+
 func Init(ctx context.Context) error {
-	viper.SetConfigName("config")
-	viper.AddConfigPath(".")
-	viper.AddConfigPath("/etc/saveany/")
-	viper.SetConfigType("toml")
-	viper.SetEnvPrefix("SAVEANY")
+	viper.SetEnvPrefix(envPrefix)
 	viper.AutomaticEnv()
 	replacer := strings.NewReplacer(".", "_")
 	viper.SetEnvKeyReplacer(replacer)
@@ -60,7 +99,7 @@ func Init(ctx context.Context) error {
 		"threads": 4,
 
 		// 缓存配置
-		"cache.ttl":          86400,
+		"cache.ttl":          "24h",
 		"cache.num_counters": 1e5,
 		"cache.max_cost":     1e6,
 
@@ -72,7 +111,8 @@ func Init(ctx context.Context) error {
 		"telegram.userbot.session": "data/usersession.db",
 
 		// 临时目录
-		"temp.base_path": "cache/",
+		"temp.base_path":    "cache/",
+		"free_space_margin": "100MB",
 
 		// 数据库
 		"db.path":    "data/saveany.db",
@@ -83,79 +123,149 @@ func Init(ctx context.Context) error {
 		viper.SetDefault(key, value)
 	}
 
-	if err := viper.SafeWriteConfigAs("config.toml"); err != nil {
-		if _, ok := err.(viper.ConfigFileAlreadyExistsError); !ok {
-			return fmt.Errorf("error saving default config: %w", err)
+	configFile := configFileFlag
+	if configFile != "" {
+		if _, err := os.Stat(configFile); err != nil {
+			return fmt.Errorf("error reading config file: %w", err)
+		}
+	} else if configFile = findConfigFile(); configFile == "" {
+		configFile = "config.toml"
+		if err := viper.SafeWriteConfigAs(configFile); err != nil {
+			if _, ok := err.(viper.ConfigFileAlreadyExistsError); !ok {
+				return fmt.Errorf("error saving default config: %w", err)
+			}
 		}
 	}
+	// the format is detected from the file extension
+	viper.SetConfigFile(configFile)
 
 	if err := viper.ReadInConfig(); err != nil {
 		fmt.Println("Error reading config file, ", err)
 		os.Exit(1)
 	}
 
-	if err := viper.Unmarshal(Cfg); err != nil {
-		fmt.Println("Error unmarshalling config file, ", err)
-		os.Exit(1)
+	newCfg, err := load(viper.GetViper())
+	if err != nil {
+		return err
 	}
 
-	storagesConfig, err := storage.LoadStorageConfigs(viper.GetViper())
-	if err != nil {
-		return fmt.Errorf("error loading storage configs: %w", err)
+	fmt.Println(i18n.TWithoutInit(newCfg.Lang, i18nk.LoadedStorages, map[string]any{
+		"Count": len(newCfg.Storages),
+	}))
+	for _, storage := range newCfg.Storages {
+		fmt.Printf("  - %s (%s)\n", storage.GetName(), storage.GetType())
 	}
-	Cfg.Storages = storagesConfig
+	logSources(log.FromContext(ctx), newCfg)
+	cfg.Store(newCfg)
+	return nil
+}
 
-	storageNames := make(map[string]struct{})
-	for _, storage := range Cfg.Storages {
-		if _, ok := storageNames[storage.GetName()]; ok {
-			return errors.New(i18n.TWithoutInit(Cfg.Lang, i18nk.ConfigInvalidDuplicateStorageName, map[string]any{
-				"Name": storage.GetName(),
-			}))
-		}
-		storageNames[storage.GetName()] = struct{}{}
+// load decodes and validates the config held by v into a new Config,
+// leaving the active one untouched.
+func load(v *viper.Viper) (*Config, error) {
+	// the resolve steps below modify settings in place, AllSettings shares the slices
+	// of the config file with viper, which would write them back on WriteConfig
+	settings := copySettings(v.AllSettings()).(map[string]any)
+	includedKeys, includes, err := applyIncludes(v, settings)
+	if err != nil {
+		return nil, err
+	}
+	if err := expandEnv(settings); err != nil {
+		return nil, fmt.Errorf("error expanding environment variables: %w", err)
+	}
+	if err := loadSecretFiles(settings); err != nil {
+		return nil, fmt.Errorf("error loading secret files: %w", err)
+	}
+	sources := applyOverrides(v, settings, includedKeys)
+	v = viper.New()
+	if err := v.MergeConfigMap(settings); err != nil {
+		return nil, fmt.Errorf("error merging expanded config: %w", err)
 	}
 
-	fmt.Println(i18n.TWithoutInit(Cfg.Lang, i18nk.LoadedStorages, map[string]any{
-		"Count": len(Cfg.Storages),
-	}))
-	for _, storage := range Cfg.Storages {
-		fmt.Printf("  - %s (%s)\n", storage.GetName(), storage.GetType())
+	c := &Config{includes: includes}
+	var md mapstructure.Metadata
+	if err := v.Unmarshal(c, func(dc *mapstructure.DecoderConfig) {
+		dc.Metadata = &md
+		dc.DecodeHook = mapstructure.ComposeDecodeHookFunc(types.DecodeHook, dc.DecodeHook)
+	}); err != nil {
+		return nil, fmt.Errorf("error unmarshalling config file: %w", err)
 	}
 
-	if Cfg.Workers < 1 || Cfg.Retry < 1 {
-		return errors.New(i18n.TWithoutInit(Cfg.Lang, i18nk.ConfigInvalidWorkersOrRetry, map[string]any{
-			"Workers": Cfg.Workers,
-			"Retry":   Cfg.Retry,
-		}))
+	c.sources = make(map[string]valueSource, len(sources))
+	for key, source := range sources {
+		c.sources[key] = valueSource{value: v.Get(key), source: source}
 	}
 
-	for _, storage := range Cfg.Storages {
+	storagesConfig, unknownStorageKeys, storageErr := storage.LoadStorageConfigs(v)
+	unknownKeys := slice.Filter(md.Unused, func(_ int, key string) bool { return key != "storages" })
+	unknownKeys = append(unknownKeys, unknownStorageKeys...)
+	sort.Strings(unknownKeys)
+	if len(unknownKeys) > 0 {
+		if c.StrictConfig {
+			return nil, fmt.Errorf("unknown config keys: %s", strings.Join(unknownKeys, ", "))
+		}
+		for _, key := range unknownKeys {
+			log.Warnf("Unknown config key: %s", key)
+		}
+	}
+	c.Storages = storagesConfig
+	c.disabledStorages = storage.DisabledStorageNames(v)
+	var storages []string
+	for _, storage := range c.Storages {
 		storages = append(storages, storage.GetName())
 	}
-	for _, user := range Cfg.Users {
-		userIDs = append(userIDs, user.ID)
+	c.userStorages = make(map[int64][]string)
+	for _, user := range c.Users {
+		c.userIDs = append(c.userIDs, user.ID)
 		if user.Blacklist {
-			userStorages[user.ID] = slice.Compact(slice.Difference(storages, user.Storages))
+			c.userStorages[user.ID] = slice.Compact(slice.Difference(storages, user.Storages))
 		} else {
-			userStorages[user.ID] = user.Storages
+			c.userStorages[user.ID] = user.Storages
 		}
 	}
-	return nil
+	ruleErr := c.checkRules()
+	if err := errors.Join(storageErr, ruleErr, c.Validate()); err != nil {
+		return nil, fmt.Errorf("invalid config:\n%w", err)
+	}
+
+	return c, nil
+}
+
+// copySettings returns a deep copy of a settings tree
+func copySettings(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for key, item := range v {
+			m[key] = copySettings(item)
+		}
+		return m
+	case []any:
+		s := make([]any, len(v))
+		for i, item := range v {
+			s[i] = copySettings(item)
+		}
+		return s
+	case []map[string]any:
+		s := make([]map[string]any, len(v))
+		for i, item := range v {
+			s[i] = copySettings(item).(map[string]any)
+		}
+		return s
+	case []string:
+		return append([]string(nil), v...)
+	}
+	return value
 }
 
 func Set(key string, value any) {
 	viper.Set(key, value)
 }
 
-func ReloadConfig() error {
+// ReloadConfig writes the values changed by Set to the config file and reloads it.
+func ReloadConfig(ctx context.Context) error {
 	if err := viper.WriteConfig(); err != nil {
 		return err
 	}
-	if err := viper.ReadInConfig(); err != nil {
-		return err
-	}
-	if error := viper.Unmarshal(Cfg); error != nil {
-		return error
-	}
-	return nil
+	return Reload(ctx)
 }
@@ -6,6 +6,7 @@ import (
 	"io"
 	"os"
 	"path"
+	"path/filepath"
 
 	"github.com/charmbracelet/log"
 	"github.com/duke-git/lancet/v2/retry"
@@ -13,7 +14,9 @@ import (
 	"github.com/krau/SaveAny-Bot/common/utils/ioutil"
 	"github.com/krau/SaveAny-Bot/config"
 	"github.com/krau/SaveAny-Bot/pkg/enums/ctxkey"
+	"github.com/krau/SaveAny-Bot/pkg/storerr"
 	"github.com/krau/SaveAny-Bot/pkg/tfile"
+	"github.com/krau/SaveAny-Bot/storage"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -21,7 +24,7 @@ func (t *Task) Execute(ctx context.Context) error {
 	logger := log.FromContext(ctx).WithPrefix(fmt.Sprintf("batch_file[%s]", t.ID))
 	logger.Info("Starting batch file task")
 	t.Progress.OnStart(ctx, t)
-	workers := config.Cfg.Workers
+	workers := config.C().Workers
 	eg, gctx := errgroup.WithContext(ctx)
 	eg.SetLimit(workers)
 	for _, elem := range t.Elems {
@@ -49,6 +52,13 @@ func (t *Task) Execute(ctx context.Context) error {
 
 func (t *Task) processElement(ctx context.Context, elem TaskElement) error {
 	logger := log.FromContext(ctx).WithPrefix(fmt.Sprintf("file[%s]", elem.File.Name()))
+	tempDir := ""
+	if !elem.stream {
+		tempDir = filepath.Dir(elem.localPath)
+	}
+	if err := storage.CheckFreeSpace(elem.Storage, elem.File.Size(), tempDir); err != nil {
+		return err
+	}
 	if elem.stream {
 		pr, pw := io.Pipe()
 		defer pr.Close()
@@ -107,18 +117,31 @@ func (t *Task) processElement(ctx context.Context, elem TaskElement) error {
 		return fmt.Errorf("failed to get file stat: %w", err)
 	}
 	vctx := context.WithValue(ctx, ctxkey.ContentLength, fileStat.Size())
+	var saveErr error
 	err = retry.Retry(func() error {
-		var file *os.File
-		file, err = os.Open(elem.localPath)
-		if err != nil {
-			return fmt.Errorf("failed to open cache file: %w", err)
+		// the file is already downloaded, only the disk of the storage may be full
+		saveErr = storage.CheckFreeSpace(elem.Storage, fileStat.Size(), "")
+		if saveErr == nil {
+			var file *os.File
+			file, err = os.Open(elem.localPath)
+			if err != nil {
+				return fmt.Errorf("failed to open cache file: %w", err)
+			}
+			defer file.Close()
+			saveErr = elem.Storage.Save(vctx, file, elem.Path)
 		}
-		defer file.Close()
-		if err = elem.Storage.Save(vctx, file, elem.Path); err != nil {
-			logger.Errorf("Failed to save file: %s, retrying...", err)
-			return err
+		if saveErr != nil {
+			if storerr.IsPermanent(saveErr) {
+				// stops retrying, saveErr is returned below
+				return nil
+			}
+			logger.Errorf("Failed to save file: %s, retrying...", saveErr)
+			return saveErr
 		}
 		return nil
-	}, retry.Context(vctx), retry.RetryTimes(uint(config.Cfg.Retry)))
-	return err
+	}, retry.Context(vctx), retry.RetryTimes(uint(config.C().Retry)))
+	if err != nil {
+		return err
+	}
+	return saveErr
 }
@@ -168,6 +168,13 @@ func (p *Progress) OnDone(ctx context.Context, info TaskInfo, err error) {
 	}
 }
 
+// nopProgress is used when the task is created without a progress tracker
+type nopProgress struct{}
+
+func (nopProgress) OnStart(ctx context.Context, info TaskInfo)           {}
+func (nopProgress) OnProgress(ctx context.Context, info TaskInfo)        {}
+func (nopProgress) OnDone(ctx context.Context, info TaskInfo, err error) {}
+
 func NewProgressTracker(messageID int, chatID int64) ProgressTracker {
 	return &Progress{
 		MessageID: messageID,
@@ -7,6 +7,7 @@ import (
 	"sync/atomic"
 
 	"github.com/krau/SaveAny-Bot/config"
+	"github.com/krau/SaveAny-Bot/core/tempdir"
 	"github.com/krau/SaveAny-Bot/pkg/enums/tasktype"
 	"github.com/krau/SaveAny-Bot/pkg/tfile"
 	"github.com/krau/SaveAny-Bot/storage"
@@ -43,27 +44,17 @@ func NewTaskElement(
 	path string,
 	file tfile.TGFile,
 ) (*TaskElement, error) {
+	if err := storage.CheckFileSize(stor, file.Size()); err != nil {
+		return nil, err
+	}
 	id := xid.New().String()
 	_, ok := stor.(storage.StorageCannotStream)
-	if !config.Cfg.Stream || ok {
-		cachePath, err := filepath.Abs(filepath.Join(config.Cfg.Temp.BasePath, fmt.Sprintf("%s_%s", id, file.Name())))
-		if err != nil {
-			return nil, fmt.Errorf("failed to get absolute path for cache: %w", err)
-		}
-		return &TaskElement{
-			ID:        id,
-			Storage:   stor,
-			Path:      path,
-			File:      file,
-			localPath: cachePath,
-		}, nil
-	}
 	return &TaskElement{
 		ID:      id,
 		Storage: stor,
 		Path:    path,
 		File:    file,
-		stream:  true,
+		stream:  config.C().Stream && !ok,
 	}, nil
 }
 
@@ -74,6 +65,15 @@ func NewBatchTGFileTask(
 	progress ProgressTracker,
 	ignoreErrors bool,
 ) *Task {
+	if progress == nil {
+		progress = nopProgress{}
+	}
+	// the elements are downloaded into the temp dir of the batch task
+	for i := range files {
+		if !files[i].stream {
+			files[i].localPath = filepath.Join(tempdir.Dir(id), fmt.Sprintf("%s_%s", files[i].ID, files[i].File.Name()))
+		}
+	}
 	task := &Task{
 		ID:         id,
 		Ctx:        ctx,
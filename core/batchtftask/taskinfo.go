@@ -43,6 +43,11 @@ func (t *Task) Downloaded() int64 {
 	return t.downloaded.Load()
 }
 
+// TrafficBytes implements core.TrafficCounter
+func (t *Task) TrafficBytes() int64 {
+	return t.downloaded.Load()
+}
+
 func (t *Task) Count() int {
 	return len(t.Elems)
 }
@@ -3,14 +3,30 @@ package core
 import (
 	"context"
 	"errors"
+	"sync"
+	"time"
 
 	"github.com/charmbracelet/log"
 	"github.com/krau/SaveAny-Bot/config"
+	"github.com/krau/SaveAny-Bot/core/tempdir"
+	"github.com/krau/SaveAny-Bot/pkg/enums/ctxkey"
 	"github.com/krau/SaveAny-Bot/pkg/enums/tasktype"
 	"github.com/krau/SaveAny-Bot/pkg/queue"
+	"github.com/krau/SaveAny-Bot/pkg/uploadinfo"
 )
 
-var queueInstance *queue.TaskQueue[Exectable]
+var queueInstance *queue.TaskQueue[userTask]
+
+var (
+	heldMu sync.Mutex
+	// tasks waiting to be queued by HoldTask
+	held = make(map[string]heldTask)
+)
+
+type heldTask struct {
+	timer  *time.Timer
+	userID int64
+}
 
 type Exectable interface {
 	Type() tasktype.TaskType
@@ -18,39 +34,47 @@ type Exectable interface {
 	Execute(ctx context.Context) error
 }
 
-func worker(ctx context.Context, qe *queue.TaskQueue[Exectable], semaphore chan struct{}) {
+func worker(ctx context.Context, qe *queue.TaskQueue[userTask], semaphore chan struct{}) {
 	logger := log.FromContext(ctx)
-	execHooks := config.Cfg.Hook.Exec
 	for {
 		semaphore <- struct{}{}
-		qtask, err := qe.Get()
+		// tasks of users at their concurrency limit wait in the queue
+		qtask, err := qe.GetFunc(func(t userTask) bool { return limiter.tryAcquire(t.userID) })
 		if err != nil {
 			logger.Error("Failed to get task from queue:", err)
 			break // queue closed and empty
 		}
 		task := qtask.Data
+		execHooks := config.C().Hook.Exec
 		logger.Infof("Processing task: %s", task.TaskID())
-		if err := ExecCommandString(qtask.Context(), execHooks.TaskBeforeStart); err != nil {
+		if err := ExecCommandString(qtask.Context(), execHooks.TaskBeforeStart, hookEnv(task.TaskID(), nil)...); err != nil {
 			logger.Errorf("Failed to execute before start hook for task %s: %v", task.TaskID(), err)
 		}
-		if err := task.Execute(qtask.Context()); err != nil {
+		tempdir.Acquire(task.TaskID())
+		execCtx, uploads := uploadinfo.WithCollector(context.WithValue(qtask.Context(), ctxkey.TaskID, task.TaskID()))
+		err = task.Execute(execCtx)
+		env := hookEnv(task.TaskID(), uploads.Files())
+		if err != nil {
 			if errors.Is(err, context.Canceled) {
 				logger.Infof("Task %s was canceled", task.TaskID())
-				if err := ExecCommandString(ctx, execHooks.TaskCancel); err != nil {
+				if err := ExecCommandString(ctx, execHooks.TaskCancel, env...); err != nil {
 					logger.Errorf("Failed to execute cancel hook for task %s: %v", task.TaskID(), err)
 				}
 			} else {
 				logger.Errorf("Failed to execute task %s: %v", task.TaskID(), err)
-				if err := ExecCommandString(ctx, execHooks.TaskFail); err != nil {
+				if err := ExecCommandString(ctx, execHooks.TaskFail, env...); err != nil {
 					logger.Errorf("Failed to execute fail hook for task %s: %v", task.TaskID(), err)
 				}
 			}
 		} else {
 			logger.Infof("Task %s completed successfully", task.TaskID())
-			if err := ExecCommandString(ctx, execHooks.TaskSuccess); err != nil {
+			if err := ExecCommandString(ctx, execHooks.TaskSuccess, env...); err != nil {
 				logger.Errorf("Failed to execute success hook for task %s: %v", task.TaskID(), err)
 			}
 		}
+		tempdir.Release(ctx, task.TaskID())
+		addTraffic(ctx, task)
+		limiter.release(task.userID)
 		qe.Done(qtask.ID)
 		<-semaphore
 	}
@@ -58,25 +82,93 @@ func worker(ctx context.Context, qe *queue.TaskQueue[Exectable], semaphore chan
 
 func Run(ctx context.Context) {
 	log.FromContext(ctx).Info("Start processing tasks...")
-	semaphore := make(chan struct{}, config.Cfg.Workers)
+	semaphore := make(chan struct{}, config.C().Workers)
 	if queueInstance == nil {
-		queueInstance = queue.NewTaskQueue[Exectable]()
+		queueInstance = queue.NewTaskQueue[userTask]()
 	}
-	for range config.Cfg.Workers {
+	for range config.C().Workers {
 		go worker(ctx, queueInstance, semaphore)
 	}
+	go tempdir.RunJanitor(ctx)
+
+}
+
+// AddTask queues a task of the user.
+// It fails if the user has reached max_queue_size or daily_traffic_limit.
+func AddTask(ctx context.Context, userID int64, task Exectable) error {
+	if err := checkLimits(ctx, userID); err != nil {
+		return err
+	}
+	return queueInstance.Add(queue.NewTask(ctx, task.TaskID(), userTask{Exectable: task, userID: userID}))
+}
+
+// HoldTask queues a task of the user after delay. Until then RemoveTask takes it back,
+// so that the user can still change their mind. It fails like AddTask.
+func HoldTask(ctx context.Context, userID int64, task Exectable, delay time.Duration) error {
+	if err := checkLimits(ctx, userID); err != nil {
+		return err
+	}
+	id := task.TaskID()
+	heldMu.Lock()
+	defer heldMu.Unlock()
+	held[id] = heldTask{userID: userID, timer: time.AfterFunc(delay, func() {
+		if !release(id) {
+			return
+		}
+		if err := queueInstance.Add(queue.NewTask(ctx, id, userTask{Exectable: task, userID: userID})); err != nil {
+			log.FromContext(ctx).Errorf("Failed to queue held task %s: %s", id, err)
+		}
+	})}
+	return nil
+}
 
+// heldCount returns how many tasks of the user are held
+func heldCount(userID int64) int {
+	heldMu.Lock()
+	defer heldMu.Unlock()
+	n := 0
+	for _, t := range held {
+		if t.userID == userID {
+			n++
+		}
+	}
+	return n
 }
 
-func AddTask(ctx context.Context, task Exectable) error {
-	return queueInstance.Add(queue.NewTask(ctx, task.TaskID(), task))
+// release removes a task from the held ones and reports whether it was held
+func release(id string) bool {
+	heldMu.Lock()
+	defer heldMu.Unlock()
+	t, ok := held[id]
+	if ok {
+		t.timer.Stop()
+		delete(held, id)
+	}
+	return ok
 }
 
 func CancelTask(ctx context.Context, id string) error {
+	if release(id) {
+		return nil
+	}
 	err := queueInstance.CancelTask(id)
 	return err
 }
 
+// RemoveTask removes a task that is held or still waiting in the queue.
+// It fails if the task has already started.
+func RemoveTask(ctx context.Context, id string) error {
+	if release(id) {
+		return nil
+	}
+	return queueInstance.RemoveTask(id)
+}
+
+// CancelAll cancels the tasks of all users and returns how many were cancelled.
+func CancelAll(ctx context.Context) int {
+	return queueInstance.CancelAll()
+}
+
 func GetLength(ctx context.Context) int {
 	if queueInstance == nil {
 		return 0
@@ -0,0 +1,74 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/krau/SaveAny-Bot/config"
+	"github.com/krau/SaveAny-Bot/pkg/enums/tasktype"
+	"github.com/krau/SaveAny-Bot/pkg/queue"
+)
+
+type testTask struct{ id string }
+
+func (t *testTask) Type() tasktype.TaskType           { return tasktype.TaskTypeTgfiles }
+func (t *testTask) TaskID() string                    { return t.id }
+func (t *testTask) Execute(ctx context.Context) error { return nil }
+
+func TestHoldTaskQueueLimit(t *testing.T) {
+	const userID = 1001
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.toml")
+	if err := os.WriteFile(configFile, []byte(fmt.Sprintf(`
+[telegram]
+token = "123:abc"
+
+[[users]]
+id = %d
+max_queue_size = 2
+`, userID)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	config.SetConfigFile(configFile)
+	if err := config.Init(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	saved := queueInstance
+	queueInstance = queue.NewTaskQueue[userTask]()
+	t.Cleanup(func() { queueInstance = saved })
+
+	ctx := context.Background()
+	if err := AddTask(ctx, userID, &testTask{id: "queued"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := HoldTask(ctx, userID, &testTask{id: "held"}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { release("held") })
+
+	// the held task counts like a queued one
+	if err := HoldTask(ctx, userID, &testTask{id: "over"}, time.Hour); err == nil {
+		release("over")
+		t.Error("HoldTask() over max_queue_size succeeded")
+	}
+	if err := AddTask(ctx, userID, &testTask{id: "over"}); err == nil {
+		t.Error("AddTask() over max_queue_size succeeded")
+	}
+	// other users are not limited by it
+	if err := HoldTask(ctx, userID+1, &testTask{id: "other"}, time.Hour); err != nil {
+		t.Errorf("HoldTask() of another user: %v", err)
+	}
+	release("other")
+
+	// taking the held task back frees its place
+	if err := RemoveTask(ctx, "held"); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddTask(ctx, userID, &testTask{id: "again"}); err != nil {
+		t.Errorf("AddTask() after removing the held task: %v", err)
+	}
+}
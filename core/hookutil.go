@@ -2,12 +2,16 @@ package core
 
 import (
 	"context"
+	"encoding/json"
 	"os"
 	"os/exec"
 	"runtime"
+	"strings"
+
+	"github.com/krau/SaveAny-Bot/pkg/uploadinfo"
 )
 
-func ExecCommandString(ctx context.Context, cmd string) error {
+func ExecCommandString(ctx context.Context, cmd string, env ...string) error {
 	if cmd == "" {
 		return nil
 	}
@@ -17,7 +21,29 @@ func ExecCommandString(ctx context.Context, cmd string) error {
 	} else {
 		execCmd = exec.CommandContext(ctx, "sh", "-c", cmd)
 	}
+	if len(env) > 0 {
+		execCmd.Env = append(os.Environ(), env...)
+	}
 	execCmd.Stdout = os.Stdout
 	execCmd.Stderr = os.Stderr
 	return execCmd.Run()
 }
+
+// hookEnv returns the environment variables of the hooks of a task, with the files reported by the storages
+func hookEnv(taskID string, files []uploadinfo.File) []string {
+	env := []string{"SAVEANY_TASK_ID=" + taskID}
+	if len(files) == 0 {
+		return env
+	}
+	var ids []string
+	for _, file := range files {
+		if file.ID != "" {
+			ids = append(ids, file.ID)
+		}
+	}
+	data, _ := json.Marshal(files)
+	return append(env,
+		"SAVEANY_FILE_IDS="+strings.Join(ids, " "),
+		"SAVEANY_UPLOADED_FILES="+string(data),
+	)
+}
@@ -0,0 +1,84 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/charmbracelet/log"
+	"github.com/krau/SaveAny-Bot/config"
+	"github.com/krau/SaveAny-Bot/database"
+)
+
+// TrafficCounter is implemented by tasks that download data.
+// The bytes are added to the daily traffic of the task's user when the task finishes.
+type TrafficCounter interface {
+	TrafficBytes() int64
+}
+
+// userTask is a task in the queue together with the user who added it
+type userTask struct {
+	Exectable
+	userID int64
+}
+
+// runningLimiter tracks the running tasks of every user to enforce max_concurrent_tasks
+type runningLimiter struct {
+	mu      sync.Mutex
+	running map[int64]int
+}
+
+var limiter = &runningLimiter{running: make(map[int64]int)}
+
+// tryAcquire reserves a running slot for the user if the limit allows it
+func (l *runningLimiter) tryAcquire(userID int64) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if max := config.C().GetUserLimits(userID).MaxConcurrentTasks; max > 0 && l.running[userID] >= max {
+		return false
+	}
+	l.running[userID]++
+	return true
+}
+
+func (l *runningLimiter) release(userID int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.running[userID]--
+	if l.running[userID] <= 0 {
+		delete(l.running, userID)
+	}
+}
+
+// checkLimits returns an error if the user can not add another task
+func checkLimits(ctx context.Context, userID int64) error {
+	limits := config.C().GetUserLimits(userID)
+	if limits.MaxQueueSize > 0 {
+		// the held tasks are queued soon, unless the user picks another storage for them
+		pending := queueInstance.CountFunc(func(t userTask) bool { return t.userID == userID }) + heldCount(userID)
+		if pending >= limits.MaxQueueSize {
+			return fmt.Errorf("queue limit reached: you already have %d tasks, wait for them to finish", pending)
+		}
+	}
+	if limits.DailyTrafficLimit > 0 {
+		used, err := database.GetTodayTraffic(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("failed to get traffic: %w", err)
+		}
+		if used >= limits.DailyTrafficLimit {
+			return fmt.Errorf("daily traffic limit reached: %.2f MB of %.2f MB used today",
+				float64(used)/(1024*1024), float64(limits.DailyTrafficLimit)/(1024*1024))
+		}
+	}
+	return nil
+}
+
+func addTraffic(ctx context.Context, task userTask) {
+	counter, ok := task.Exectable.(TrafficCounter)
+	if !ok {
+		return
+	}
+	if err := database.AddTraffic(ctx, task.userID, counter.TrafficBytes()); err != nil {
+		log.FromContext(ctx).Errorf("Failed to add traffic for user %d: %v", task.userID, err)
+	}
+}
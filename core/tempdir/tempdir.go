@@ -0,0 +1,168 @@
+// Package tempdir manages the directories of the tasks under temp.base_path.
+//
+// Every task downloads into its own directory, named after the task id. The directory is
+// removed when the task ends, and a janitor removes the ones left behind by a crash as well as
+// the oldest ones once temp.max_age or temp.max_size is exceeded. Directories of running tasks
+// are never removed.
+package tempdir
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/krau/SaveAny-Bot/config"
+	"github.com/rs/xid"
+)
+
+const janitorInterval = time.Minute
+
+var (
+	mu      sync.Mutex
+	running = make(map[string]string) // task id -> temp dir
+)
+
+// Dir returns the temp directory of the task, it is created by the task when needed
+func Dir(taskID string) string {
+	dir := filepath.Join(config.C().Temp.BasePath, taskID)
+	if abs, err := filepath.Abs(dir); err == nil {
+		return abs
+	}
+	return dir
+}
+
+// Acquire marks the directory of the task as in use until Release is called
+func Acquire(taskID string) {
+	mu.Lock()
+	defer mu.Unlock()
+	running[taskID] = Dir(taskID)
+}
+
+// Release removes the directory of the task together with everything left in it
+func Release(ctx context.Context, taskID string) {
+	mu.Lock()
+	defer mu.Unlock()
+	dir, ok := running[taskID]
+	if !ok {
+		return
+	}
+	delete(running, taskID)
+	if err := os.RemoveAll(dir); err != nil {
+		log.FromContext(ctx).Errorf("Failed to remove temp dir of task %s: %s", taskID, err)
+	}
+}
+
+// remove removes the directory of the task under base unless the task is running
+func remove(base, taskID string) (bool, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := running[taskID]; ok {
+		return false, nil
+	}
+	return true, os.RemoveAll(filepath.Join(base, taskID))
+}
+
+// RunJanitor removes the task directories left from a previous run, then enforces
+// temp.max_age and temp.max_size periodically until ctx is done
+func RunJanitor(ctx context.Context) {
+	run := func(startup bool) {
+		temp := config.C().Temp
+		clean(ctx, temp.BasePath, temp.MaxAge.Duration(), temp.MaxSize.Bytes(), startup)
+	}
+	run(true)
+	ticker := time.NewTicker(janitorInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			run(false)
+		}
+	}
+}
+
+type taskDir struct {
+	id      string
+	modTime time.Time
+	size    int64
+}
+
+// clean removes the task directories under base over the limits, the oldest first. At startup no task
+// is running yet, so all of them are leftovers
+func clean(ctx context.Context, base string, maxAge time.Duration, maxSize int64, startup bool) {
+	logger := log.FromContext(ctx)
+	if !startup && maxAge <= 0 && maxSize <= 0 {
+		return
+	}
+	dirs, total, err := scan(base)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Errorf("Failed to scan temp dir: %s", err)
+		}
+		return
+	}
+	for _, dir := range dirs {
+		expired := maxAge > 0 && time.Since(dir.modTime) > maxAge
+		if !startup && !expired && (maxSize <= 0 || total <= maxSize) {
+			continue
+		}
+		removed, err := remove(base, dir.id)
+		if err != nil {
+			logger.Errorf("Failed to remove temp dir of task %s: %s", dir.id, err)
+			continue
+		}
+		if removed {
+			logger.Infof("Removed temp dir of task %s (%.2f MB)", dir.id, float64(dir.size)/(1024*1024))
+			total -= dir.size
+		}
+	}
+	if maxSize > 0 && total > maxSize {
+		logger.Warnf("Temp dir uses %.2f MB of %.2f MB, all of it by running tasks",
+			float64(total)/(1024*1024), float64(maxSize)/(1024*1024))
+	}
+}
+
+// scan returns the task directories under base sorted from the oldest, and their total size.
+// Only directories named after a task id are considered, anything else is left alone
+func scan(base string) ([]taskDir, int64, error) {
+	entries, err := os.ReadDir(base)
+	if err != nil {
+		return nil, 0, err
+	}
+	var dirs []taskDir
+	var total int64
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := xid.FromString(entry.Name()); err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		dir := taskDir{id: entry.Name(), modTime: info.ModTime()}
+		filepath.WalkDir(filepath.Join(base, entry.Name()), func(_ string, d os.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				dir.size += info.Size()
+				if info.ModTime().After(dir.modTime) {
+					dir.modTime = info.ModTime()
+				}
+			}
+			return nil
+		})
+		total += dir.size
+		dirs = append(dirs, dir)
+	}
+	sort.Slice(dirs, func(i, j int) bool { return dirs[i].modTime.Before(dirs[j].modTime) })
+	return dirs, total, nil
+}
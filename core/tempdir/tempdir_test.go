@@ -0,0 +1,168 @@
+package tempdir
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rs/xid"
+)
+
+// makeTaskDir creates the directory of a task with a file of size bytes, last modified age ago
+func makeTaskDir(t *testing.T, base string, size int, age time.Duration) string {
+	t.Helper()
+	id := xid.New().String()
+	dir := filepath.Join(base, id)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "file.bin")
+	if err := os.WriteFile(file, make([]byte, size), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mod := time.Now().Add(-age)
+	for _, p := range []string{file, dir} {
+		if err := os.Chtimes(p, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return id
+}
+
+// setRunning replaces the running tasks for the test
+func setRunning(t *testing.T, base string, ids ...string) {
+	t.Helper()
+	mu.Lock()
+	saved := running
+	running = make(map[string]string, len(ids))
+	for _, id := range ids {
+		running[id] = filepath.Join(base, id)
+	}
+	mu.Unlock()
+	t.Cleanup(func() {
+		mu.Lock()
+		running = saved
+		mu.Unlock()
+	})
+}
+
+func remaining(t *testing.T, base string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	slices.Sort(names)
+	return names
+}
+
+func sorted(names ...string) []string {
+	slices.Sort(names)
+	return names
+}
+
+func TestScan(t *testing.T) {
+	base := t.TempDir()
+	newest := makeTaskDir(t, base, 10, time.Hour)
+	oldest := makeTaskDir(t, base, 20, 3*time.Hour)
+	middle := makeTaskDir(t, base, 30, 2*time.Hour)
+	if err := os.MkdirAll(filepath.Join(base, "not-a-task"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(base, "notes.txt"), []byte("keep"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	dirs, total, err := scan(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, dir := range dirs {
+		ids = append(ids, dir.id)
+	}
+	if want := []string{oldest, middle, newest}; !slices.Equal(ids, want) {
+		t.Errorf("scan() = %v, want %v", ids, want)
+	}
+	if total != 60 {
+		t.Errorf("total = %d, want 60", total)
+	}
+}
+
+func TestCleanMaxSize(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("oldest first", func(t *testing.T) {
+		base := t.TempDir()
+		oldest := makeTaskDir(t, base, 100, 3*time.Hour)
+		middle := makeTaskDir(t, base, 100, 2*time.Hour)
+		newest := makeTaskDir(t, base, 100, time.Hour)
+		setRunning(t, base)
+
+		clean(ctx, base, 0, 250, false)
+		if got, want := remaining(t, base), sorted(middle, newest); !slices.Equal(got, want) {
+			t.Errorf("remaining = %v, want %v (%s removed)", got, want, oldest)
+		}
+	})
+
+	t.Run("running kept", func(t *testing.T) {
+		base := t.TempDir()
+		oldest := makeTaskDir(t, base, 100, 3*time.Hour)
+		makeTaskDir(t, base, 100, 2*time.Hour)
+		makeTaskDir(t, base, 100, time.Hour)
+		setRunning(t, base, oldest)
+
+		// the running task does not free any space, so the newer ones have to go
+		clean(ctx, base, 0, 150, false)
+		if got, want := remaining(t, base), []string{oldest}; !slices.Equal(got, want) {
+			t.Errorf("remaining = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestCleanMaxAge(t *testing.T) {
+	base := t.TempDir()
+	expired := makeTaskDir(t, base, 10, 3*time.Hour)
+	expiredRunning := makeTaskDir(t, base, 10, 2*time.Hour)
+	fresh := makeTaskDir(t, base, 10, time.Minute)
+	setRunning(t, base, expiredRunning)
+
+	clean(context.Background(), base, time.Hour, 0, false)
+	if got, want := remaining(t, base), sorted(expiredRunning, fresh); !slices.Equal(got, want) {
+		t.Errorf("remaining = %v, want %v (%s removed)", got, want, expired)
+	}
+}
+
+func TestCleanStartup(t *testing.T) {
+	base := t.TempDir()
+	makeTaskDir(t, base, 10, time.Hour)
+	makeTaskDir(t, base, 10, time.Minute)
+	running := makeTaskDir(t, base, 10, time.Minute)
+	if err := os.MkdirAll(filepath.Join(base, "not-a-task"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(base, "notes.txt"), []byte("keep"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	setRunning(t, base, running)
+
+	// no limits are set, but the task directories left from a previous run are removed anyway
+	clean(context.Background(), base, 0, 0, true)
+	if got, want := remaining(t, base), sorted(running, "not-a-task", "notes.txt"); !slices.Equal(got, want) {
+		t.Errorf("remaining = %v, want %v", got, want)
+	}
+
+	// without limits nothing is removed after startup
+	left := makeTaskDir(t, base, 10, 24*time.Hour)
+	clean(context.Background(), base, 0, 0, false)
+	if got, want := remaining(t, base), sorted(left, running, "not-a-task", "notes.txt"); !slices.Equal(got, want) {
+		t.Errorf("remaining = %v, want %v", got, want)
+	}
+}
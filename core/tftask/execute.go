@@ -5,13 +5,16 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"path/filepath"
 	"time"
 
 	"github.com/charmbracelet/log"
 	"github.com/krau/SaveAny-Bot/common/utils/fsutil"
 	"github.com/krau/SaveAny-Bot/config"
 	"github.com/krau/SaveAny-Bot/pkg/enums/ctxkey"
+	"github.com/krau/SaveAny-Bot/pkg/storerr"
 	"github.com/krau/SaveAny-Bot/pkg/tfile"
+	"github.com/krau/SaveAny-Bot/storage"
 )
 
 func (t *Task) Execute(ctx context.Context) error {
@@ -19,6 +22,16 @@ func (t *Task) Execute(ctx context.Context) error {
 	if t.Progress != nil {
 		t.Progress.OnStart(ctx, t)
 	}
+	tempDir := ""
+	if !t.stream {
+		tempDir = filepath.Dir(t.localPath)
+	}
+	if err := storage.CheckFreeSpace(t.Storage, t.File.Size(), tempDir); err != nil {
+		if t.Progress != nil {
+			t.Progress.OnDone(ctx, t, err)
+		}
+		return err
+	}
 	if t.stream {
 		return executeStream(ctx, t)
 	}
@@ -33,7 +46,7 @@ func (t *Task) Execute(ctx context.Context) error {
 			logger.Errorf("Failed to close local file: %v", err)
 		}
 	}()
-	wrAt := newWriterAt(ctx, localFile, t.Progress, t)
+	wrAt := newWriterAt(ctx, localFile, t.Progress, t, &t.downloaded)
 
 	defer func() {
 		if t.Progress != nil {
@@ -57,10 +70,14 @@ func (t *Task) Execute(ctx context.Context) error {
 		return fmt.Errorf("failed to get file stat: %w", err)
 	}
 	vctx := context.WithValue(ctx, ctxkey.ContentLength, fileStat.Size())
-	for i := range config.Cfg.Retry + 1 {
+	for i := range config.C().Retry + 1 {
 		if err = vctx.Err(); err != nil {
 			return fmt.Errorf("context canceled while saving file: %w", err)
 		}
+		// the file is already downloaded, only the disk of the storage may be full
+		if err = storage.CheckFreeSpace(t.Storage, fileStat.Size(), ""); err != nil {
+			return err
+		}
 		var file *os.File
 		file, err = os.Open(t.localPath)
 		if err != nil {
@@ -68,7 +85,7 @@ func (t *Task) Execute(ctx context.Context) error {
 		}
 		defer file.Close()
 		if err = t.Storage.Save(vctx, file, t.Path); err != nil {
-			if i == config.Cfg.Retry {
+			if i == config.C().Retry || storerr.IsPermanent(err) {
 				return fmt.Errorf("failed to save file: %w", err)
 			}
 			logger.Errorf("Failed to save file: %s, retrying...", err)
@@ -19,7 +19,7 @@ func executeStream(ctx context.Context, task *Task) error {
 	errg.Go(func() error {
 		return task.Storage.Save(uploadCtx, pr, task.Path)
 	})
-	wr := newWriter(ctx, pw, task.Progress, task)
+	wr := newWriter(ctx, pw, task.Progress, task, &task.downloaded)
 	errg.Go(func() error {
 		defer pw.Close()
 		logger.Info("Starting file download in stream mode")
@@ -20,6 +20,11 @@ func (t *Task) FileSize() int64 {
 	return t.File.Size()
 }
 
+// TrafficBytes implements core.TrafficCounter
+func (t *Task) TrafficBytes() int64 {
+	return t.downloaded.Load()
+}
+
 func (t *Task) StoragePath() string {
 	return t.Path
 }
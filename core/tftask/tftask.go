@@ -2,10 +2,11 @@ package tftask
 
 import (
 	"context"
-	"fmt"
 	"path/filepath"
+	"sync/atomic"
 
 	"github.com/krau/SaveAny-Bot/config"
+	"github.com/krau/SaveAny-Bot/core/tempdir"
 	"github.com/krau/SaveAny-Bot/pkg/enums/tasktype"
 	"github.com/krau/SaveAny-Bot/pkg/tfile"
 	"github.com/krau/SaveAny-Bot/storage"
@@ -20,6 +21,8 @@ type Task struct {
 	Progress  ProgressTracker
 	stream    bool // true if the file should be downloaded in stream mode
 	localPath string
+	// bytes written by the downloader, counted as the traffic of the task
+	downloaded atomic.Int64
 }
 
 func (t *Task) Type() tasktype.TaskType {
@@ -34,12 +37,12 @@ func NewTGFileTask(
 	path string,
 	progress ProgressTracker,
 ) (*Task, error) {
+	if err := storage.CheckFileSize(stor, file.Size()); err != nil {
+		return nil, err
+	}
 	_, ok := stor.(storage.StorageCannotStream)
-	if !config.Cfg.Stream || ok {
-		cachePath, err := filepath.Abs(filepath.Join(config.Cfg.Temp.BasePath, fmt.Sprintf("%s_%s", id, file.Name())))
-		if err != nil {
-			return nil, fmt.Errorf("failed to get absolute path for cache: %w", err)
-		}
+	if !config.C().Stream || ok {
+		cachePath := filepath.Join(tempdir.Dir(id), file.Name())
 		tftask := &Task{
 			ID:        id,
 			Ctx:       ctx,
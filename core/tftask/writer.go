@@ -31,11 +31,12 @@ func newWriterAt(
 	wrAt io.WriterAt,
 	progress ProgressTracker,
 	taskInfo TaskInfo,
+	downloaded *atomic.Int64,
 ) *ProgressWriterAt {
 	return &ProgressWriterAt{
 		ctx:        ctx,
 		progress:   progress,
-		downloaded: &atomic.Int64{},
+		downloaded: downloaded,
 		total:      taskInfo.FileSize(),
 		wrAt:       wrAt,
 		info:       taskInfo,
@@ -67,11 +68,12 @@ func newWriter(
 	wr io.Writer,
 	progress ProgressTracker,
 	taskInfo TaskInfo,
+	downloaded *atomic.Int64,
 ) *ProgressWriter {
 	return &ProgressWriter{
 		ctx:        ctx,
 		progress:   progress,
-		downloaded: &atomic.Int64{},
+		downloaded: downloaded,
 		total:      taskInfo.FileSize(),
 		wrAt:       wr,
 		info:       taskInfo,
@@ -11,6 +11,8 @@ import (
 	"github.com/duke-git/lancet/v2/retry"
 	"github.com/krau/SaveAny-Bot/common/utils/fsutil"
 	"github.com/krau/SaveAny-Bot/config"
+	"github.com/krau/SaveAny-Bot/core/tempdir"
+	"github.com/krau/SaveAny-Bot/pkg/storerr"
 	"go.uber.org/multierr"
 	"golang.org/x/sync/errgroup"
 )
@@ -20,7 +22,7 @@ func (t *Task) Execute(ctx context.Context) error {
 	logger.Infof("Starting Telegraph task %s", t.PhPath)
 	t.progress.OnStart(ctx, t)
 	eg, gctx := errgroup.WithContext(ctx)
-	eg.SetLimit(config.Cfg.Workers)
+	eg.SetLimit(config.C().Workers)
 	for i, pic := range t.Pics {
 		pic := pic
 		i := i
@@ -48,7 +50,7 @@ func (t *Task) Execute(ctx context.Context) error {
 func (t *Task) processPic(ctx context.Context, picUrl string, index int) error {
 	retryOpts := []retry.Option{
 		retry.Context(ctx),
-		retry.RetryTimes(uint(config.Cfg.Retry)),
+		retry.RetryTimes(uint(config.C().Retry)),
 	}
 	var lastErr error
 	err := retry.Retry(func() error {
@@ -59,11 +61,10 @@ func (t *Task) processPic(ctx context.Context, picUrl string, index int) error {
 			return lastErr
 		}
 		defer body.Close()
+		counted := &countingReader{r: body, n: &t.downloadedBytes}
 		filename := fmt.Sprintf("%d%s", index+1, path.Ext(picUrl))
 		if t.cannotStream {
-			cacheFile, err := fsutil.CreateFile(filepath.Join(config.Cfg.Temp.BasePath,
-				fmt.Sprintf("tph_%s_%s", t.TaskID(), filename),
-			))
+			cacheFile, err := fsutil.CreateFile(filepath.Join(tempdir.Dir(t.TaskID()), filename))
 			if err != nil {
 				lastErr = fmt.Errorf("failed to create cache file for picture %s: %w", filename, err)
 				return lastErr
@@ -74,18 +75,27 @@ func (t *Task) processPic(ctx context.Context, picUrl string, index int) error {
 					logger.Errorf("Failed to close and remove cache file for picture %s: %v", filename, err)
 				}
 			}()
-			_, lastErr = io.Copy(cacheFile, body)
+			_, lastErr = io.Copy(cacheFile, counted)
 			if lastErr != nil {
 				lastErr = fmt.Errorf("failed to copy picture %s to cache file: %w", filename, lastErr)
 				return lastErr
 			}
+			// the storage reads the file from where the copy stopped otherwise
+			if _, lastErr = cacheFile.Seek(0, io.SeekStart); lastErr != nil {
+				lastErr = fmt.Errorf("failed to seek cache file for picture %s: %w", filename, lastErr)
+				return lastErr
+			}
 			lastErr = t.Stor.Save(ctx, cacheFile, path.Join(t.StorPath, filename))
 		} else {
-			lastErr = t.Stor.Save(ctx, body, path.Join(t.StorPath, filename))
+			lastErr = t.Stor.Save(ctx, counted, path.Join(t.StorPath, filename))
 		}
 
 		if lastErr != nil {
 			lastErr = fmt.Errorf("failed to save picture %s: %w", filename, lastErr)
+			if storerr.IsPermanent(lastErr) {
+				// stops retrying, lastErr is returned below
+				return nil
+			}
 			return lastErr
 		}
 		return nil
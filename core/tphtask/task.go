@@ -22,6 +22,8 @@ type Task struct {
 	cannotStream bool
 	totalpics    int
 	downloaded   atomic.Int64
+	// bytes of all downloaded pictures, including retries
+	downloadedBytes atomic.Int64
 }
 
 func (t *Task) Type() tasktype.TaskType {
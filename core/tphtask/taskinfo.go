@@ -25,6 +25,11 @@ func (t *Task) Downloaded() int64 {
 	return t.downloaded.Load()
 }
 
+// TrafficBytes implements core.TrafficCounter
+func (t *Task) TrafficBytes() int64 {
+	return t.downloadedBytes.Load()
+}
+
 func (t *Task) StorageName() string {
 	return t.Stor.Name()
 }
@@ -1,5 +1,22 @@
 package tphtask
 
+import (
+	"io"
+	"sync/atomic"
+)
+
+// countingReader adds the bytes read from r to n
+type countingReader struct {
+	r io.Reader
+	n *atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
 func shouldUpdateProgress(downloaded int64, total int64) bool {
 	if total <= 0 || downloaded <= 0 {
 		return false
@@ -0,0 +1,41 @@
+package database
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+func CreateBotRule(ctx context.Context, rule *BotRule) error {
+	return db.WithContext(ctx).Create(rule).Error
+}
+
+// GetBotRules returns all rules added with /ruleadd, including the disabled ones
+func GetBotRules(ctx context.Context) ([]BotRule, error) {
+	var rules []BotRule
+	err := db.WithContext(ctx).Order("id").Find(&rules).Error
+	return rules, err
+}
+
+// DeleteBotRule deletes the rule, it returns gorm.ErrRecordNotFound if there is no such rule
+func DeleteBotRule(ctx context.Context, id uint) error {
+	result := db.WithContext(ctx).Unscoped().Delete(&BotRule{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ToggleBotRule enables a disabled rule or disables an enabled one, and returns whether it is enabled now
+func ToggleBotRule(ctx context.Context, id uint) (bool, error) {
+	var rule BotRule
+	if err := db.WithContext(ctx).First(&rule, id).Error; err != nil {
+		return false, err
+	}
+	enabled := !rule.Enabled
+	err := db.WithContext(ctx).Model(&rule).Update("enabled", enabled).Error
+	return enabled, err
+}
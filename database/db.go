@@ -19,11 +19,11 @@ var db *gorm.DB
 
 func Init(ctx context.Context) {
 	logger := log.FromContext(ctx)
-	if err := os.MkdirAll(filepath.Dir(config.Cfg.DB.Path), 0755); err != nil {
+	if err := os.MkdirAll(filepath.Dir(config.C().DB.Path), 0755); err != nil {
 		logger.Fatal("Failed to create data directory: ", err)
 	}
 	var err error
-	db, err = gorm.Open(gormlite.Open(config.Cfg.DB.Path), &gorm.Config{
+	db, err = gorm.Open(gormlite.Open(config.C().DB.Path), &gorm.Config{
 		Logger: glogger.New(logger, glogger.Config{
 			Colorful:                  true,
 			SlowThreshold:             time.Second * 5,
@@ -37,12 +37,17 @@ func Init(ctx context.Context) {
 		logger.Fatal("Failed to open database: ", err)
 	}
 	logger.Debug("Database connected")
-	if err := db.AutoMigrate(&User{}, &Dir{}, &Rule{}, &WatchChat{}); err != nil {
+	if err := db.AutoMigrate(&User{}, &Dir{}, &Rule{}, &WatchChat{}, &Traffic{}, &BotRule{}); err != nil {
 		logger.Fatal("迁移数据库失败, 如果您从旧版本升级, 建议手动删除数据库文件后重试: ", err)
 	}
 	if err := syncUsers(ctx); err != nil {
 		logger.Fatal("Failed to sync users:", err)
 	}
+	config.OnReload(func(ctx context.Context, _, _ *config.Config) {
+		if err := syncUsers(ctx); err != nil {
+			log.FromContext(ctx).Errorf("Failed to sync users: %s", err)
+		}
+	})
 	logger.Debug("Database migrated")
 	logger.Info("Database initialized")
 }
@@ -60,7 +65,7 @@ func syncUsers(ctx context.Context) error {
 	}
 
 	cfgUserMap := make(map[int64]struct{})
-	for _, u := range config.Cfg.Users {
+	for _, u := range config.C().Users {
 		cfgUserMap[u.ID] = struct{}{}
 	}
 
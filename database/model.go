@@ -9,10 +9,13 @@ type User struct {
 	ChatID         int64 `gorm:"uniqueIndex;not null"`
 	Silent         bool
 	DefaultStorage string
-	Dirs           []Dir
-	ApplyRule      bool
-	Rules          []Rule
-	WatchChats     []WatchChat
+	// set with /setdefault, used whether or not silent mode is on
+	DefaultSaveStorage string
+	DefaultSavePath    string
+	Dirs               []Dir
+	ApplyRule          bool
+	Rules              []Rule
+	WatchChats         []WatchChat
 }
 
 type WatchChat struct {
@@ -37,3 +40,30 @@ type Rule struct {
 	StorageName string
 	DirPath     string
 }
+
+// Traffic is the number of bytes downloaded by a user on a day
+type Traffic struct {
+	gorm.Model
+	ChatID int64  `gorm:"uniqueIndex:idx_traffic_chat_date;not null"`
+	Date   string `gorm:"uniqueIndex:idx_traffic_chat_date;not null"` // 2006-01-02, local time
+	Bytes  int64
+}
+
+// BotRule is a rule added by an admin with /ruleadd, it has the same fields as the rules in the config
+type BotRule struct {
+	gorm.Model
+	Name         string
+	Type         string
+	Value        string
+	AllOf        string // comma separated
+	AnyOf        string // comma separated
+	Storage      string
+	Path         string
+	PathTemplate string
+	Action       string
+	Silent       bool
+	Priority     int
+	UserID       int64
+	Enabled      bool
+	CreatedBy    int64
+}
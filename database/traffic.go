@@ -0,0 +1,37 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func trafficDate(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+// AddTraffic adds the downloaded bytes to the user's traffic of today
+func AddTraffic(ctx context.Context, chatID int64, bytes int64) error {
+	if bytes <= 0 {
+		return nil
+	}
+	return db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "chat_id"}, {Name: "date"}},
+		DoUpdates: clause.Assignments(map[string]any{"bytes": gorm.Expr("bytes + ?", bytes), "updated_at": time.Now()}),
+	}).Create(&Traffic{
+		ChatID: chatID,
+		Date:   trafficDate(time.Now()),
+		Bytes:  bytes,
+	}).Error
+}
+
+// GetTodayTraffic returns the bytes downloaded by the user today
+func GetTodayTraffic(ctx context.Context, chatID int64) (int64, error) {
+	var traffic Traffic
+	err := db.WithContext(ctx).
+		Where("chat_id = ? AND date = ?", chatID, trafficDate(time.Now())).
+		Limit(1).Find(&traffic).Error
+	return traffic.Bytes, err
+}
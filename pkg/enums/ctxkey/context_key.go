@@ -1,5 +1,5 @@
 package ctxkey
 
 //go:generate go-enum --values --names --flag --nocase --noprefix
-// ENUM(content-length)
+// ENUM(content-length, uploaded-files, task-id)
 type ContextKey string
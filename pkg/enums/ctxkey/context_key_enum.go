@@ -14,12 +14,18 @@ import (
 const (
 	// ContentLength is a ContextKey of type content-length.
 	ContentLength ContextKey = "content-length"
+	// UploadedFiles is a ContextKey of type uploaded-files.
+	UploadedFiles ContextKey = "uploaded-files"
+	// TaskID is a ContextKey of type task-id.
+	TaskID ContextKey = "task-id"
 )
 
 var ErrInvalidContextKey = fmt.Errorf("not a valid ContextKey, try [%s]", strings.Join(_ContextKeyNames, ", "))
 
 var _ContextKeyNames = []string{
 	string(ContentLength),
+	string(UploadedFiles),
+	string(TaskID),
 }
 
 // ContextKeyNames returns a list of possible string values of ContextKey.
@@ -33,6 +39,8 @@ func ContextKeyNames() []string {
 func ContextKeyValues() []ContextKey {
 	return []ContextKey{
 		ContentLength,
+		UploadedFiles,
+		TaskID,
 	}
 }
 
@@ -50,6 +58,8 @@ func (x ContextKey) IsValid() bool {
 
 var _ContextKeyValue = map[string]ContextKey{
 	"content-length": ContentLength,
+	"uploaded-files": UploadedFiles,
+	"task-id":        TaskID,
 }
 
 // ParseContextKey attempts to convert a string to a ContextKey.
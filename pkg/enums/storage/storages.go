@@ -4,6 +4,6 @@ package storage
 
 // StorageType
 /* ENUM(
-local, webdav, alist, minio, telegram
+local, webdav, alist, minio, telegram, ftp, smb, gdrive, onedrive, dropbox, b2
 ) */
 type StorageType string
@@ -22,6 +22,18 @@ const (
 	Minio StorageType = "minio"
 	// Telegram is a StorageType of type telegram.
 	Telegram StorageType = "telegram"
+	// Ftp is a StorageType of type ftp.
+	Ftp StorageType = "ftp"
+	// Smb is a StorageType of type smb.
+	Smb StorageType = "smb"
+	// Gdrive is a StorageType of type gdrive.
+	Gdrive StorageType = "gdrive"
+	// Onedrive is a StorageType of type onedrive.
+	Onedrive StorageType = "onedrive"
+	// Dropbox is a StorageType of type dropbox.
+	Dropbox StorageType = "dropbox"
+	// B2 is a StorageType of type b2.
+	B2 StorageType = "b2"
 )
 
 var ErrInvalidStorageType = fmt.Errorf("not a valid StorageType, try [%s]", strings.Join(_StorageTypeNames, ", "))
@@ -32,6 +44,12 @@ var _StorageTypeNames = []string{
 	string(Alist),
 	string(Minio),
 	string(Telegram),
+	string(Ftp),
+	string(Smb),
+	string(Gdrive),
+	string(Onedrive),
+	string(Dropbox),
+	string(B2),
 }
 
 // StorageTypeNames returns a list of possible string values of StorageType.
@@ -49,6 +67,12 @@ func StorageTypeValues() []StorageType {
 		Alist,
 		Minio,
 		Telegram,
+		Ftp,
+		Smb,
+		Gdrive,
+		Onedrive,
+		Dropbox,
+		B2,
 	}
 }
 
@@ -70,6 +94,12 @@ var _StorageTypeValue = map[string]StorageType{
 	"alist":    Alist,
 	"minio":    Minio,
 	"telegram": Telegram,
+	"ftp":      Ftp,
+	"smb":      Smb,
+	"gdrive":   Gdrive,
+	"onedrive": Onedrive,
+	"dropbox":  Dropbox,
+	"b2":       B2,
 }
 
 // ParseStorageType attempts to convert a string to a StorageType.
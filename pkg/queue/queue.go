@@ -51,35 +51,36 @@ func (tq *TaskQueue[T]) Add(task *Task[T]) error {
 }
 
 func (tq *TaskQueue[T]) Get() (*Task[T], error) {
+	return tq.GetFunc(nil)
+}
+
+// GetFunc returns the first queued task accepted by match, waiting until there is one.
+// match is called with the queue locked, so it must not call back into the queue.
+// A nil match accepts every task.
+func (tq *TaskQueue[T]) GetFunc(match func(T) bool) (*Task[T], error) {
 	tq.mu.Lock()
 	defer tq.mu.Unlock()
 
-	for tq.tasks.Len() == 0 && !tq.closed {
-		tq.cond.Wait()
-	}
-
-	if tq.closed && tq.tasks.Len() == 0 {
-		return nil, fmt.Errorf("queue is closed and empty")
-	}
-
-	for tq.tasks.Len() > 0 {
-		element := tq.tasks.Front()
-		task := element.Value.(*Task[T])
-
-		tq.tasks.Remove(element)
-		task.element = nil
-
-		if !task.IsCancelled() {
-			tq.runningTaskMap[task.ID] = task
-			return task, nil
+	for {
+		for element := tq.tasks.Front(); element != nil; {
+			next := element.Next()
+			task := element.Value.(*Task[T])
+			if task.IsCancelled() {
+				tq.tasks.Remove(element)
+				task.element = nil
+			} else if match == nil || match(task.Data) {
+				tq.tasks.Remove(element)
+				task.element = nil
+				tq.runningTaskMap[task.ID] = task
+				return task, nil
+			}
+			element = next
 		}
+		if tq.closed && tq.tasks.Len() == 0 {
+			return nil, fmt.Errorf("queue is closed and empty")
+		}
+		tq.cond.Wait()
 	}
-
-	if !tq.closed {
-		return tq.Get()
-	}
-
-	return nil, fmt.Errorf("queue is closed and empty")
 }
 
 func (tq *TaskQueue[T]) Done(taskID string) {
@@ -88,6 +89,8 @@ func (tq *TaskQueue[T]) Done(taskID string) {
 
 	delete(tq.taskMap, taskID)
 	delete(tq.runningTaskMap, taskID)
+	// tasks skipped by GetFunc may be accepted now
+	tq.cond.Broadcast()
 }
 
 func (tq *TaskQueue[T]) Peek() (*Task[T], error) {
@@ -128,6 +131,27 @@ func (tq *TaskQueue[T]) ActiveLength() int {
 	return count
 }
 
+// CountFunc returns the number of queued and running tasks accepted by match.
+// Cancelled tasks that are still queued are not counted.
+func (tq *TaskQueue[T]) CountFunc(match func(T) bool) int {
+	tq.mu.RLock()
+	defer tq.mu.RUnlock()
+
+	count := 0
+	for element := tq.tasks.Front(); element != nil; element = element.Next() {
+		task := element.Value.(*Task[T])
+		if !task.IsCancelled() && match(task.Data) {
+			count++
+		}
+	}
+	for _, task := range tq.runningTaskMap {
+		if match(task.Data) {
+			count++
+		}
+	}
+	return count
+}
+
 func (tq *TaskQueue[T]) CancelTask(taskID string) error {
 	tq.mu.RLock()
 	task, exists := tq.taskMap[taskID]
@@ -148,13 +172,12 @@ func (tq *TaskQueue[T]) RemoveTask(taskID string) error {
 	tq.mu.Lock()
 	defer tq.mu.Unlock()
 
+	if _, running := tq.runningTaskMap[taskID]; running {
+		return fmt.Errorf("task %s is already running, cannot remove from queue", taskID)
+	}
 	task, exists := tq.taskMap[taskID]
 	if !exists {
-		_, exists = tq.runningTaskMap[taskID]
-		if exists {
-			delete(tq.runningTaskMap, taskID)
-		}
-		return fmt.Errorf("task %s is already running, cannot remove from queue", taskID)
+		return fmt.Errorf("task %s does not exist", taskID)
 	}
 
 	if task.element != nil {
@@ -165,17 +188,27 @@ func (tq *TaskQueue[T]) RemoveTask(taskID string) error {
 	return nil
 }
 
-func (tq *TaskQueue[T]) CancelAll() {
+// CancelAll cancels all queued and running tasks and returns how many were cancelled.
+func (tq *TaskQueue[T]) CancelAll() int {
 	tq.mu.RLock()
-	tasks := make([]*Task[T], 0, tq.tasks.Len())
+	tasks := make([]*Task[T], 0, tq.tasks.Len()+len(tq.runningTaskMap))
 	for element := tq.tasks.Front(); element != nil; element = element.Next() {
 		tasks = append(tasks, element.Value.(*Task[T]))
 	}
+	for _, task := range tq.runningTaskMap {
+		tasks = append(tasks, task)
+	}
 	tq.mu.RUnlock()
 
+	count := 0
 	for _, task := range tasks {
+		if task.IsCancelled() {
+			continue
+		}
 		task.Cancel()
+		count++
 	}
+	return count
 }
 
 func (tq *TaskQueue[T]) GetTask(taskID string) (*Task[T], error) {
@@ -70,6 +70,47 @@ func TestGetAndPeek(t *testing.T) {
 	}
 }
 
+func TestGetFuncAndCountFunc(t *testing.T) {
+	q := queue.NewTaskQueue[int]()
+	q.Add(queue.NewTask(context.Background(), "u1-a", 1))
+	q.Add(queue.NewTask(context.Background(), "u1-b", 1))
+	q.Add(queue.NewTask(context.Background(), "u2-a", 2))
+	isUser := func(user int) func(int) bool {
+		return func(data int) bool { return data == user }
+	}
+	if n := q.CountFunc(isUser(1)); n != 2 {
+		t.Fatalf("expected 2 tasks of user 1, got %d", n)
+	}
+	// skip the tasks of user 1, the first task of user 2 should be returned
+	task, err := q.GetFunc(isUser(2))
+	if err != nil {
+		t.Fatalf("unexpected error on GetFunc: %v", err)
+	}
+	if task.ID != "u2-a" {
+		t.Fatalf("expected GetFunc ID 'u2-a', got '%s'", task.ID)
+	}
+	// running tasks are counted too
+	if n := q.CountFunc(isUser(2)); n != 1 {
+		t.Fatalf("expected 1 task of user 2, got %d", n)
+	}
+
+	got := make(chan string)
+	go func() {
+		task, err := q.GetFunc(isUser(3))
+		if err != nil {
+			t.Errorf("unexpected error on GetFunc: %v", err)
+		}
+		got <- task.ID
+	}()
+	q.Add(queue.NewTask(context.Background(), "u3-a", 3))
+	if id := <-got; id != "u3-a" {
+		t.Fatalf("expected waiting GetFunc to return 'u3-a', got '%s'", id)
+	}
+	if q.Length() != 2 {
+		t.Fatalf("expected the tasks of user 1 to stay queued, got length %d", q.Length())
+	}
+}
+
 func TestCancelAndActiveLength(t *testing.T) {
 	q := queue.NewTaskQueue[int]()
 	t1 := newTask("1")
@@ -100,6 +141,38 @@ func TestRemoveTask(t *testing.T) {
 	if q.Length() != 0 {
 		t.Fatalf("expected length 0 after remove, got %d", q.Length())
 	}
+
+	t2 := newTask("r2")
+	q.Add(t2)
+	if _, err := q.Get(); err != nil {
+		t.Fatalf("unexpected error on Get: %v", err)
+	}
+	if err := q.RemoveTask("r2"); err == nil {
+		t.Fatal("expected error when removing a running task")
+	}
+	if t2.IsCancelled() {
+		t.Fatal("running task should not be cancelled by RemoveTask")
+	}
+}
+
+func TestCancelAll(t *testing.T) {
+	q := queue.NewTaskQueue[int]()
+	running := newTask("a1")
+	q.Add(running)
+	if _, err := q.Get(); err != nil {
+		t.Fatalf("unexpected error on Get: %v", err)
+	}
+	queued := newTask("a2")
+	q.Add(queued)
+	if n := q.CancelAll(); n != 2 {
+		t.Fatalf("expected 2 cancelled tasks, got %d", n)
+	}
+	if !running.IsCancelled() || !queued.IsCancelled() {
+		t.Fatal("expected both running and queued tasks to be cancelled")
+	}
+	if n := q.CancelAll(); n != 0 {
+		t.Fatalf("expected 0 cancelled tasks on second call, got %d", n)
+	}
 }
 
 func TestClearAndCleanupCancelled(t *testing.T) {
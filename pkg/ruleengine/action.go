@@ -0,0 +1,118 @@
+package ruleengine
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/krau/SaveAny-Bot/common/utils/strutil"
+)
+
+// Rule actions
+const (
+	ActionSave     = "save"      // save the file to the storage and path of the rule
+	ActionSkip     = "skip"      // do not save the file
+	ActionKeepName = "keep-name" // like save, but always use the original file name
+)
+
+// Actions returns all supported rule actions
+func Actions() []string {
+	return []string{ActionSave, ActionSkip, ActionKeepName}
+}
+
+// DefaultPathFallback replaces the placeholders without a value, e.g. the chat title of a file from an unknown chat
+const DefaultPathFallback = "unknown"
+
+// PathData holds the placeholders available in path templates, e.g. {{.ChatTitle}}/{{.Year}}/{{.Month}} or {{.Date "2006/01"}}
+type PathData struct {
+	ChatID    string
+	ChatTitle string
+	SenderID  string
+	UserID    string
+	FileName  string
+	MediaType string
+	Year      string
+	Month     string // 01-12
+	Day       string // 01-31
+
+	date time.Time
+}
+
+// NewPathData returns the placeholders of the input, the date is the one of the message, or now if it is unknown.
+// Unknown values are left empty
+func NewPathData(in Input) PathData {
+	date := in.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+	// placeholders must not add directories
+	clean := strings.NewReplacer("/", "_", "\\", "_")
+	return PathData{
+		ChatID:    formatID(in.ChatID),
+		ChatTitle: clean.Replace(in.ChatTitle),
+		SenderID:  formatID(in.SenderID),
+		UserID:    formatID(in.UserID),
+		FileName:  clean.Replace(in.FileName),
+		MediaType: in.MediaType,
+		Year:      date.Format("2006"),
+		Month:     date.Format("01"),
+		Day:       date.Format("02"),
+		date:      date,
+	}
+}
+
+func formatID(id int64) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.FormatInt(id, 10)
+}
+
+// Date formats the date with the Go layout, e.g. {{.Date "2006/01"}}
+func (d PathData) Date(layout string) string {
+	return d.date.Format(layout)
+}
+
+// Sanitize cleans the values like folder names and replaces the empty ones with fallback
+func (d PathData) Sanitize(fallback string) PathData {
+	for _, v := range []*string{&d.ChatID, &d.ChatTitle, &d.SenderID, &d.UserID, &d.FileName, &d.MediaType} {
+		if *v = strutil.CleanFolderName(*v); *v == "" {
+			*v = fallback
+		}
+	}
+	return d
+}
+
+// ParsePathTemplate parses a path template and checks that it only uses the placeholders of PathData
+func ParsePathTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("path").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	// unknown placeholders are only reported on execution
+	if err := tmpl.Execute(new(strings.Builder), PathData{}); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// RenderPath renders a path template into a relative, cleaned path
+func RenderPath(tmpl *template.Template, data PathData) (string, error) {
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("failed to render path template: %w", err)
+	}
+	return path.Clean("/" + sb.String())[1:], nil
+}
+
+// DirPath returns the directory the file is saved to, rendering the path template if the rule has one.
+// The placeholders are cleaned like folder names, as in the path templates of storages
+func (r *Rule) DirPath(in Input) (string, error) {
+	if r.pathTmpl == nil {
+		return r.Path, nil
+	}
+	return RenderPath(r.pathTmpl, NewPathData(in).Sanitize(DefaultPathFallback))
+}
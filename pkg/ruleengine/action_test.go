@@ -0,0 +1,94 @@
+package ruleengine
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDirPath(t *testing.T) {
+	in := Input{
+		ChatID:    1234567890,
+		ChatTitle: "AC/DC Fans",
+		FileName:  "song.mp3",
+		MediaType: MediaAudio,
+		Date:      time.Date(2025, 3, 7, 12, 0, 0, 0, time.UTC),
+	}
+	for _, tc := range []struct {
+		rule Rule
+		want string
+	}{
+		{Rule{Type: TypeKeyword, Value: "x", Path: "Music"}, "Music"},
+		{Rule{Type: TypeKeyword, Value: "x", PathTemplate: "{{.ChatTitle}}/{{.Year}}/{{.Month}}"}, "AC_DC_Fans/2025/03"},
+		{Rule{Type: TypeKeyword, Value: "x", PathTemplate: "/{{.MediaType}}/{{.ChatID}}-{{.Day}}/"}, "audio/1234567890-07"},
+		{Rule{Type: TypeKeyword, Value: "x", PathTemplate: "../{{.ChatTitle}}"}, "AC_DC_Fans"},
+		{Rule{Type: TypeKeyword, Value: "x", PathTemplate: "{{.SenderID}}/{{.FileName}}"}, "unknown/song.mp3"},
+	} {
+		r := mustRule(t, tc.rule)
+		got, err := r.DirPath(in)
+		if err != nil {
+			t.Fatalf("DirPath(%q): %v", tc.rule.PathTemplate, err)
+		}
+		if got != tc.want {
+			t.Errorf("DirPath(%q) = %q, want %q", tc.rule.PathTemplate, got, tc.want)
+		}
+	}
+}
+
+func TestRenderStoragePath(t *testing.T) {
+	tmpl, err := ParsePathTemplate(`{{.Date "2006/01"}}/{{.ChatTitle}}/{{.UserID}}-{{.ChatID}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		in   Input
+		want string
+	}{
+		{Input{UserID: 42, ChatID: 100, ChatTitle: "AC/DC: Fans?", Date: time.Date(2025, 3, 7, 0, 0, 0, 0, time.UTC)}, "2025/03/AC_DC_Fans/42-100"},
+		{Input{UserID: 42, Date: time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)}, "2024/12/unknown/42-unknown"},
+	} {
+		got, err := RenderPath(tmpl, NewPathData(tc.in).Sanitize("unknown"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("RenderPath(%+v) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestNewRuleFieldErrors(t *testing.T) {
+	for _, tc := range []struct {
+		rule Rule
+		want string
+	}{
+		{Rule{Type: TypeKeyword, Value: "x", Action: "delete"}, "action: unknown action"},
+		{Rule{Type: TypeKeyword, Value: "x", PathTemplate: "{{.Year"}, "path_template:"},
+		{Rule{Type: TypeKeyword, Value: "x", PathTemplate: "{{.Hour}}"}, "path_template:"},
+		{Rule{Type: TypeFileNameRegex, Value: "("}, "rule: invalid regex"},
+	} {
+		_, err := NewRule(tc.rule)
+		if err == nil || !strings.HasPrefix(err.Error(), tc.want) {
+			t.Errorf("NewRule(%+v) = %v, want an error starting with %q", tc.rule, err, tc.want)
+		}
+	}
+}
+
+func TestSkipRules(t *testing.T) {
+	engine := mustEngine(t, []*Rule{
+		mustRule(t, Rule{Type: TypeMediaType, Value: "sticker", Action: "Skip"}),
+		mustRule(t, Rule{Name: "ref", Type: TypeMediaType, Value: "photo"}),
+		mustRule(t, Rule{Type: TypeExtension, Value: "webp", Storage: "local", Action: ActionKeepName}),
+	})
+	r, ok := engine.Match(Input{FileName: "a.webp", MediaType: MediaSticker}, nil)
+	if !ok || !r.Skips() {
+		t.Errorf("expected the sticker to be skipped, got %v", r)
+	}
+	r, ok = engine.Match(Input{FileName: "a.webp", MediaType: MediaDocument}, nil)
+	if !ok || r.Skips() || r.Action != ActionKeepName {
+		t.Errorf("expected the keep-name rule, got %v", r)
+	}
+	if r, ok := engine.Match(Input{FileName: "a.jpg", MediaType: MediaPhoto}, nil); ok {
+		t.Errorf("reference-only rule should not route files, got %v", r)
+	}
+}
@@ -0,0 +1,98 @@
+package ruleengine
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// NormalizeChatID strips the -100 prefix of bot api style chat ids, so that they match the ids in MTProto updates
+func NormalizeChatID(id int64) int64 {
+	if id >= 0 {
+		return id
+	}
+	s := strconv.FormatInt(-id, 10)
+	if after, ok := strings.CutPrefix(s, "100"); ok && len(after) >= 10 {
+		if cid, err := strconv.ParseInt(after, 10, 64); err == nil {
+			return cid
+		}
+	}
+	return -id
+}
+
+// idList parses a comma separated list of chat or user ids
+func idList(value string) ([]int64, error) {
+	var ids []int64
+	for _, s := range strings.Split(value, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id %q", s)
+		}
+		ids = append(ids, NormalizeChatID(id))
+	}
+	if len(ids) == 0 {
+		return nil, errors.New("at least one id is required")
+	}
+	return ids, nil
+}
+
+// chatID matches the chat the message comes from
+type chatID struct {
+	ids []int64
+}
+
+func newChatID(value string) (*chatID, error) {
+	ids, err := idList(value)
+	if err != nil {
+		return nil, err
+	}
+	return &chatID{ids: ids}, nil
+}
+
+func (c *chatID) Match(in Input) bool {
+	return in.ChatID != 0 && slices.Contains(c.ids, in.ChatID)
+}
+
+// senderID matches the user who sent the message
+type senderID struct {
+	ids []int64
+}
+
+func newSenderID(value string) (*senderID, error) {
+	ids, err := idList(value)
+	if err != nil {
+		return nil, err
+	}
+	return &senderID{ids: ids}, nil
+}
+
+func (c *senderID) Match(in Input) bool {
+	return in.SenderID != 0 && slices.Contains(c.ids, in.SenderID)
+}
+
+// chatTitleRegex matches the title of the chat the message comes from
+type chatTitleRegex struct {
+	re *regexp.Regexp
+}
+
+func newChatTitleRegex(value string) (*chatTitleRegex, error) {
+	if value == "" {
+		return nil, errors.New("regex is required")
+	}
+	re, err := regexp.Compile(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex: %w", err)
+	}
+	return &chatTitleRegex{re: re}, nil
+}
+
+func (c *chatTitleRegex) Match(in Input) bool {
+	return in.ChatTitle != "" && c.re.MatchString(in.ChatTitle)
+}
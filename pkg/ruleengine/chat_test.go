@@ -0,0 +1,42 @@
+package ruleengine
+
+import "testing"
+
+func TestNormalizeChatID(t *testing.T) {
+	for id, want := range map[int64]int64{
+		-1001234567890: 1234567890,
+		1234567890:     1234567890,
+		-123456789:     123456789,
+		-1009:          1009,
+	} {
+		if got := NormalizeChatID(id); got != want {
+			t.Errorf("NormalizeChatID(%d) = %d, want %d", id, got, want)
+		}
+	}
+}
+
+func TestChatConditions(t *testing.T) {
+	for _, tc := range []struct {
+		typ, value string
+		in         Input
+		want       bool
+	}{
+		{TypeChatID, "-1001234567890", Input{ChatID: 1234567890}, true},
+		{TypeChatID, "777, 1234567890", Input{ChatID: 1234567890}, true},
+		{TypeChatID, "-1001234567890", Input{ChatID: 42}, false},
+		{TypeChatID, "42", Input{SenderID: 42}, false},
+		{TypeSenderID, "42, 43", Input{SenderID: 43}, true},
+		{TypeSenderID, "42", Input{ChatID: 42}, false},
+		{TypeChatTitle, `(?i)^movies`, Input{ChatTitle: "Movies HD"}, true},
+		{TypeChatTitle, `HD$`, Input{ChatTitle: "Music"}, false},
+		{TypeChatTitle, `.*`, Input{}, false},
+	} {
+		cond, err := NewCondition(tc.typ, tc.value)
+		if err != nil {
+			t.Fatalf("NewCondition(%q, %q): %v", tc.typ, tc.value, err)
+		}
+		if got := cond.Match(tc.in); got != tc.want {
+			t.Errorf("%s %q Match(%+v) = %v, want %v", tc.typ, tc.value, tc.in, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package ruleengine
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// recorder is a condition that records its evaluation
+type recorder struct {
+	name   string
+	result bool
+	calls  *[]string
+}
+
+func (c recorder) Match(Input) bool {
+	*c.calls = append(*c.calls, c.name)
+	return c.result
+}
+
+func TestComposeShortCircuit(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		own   *bool
+		allOf []bool
+		anyOf []bool
+		want  bool
+		calls []string
+	}{
+		{"all true", nil, []bool{true, true}, nil, true, []string{"all0", "all1"}},
+		{"all stops at first false", nil, []bool{false, true}, nil, false, []string{"all0"}},
+		{"any stops at first true", nil, nil, []bool{false, true, true}, true, []string{"any0", "any1"}},
+		{"any all false", nil, nil, []bool{false, false}, false, []string{"any0", "any1"}},
+		{"all before any", nil, []bool{true}, []bool{true}, true, []string{"all0", "any0"}},
+		{"failed all skips any", nil, []bool{false}, []bool{true}, false, []string{"all0"}},
+		{"own condition first", ptr(false), []bool{true}, []bool{true}, false, []string{"own"}},
+		{"own then refs", ptr(true), []bool{true}, []bool{true}, true, []string{"own", "all0", "any0"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls []string
+			leaf := func(name string, result bool) *Rule {
+				return &Rule{Name: name, cond: recorder{name: name, result: result, calls: &calls}}
+			}
+			r := &Rule{}
+			if tc.own != nil {
+				r.cond = recorder{name: "own", result: *tc.own, calls: &calls}
+			}
+			for i, result := range tc.allOf {
+				r.allOf = append(r.allOf, leaf("all"+string(rune('0'+i)), result))
+			}
+			for i, result := range tc.anyOf {
+				r.anyOf = append(r.anyOf, leaf("any"+string(rune('0'+i)), result))
+			}
+			if got := r.Match(Input{}); got != tc.want {
+				t.Errorf("Match = %v, want %v", got, tc.want)
+			}
+			if !slices.Equal(calls, tc.calls) {
+				t.Errorf("evaluated %v, want %v", calls, tc.calls)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestComposedRules(t *testing.T) {
+	engine := mustEngine(t, []*Rule{
+		mustRule(t, Rule{Name: "wallpaper", Type: TypeKeyword, Value: "壁纸, Wallpaper"}),
+		mustRule(t, Rule{Name: "photo", Type: TypeMediaType, Value: "photo"}),
+		mustRule(t, Rule{Name: "image", Type: TypeExtension, Value: "png, jpg"}),
+		mustRule(t, Rule{AllOf: []string{"wallpaper"}, AnyOf: []string{"photo", "image"}, Storage: "local", Path: "Wallpapers"}),
+		mustRule(t, Rule{Name: "big", Type: TypeSize, Value: ">1GB", Storage: "s3", Priority: 1}),
+	})
+	for _, tc := range []struct {
+		in   Input
+		want string
+	}{
+		{Input{Caption: "今日壁纸", MediaType: MediaPhoto, FileName: "a.jpg"}, "local"},
+		{Input{Caption: "nice WALLPAPER", MediaType: MediaDocument, FileName: "a.png"}, "local"},
+		{Input{Caption: "今日壁纸", MediaType: MediaDocument, FileName: "a.pdf"}, ""},
+		{Input{Caption: "photo", MediaType: MediaPhoto, FileName: "a.jpg"}, ""},
+		// reference-only rules never route files themselves
+		{Input{MediaType: MediaPhoto}, ""},
+		{Input{Caption: "壁纸", MediaType: MediaPhoto, Size: 2e9}, "local"},
+		{Input{Size: 2e9}, "s3"},
+	} {
+		got := ""
+		if r, ok := engine.Match(tc.in, nil); ok {
+			got = r.Storage
+		}
+		if got != tc.want {
+			t.Errorf("Match(%+v) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestNewReferenceErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		rules []Rule
+		want  []string
+		valid int // rules left in the engine
+	}{
+		{
+			name: "undefined",
+			rules: []Rule{
+				{Source: "rules[0]", Name: "a", AllOf: []string{"missing"}},
+				{Source: "rules[1]", Type: TypeKeyword, Value: "x", AnyOf: []string{"a"}, Storage: "s"},
+			},
+			want:  []string{`rules[0].all_of[0]: rule "missing" is not defined`},
+			valid: 0,
+		},
+		{
+			name: "duplicate name",
+			rules: []Rule{
+				{Source: "rules[0]", Name: "a", Type: TypeKeyword, Value: "x"},
+				{Source: "rules[1]", Name: "a", Type: TypeKeyword, Value: "y"},
+			},
+			want:  []string{`rules[1].name: duplicate rule name "a"`},
+			valid: 2,
+		},
+		{
+			name: "self reference",
+			rules: []Rule{
+				{Source: "rules[0]", Name: "a", Type: TypeKeyword, Value: "x", AllOf: []string{"a"}, Storage: "s"},
+			},
+			want:  []string{"rules[0]: reference cycle: a -> a"},
+			valid: 0,
+		},
+		{
+			name: "cycle",
+			rules: []Rule{
+				{Source: "rules[0]", Name: "top", AllOf: []string{"a"}, Storage: "s"},
+				{Source: "rules[1]", Name: "a", AllOf: []string{"b"}},
+				{Source: "rules[2]", Name: "b", AnyOf: []string{"c", "a"}},
+				{Source: "rules[3]", Name: "c", Type: TypeKeyword, Value: "x"},
+			},
+			want:  []string{"rules[1]: reference cycle: a -> b -> a"},
+			valid: 1,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rules := make([]*Rule, 0, len(tc.rules))
+			for _, r := range tc.rules {
+				rules = append(rules, mustRule(t, r))
+			}
+			engine, err := New(rules)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error does not mention %q:\n%s", want, err)
+				}
+			}
+			if n := strings.Count(err.Error(), "\n") + 1; n != len(tc.want) {
+				t.Errorf("expected %d errors, got:\n%s", len(tc.want), err)
+			}
+			if got := len(engine.Rules()); got != tc.valid {
+				t.Errorf("engine has %d rules, want %d", got, tc.valid)
+			}
+		})
+	}
+}
+
+func TestExplain(t *testing.T) {
+	engine := mustEngine(t, []*Rule{
+		mustRule(t, Rule{Name: "photo", Type: TypeMediaType, Value: "photo"}),
+		mustRule(t, Rule{Name: "big", Type: TypeSize, Value: ">1MB"}),
+		mustRule(t, Rule{Type: TypeKeyword, Value: "壁纸", AllOf: []string{"photo"}, AnyOf: []string{"big"}, UserID: 1, Storage: "s"}),
+	})
+	r := engine.Rules()[2]
+	got := r.Explain(Input{UserID: 1, MediaType: MediaVideo, Caption: "壁纸", Size: 2e6})
+	want := []string{`user_id 1: true`, `keyword "壁纸": true`, `all_of photo: false`, `any_of big: true`}
+	if !slices.Equal(got, want) {
+		t.Errorf("Explain = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,109 @@
+package ruleengine
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+type fileNameRegex struct {
+	re *regexp.Regexp
+}
+
+func newFileNameRegex(value string) (*fileNameRegex, error) {
+	if value == "" {
+		return nil, errors.New("regex is required")
+	}
+	re, err := regexp.Compile(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex: %w", err)
+	}
+	return &fileNameRegex{re: re}, nil
+}
+
+func (c *fileNameRegex) Match(in Input) bool {
+	return c.re.MatchString(in.FileName)
+}
+
+// extension matches a comma separated list of extensions like "epub, .mobi, tar.gz", case-insensitive
+type extension struct {
+	exts []string
+}
+
+func newExtension(value string) (*extension, error) {
+	var exts []string
+	for _, ext := range strings.Split(value, ",") {
+		ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+		if ext != "" {
+			exts = append(exts, "."+ext)
+		}
+	}
+	if len(exts) == 0 {
+		return nil, errors.New("at least one extension is required")
+	}
+	return &extension{exts: exts}, nil
+}
+
+func (c *extension) Match(in Input) bool {
+	name := strings.ToLower(in.FileName)
+	for _, ext := range c.exts {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// mediaType matches a comma separated list of media types like "photo, video"
+type mediaType struct {
+	types []string
+}
+
+func newMediaType(value string) (*mediaType, error) {
+	var types []string
+	for _, t := range strings.Split(value, ",") {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" {
+			continue
+		}
+		if !slices.Contains(MediaTypes(), t) {
+			return nil, fmt.Errorf("unknown media type %q, must be one of: %s", t, strings.Join(MediaTypes(), ", "))
+		}
+		types = append(types, t)
+	}
+	if len(types) == 0 {
+		return nil, errors.New("at least one media type is required")
+	}
+	return &mediaType{types: types}, nil
+}
+
+func (c *mediaType) Match(in Input) bool {
+	return slices.Contains(c.types, in.MediaType)
+}
+
+// keyword matches the caption containing any of a comma separated list of keywords, case-insensitive
+type keyword struct {
+	words []string
+}
+
+func newKeyword(value string) (*keyword, error) {
+	var words []string
+	for _, w := range strings.Split(value, ",") {
+		if w = strings.ToLower(strings.TrimSpace(w)); w != "" {
+			words = append(words, w)
+		}
+	}
+	if len(words) == 0 {
+		return nil, errors.New("at least one keyword is required")
+	}
+	return &keyword{words: words}, nil
+}
+
+func (c *keyword) Match(in Input) bool {
+	caption := strings.ToLower(in.Caption)
+	return slices.ContainsFunc(c.words, func(w string) bool {
+		return strings.Contains(caption, w)
+	})
+}
@@ -0,0 +1,347 @@
+// Package ruleengine decides where a file is saved by the rules in the config.
+// It does not depend on telegram or the config package, the caller fills in the Input.
+package ruleengine
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Rule types
+const (
+	TypeFileNameRegex = "filename-regex"
+	TypeExtension     = "extension"
+	TypeMediaType     = "media-type"
+	TypeSize          = "size"
+	TypeChatID        = "chat-id"
+	TypeSenderID      = "sender-id"
+	TypeChatTitle     = "chat-title-regex"
+	TypeKeyword       = "keyword"
+)
+
+// Types returns all supported rule types
+func Types() []string {
+	return []string{TypeFileNameRegex, TypeExtension, TypeMediaType, TypeSize, TypeChatID, TypeSenderID, TypeChatTitle, TypeKeyword}
+}
+
+// Media types of telegram messages
+const (
+	MediaPhoto    = "photo"
+	MediaVideo    = "video"
+	MediaDocument = "document"
+	MediaAudio    = "audio"
+	MediaVoice    = "voice"
+	MediaSticker  = "sticker"
+)
+
+// MediaTypes returns all media types a media-type rule can match
+func MediaTypes() []string {
+	return []string{MediaPhoto, MediaVideo, MediaDocument, MediaAudio, MediaVoice, MediaSticker}
+}
+
+// Input is the information about a file that rules match against.
+// It is taken from the telegram media before the download starts.
+type Input struct {
+	UserID    int64 // the user who saves the file
+	FileName  string
+	Size      int64
+	MediaType string
+	Caption   string
+	Date      time.Time // when the message was sent
+	// the origin of the message, taken from the forward header if the message is forwarded
+	ChatID    int64 // without the -100 prefix
+	ChatTitle string
+	SenderID  int64
+}
+
+// Condition reports whether a file matches a rule
+type Condition interface {
+	Match(in Input) bool
+}
+
+// NewCondition parses the value of a rule of the given type
+func NewCondition(ruleType, value string) (Condition, error) {
+	switch strings.ToLower(ruleType) {
+	case TypeFileNameRegex:
+		return newFileNameRegex(value)
+	case TypeExtension:
+		return newExtension(value)
+	case TypeMediaType:
+		return newMediaType(value)
+	case TypeSize:
+		return ParseSizeRange(value)
+	case TypeChatID:
+		return newChatID(value)
+	case TypeSenderID:
+		return newSenderID(value)
+	case TypeChatTitle:
+		return newChatTitleRegex(value)
+	case TypeKeyword:
+		return newKeyword(value)
+	}
+	return nil, fmt.Errorf("unknown rule type %q, must be one of: %s", ruleType, strings.Join(Types(), ", "))
+}
+
+// Rule routes the files matching its condition to a storage and path
+type Rule struct {
+	Name         string // used to reference the rule in AllOf and AnyOf of other rules
+	Type         string // may be empty if the rule only composes other rules
+	Value        string
+	AllOf        []string // names of rules that must all match
+	AnyOf        []string // names of rules of which at least one must match
+	Storage      string   // rules without a storage only serve as a reference and never route files
+	Path         string
+	PathTemplate string // replaces Path if set, see PathData for the placeholders
+	Action       string // one of Actions, empty means ActionSave
+	Silent       bool   // do not notify the user when a file is skipped
+	Priority     int    // rules with a lower priority are evaluated first
+	UserID       int64  // 0 applies to all users
+	Source       string // where the rule is defined, e.g. rules[2]
+
+	cond     Condition
+	allOf    []*Rule
+	anyOf    []*Rule
+	pathTmpl *template.Template
+}
+
+// NewRule parses the condition, action and path template of the rule, the references are resolved by New.
+// Errors are prefixed with the name of the invalid field, e.g. "rule: invalid regex".
+func NewRule(r Rule) (*Rule, error) {
+	if r.Type != "" || (len(r.AllOf) == 0 && len(r.AnyOf) == 0) {
+		cond, err := NewCondition(r.Type, r.Value)
+		if err != nil {
+			return nil, fmt.Errorf("rule: %w", err)
+		}
+		r.cond = cond
+	}
+	r.Action = strings.ToLower(r.Action)
+	if r.Action == "" {
+		r.Action = ActionSave
+	}
+	if !slices.Contains(Actions(), r.Action) {
+		return nil, fmt.Errorf("action: unknown action %q, must be one of: %s", r.Action, strings.Join(Actions(), ", "))
+	}
+	if r.PathTemplate != "" {
+		tmpl, err := ParsePathTemplate(r.PathTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("path_template: %w", err)
+		}
+		r.pathTmpl = tmpl
+	}
+	return &r, nil
+}
+
+// Match reports whether the rule applies to the user and the file matches it.
+// Its own condition is evaluated first, then AllOf and AnyOf in the order they are listed,
+// stopping as soon as the result is known.
+func (r *Rule) Match(in Input) bool {
+	if r.UserID != 0 && r.UserID != in.UserID {
+		return false
+	}
+	if r.cond != nil && !r.cond.Match(in) {
+		return false
+	}
+	for _, ref := range r.allOf {
+		if !ref.Match(in) {
+			return false
+		}
+	}
+	if len(r.anyOf) == 0 {
+		return true
+	}
+	for _, ref := range r.anyOf {
+		if ref.Match(in) {
+			return true
+		}
+	}
+	return false
+}
+
+// Explain evaluates every part of the rule without stopping early and describes the results,
+// e.g. [extension "mkv": true, all_of big: false]
+func (r *Rule) Explain(in Input) []string {
+	var parts []string
+	if r.UserID != 0 {
+		parts = append(parts, fmt.Sprintf("user_id %d: %t", r.UserID, r.UserID == in.UserID))
+	}
+	if r.cond != nil {
+		parts = append(parts, fmt.Sprintf("%s %q: %t", r.Type, r.Value, r.cond.Match(in)))
+	}
+	for _, ref := range r.allOf {
+		parts = append(parts, fmt.Sprintf("all_of %s: %t", ref.Name, ref.Match(in)))
+	}
+	for _, ref := range r.anyOf {
+		parts = append(parts, fmt.Sprintf("any_of %s: %t", ref.Name, ref.Match(in)))
+	}
+	return parts
+}
+
+// Routes reports whether the rule decides what happens to files,
+// rules without a storage that do not skip files are only referenced by other rules
+func (r *Rule) Routes() bool {
+	return r.Storage != "" || r.Skips()
+}
+
+// Skips reports whether files matching the rule are not saved
+func (r *Rule) Skips() bool {
+	return r.Action == ActionSkip
+}
+
+// Engine evaluates rules in priority order, the first matching rule wins
+type Engine struct {
+	rules []*Rule
+}
+
+// New resolves the references between the rules and returns an engine of them,
+// rules with the same priority keep their order. Undefined references and cycles are reported,
+// the rules involved are left out of the engine.
+func New(rules []*Rule) (*Engine, error) {
+	byName := make(map[string]*Rule, len(rules))
+	var errs []error
+	for _, r := range rules {
+		if r.Name == "" {
+			continue
+		}
+		if _, ok := byName[r.Name]; ok {
+			errs = append(errs, fmt.Errorf("%s.name: duplicate rule name %q", r.where(), r.Name))
+			continue
+		}
+		byName[r.Name] = r
+	}
+	invalid := make(map[*Rule]bool)
+	for _, r := range rules {
+		var err error
+		if r.allOf, err = resolveRefs(r, "all_of", r.AllOf, byName); err != nil {
+			errs = append(errs, err)
+			invalid[r] = true
+			continue
+		}
+		if r.anyOf, err = resolveRefs(r, "any_of", r.AnyOf, byName); err != nil {
+			errs = append(errs, err)
+			invalid[r] = true
+		}
+	}
+	for _, r := range rules {
+		if invalid[r] {
+			continue
+		}
+		if cycle := findCycle(r, r, nil); cycle != nil {
+			names := []string{r.Name}
+			for _, c := range cycle {
+				names = append(names, c.Name)
+				invalid[c] = true
+			}
+			errs = append(errs, fmt.Errorf("%s: reference cycle: %s", r.where(), strings.Join(names, " -> ")))
+		}
+	}
+
+	var broken func(r *Rule) bool
+	broken = func(r *Rule) bool {
+		if !invalid[r] && (slices.ContainsFunc(r.allOf, broken) || slices.ContainsFunc(r.anyOf, broken)) {
+			invalid[r] = true
+		}
+		return invalid[r]
+	}
+	sorted := slices.DeleteFunc(slices.Clone(rules), broken)
+	slices.SortStableFunc(sorted, func(a, b *Rule) int {
+		return a.Priority - b.Priority
+	})
+	return &Engine{rules: sorted}, errors.Join(errs...)
+}
+
+func resolveRefs(r *Rule, field string, names []string, byName map[string]*Rule) ([]*Rule, error) {
+	refs := make([]*Rule, 0, len(names))
+	for i, name := range names {
+		ref, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("%s.%s[%d]: rule %q is not defined", r.where(), field, i, name)
+		}
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
+
+// findCycle returns the rules along a path of references from r back to start, or nil if there is none
+func findCycle(start, r *Rule, seen map[*Rule]bool) []*Rule {
+	if seen == nil {
+		seen = make(map[*Rule]bool)
+	}
+	seen[r] = true
+	for _, ref := range slices.Concat(r.allOf, r.anyOf) {
+		if ref == start {
+			return []*Rule{ref}
+		}
+		if seen[ref] {
+			continue
+		}
+		if path := findCycle(start, ref, seen); path != nil {
+			return append([]*Rule{ref}, path...)
+		}
+	}
+	return nil
+}
+
+// where returns the source of the rule for error messages
+func (r *Rule) where() string {
+	if r.Source != "" {
+		return r.Source
+	}
+	return fmt.Sprintf("rule %q", r.Name)
+}
+
+// Rules returns the rules in evaluation order
+func (e *Engine) Rules() []*Rule {
+	if e == nil {
+		return nil
+	}
+	return e.rules
+}
+
+// Match returns the first rule matching the file, accept can reject a matching rule
+// (e.g. when its storage is not available to the user) so that the next one is tried.
+func (e *Engine) Match(in Input, accept func(r *Rule) bool) (*Rule, bool) {
+	for _, r := range e.Rules() {
+		if !r.Routes() || !r.Match(in) {
+			continue
+		}
+		if accept != nil && !accept(r) {
+			continue
+		}
+		return r, true
+	}
+	return nil, false
+}
+
+// String describes the rule, e.g. rules[2] extension "epub, mobi" -> WebDAV:Books
+func (r *Rule) String() string {
+	var conds []string
+	if r.Type != "" {
+		conds = append(conds, fmt.Sprintf("%s %q", r.Type, r.Value))
+	}
+	if len(r.AllOf) > 0 {
+		conds = append(conds, "all_of "+strings.Join(r.AllOf, ", "))
+	}
+	if len(r.AnyOf) > 0 {
+		conds = append(conds, "any_of "+strings.Join(r.AnyOf, ", "))
+	}
+	var s string
+	switch {
+	case r.Skips():
+		s = strings.Join(conds, " and ") + " -> skip"
+	case r.PathTemplate != "":
+		s = fmt.Sprintf("%s -> %s:%s", strings.Join(conds, " and "), r.Storage, r.PathTemplate)
+	default:
+		s = fmt.Sprintf("%s -> %s:%s", strings.Join(conds, " and "), r.Storage, r.Path)
+	}
+	if r.Action == ActionKeepName {
+		s += " (keep-name)"
+	}
+	if r.Source != "" {
+		s = r.Source + " " + s
+	}
+	return s
+}
@@ -0,0 +1,80 @@
+package ruleengine
+
+import "testing"
+
+func mustRule(t *testing.T, r Rule) *Rule {
+	t.Helper()
+	rule, err := NewRule(r)
+	if err != nil {
+		t.Fatalf("NewRule(%+v): %v", r, err)
+	}
+	return rule
+}
+
+func mustEngine(t *testing.T, rules []*Rule) *Engine {
+	t.Helper()
+	engine, err := New(rules)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return engine
+}
+
+func TestEngineMatch(t *testing.T) {
+	engine := mustEngine(t, []*Rule{
+		mustRule(t, Rule{Type: TypeExtension, Value: "epub, .MOBI", Storage: "books", Priority: 2}),
+		mustRule(t, Rule{Type: TypeFileNameRegex, Value: `S\d+E\d+`, Storage: "tv", Priority: 1}),
+		mustRule(t, Rule{Type: TypeExtension, Value: "mkv", Storage: "movies", Priority: 1, UserID: 2}),
+		mustRule(t, Rule{Type: TypeExtension, Value: "mkv", Storage: "other", Priority: 3}),
+		mustRule(t, Rule{Type: TypeSize, Value: ">500MB", Storage: "s3", Priority: 4}),
+		mustRule(t, Rule{Type: TypeMediaType, Value: "photo, sticker", Storage: "local", Priority: 5}),
+	})
+	for _, tc := range []struct {
+		in   Input
+		want string
+	}{
+		{Input{UserID: 1, FileName: "book.epub"}, "books"},
+		{Input{UserID: 1, FileName: "Book.mobi"}, "books"},
+		{Input{UserID: 1, FileName: "Show.S01E02.mkv"}, "tv"},
+		{Input{UserID: 2, FileName: "movie.mkv"}, "movies"},
+		{Input{UserID: 1, FileName: "movie.mkv"}, "other"},
+		{Input{UserID: 1, FileName: "photo.jpg", MediaType: MediaPhoto}, "local"},
+		{Input{UserID: 1, FileName: "big.jpg", MediaType: MediaPhoto, Size: 600e6}, "s3"},
+		{Input{UserID: 1, FileName: "song.mp3", MediaType: MediaAudio}, ""},
+		{Input{UserID: 1, FileName: "epub"}, ""},
+	} {
+		got := ""
+		if r, ok := engine.Match(tc.in, nil); ok {
+			got = r.Storage
+		}
+		if got != tc.want {
+			t.Errorf("Match(%+v) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+
+	// a rejected rule falls through to the next one
+	r, ok := engine.Match(Input{UserID: 2, FileName: "movie.mkv"}, func(r *Rule) bool { return r.Storage != "movies" })
+	if !ok || r.Storage != "other" {
+		t.Errorf("expected rejected rule to fall through to %q, got %+v", "other", r)
+	}
+}
+
+func TestNewConditionErrors(t *testing.T) {
+	for _, tc := range []struct{ typ, value string }{
+		{TypeFileNameRegex, "("},
+		{TypeFileNameRegex, ""},
+		{TypeExtension, " , ."},
+		{"mime", "video/*"},
+		{TypeMediaType, "gif"},
+		{TypeSize, "500MB"},
+		{TypeChatID, "abc"},
+		{TypeSenderID, " , "},
+		{TypeChatTitle, "["},
+		{TypeKeyword, ","},
+		{"", ""},
+	} {
+		if _, err := NewCondition(tc.typ, tc.value); err == nil {
+			t.Errorf("NewCondition(%q, %q): expected an error", tc.typ, tc.value)
+		}
+	}
+}
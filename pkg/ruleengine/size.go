@@ -0,0 +1,90 @@
+package ruleengine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/krau/SaveAny-Bot/config/types"
+)
+
+// SizeRange matches files whose size is within Min and Max, both inclusive.
+// A negative bound is open.
+type SizeRange struct {
+	Min int64
+	Max int64
+}
+
+// ParseSizeRange parses size expressions like ">500MB", "<=1GiB", "10MB-1GB", "10MB-" or "-1GB".
+// Sizes use the same units as the config, e.g. "500MB" or "1.5GiB".
+func ParseSizeRange(expr string) (*SizeRange, error) {
+	s := strings.TrimSpace(expr)
+	parse := func(v string) (int64, error) {
+		var size types.Size
+		if err := size.UnmarshalText([]byte(v)); err != nil {
+			return 0, err
+		}
+		return size.Bytes(), nil
+	}
+	invalid := func(err error) error {
+		return fmt.Errorf("invalid size expression %q, use one like \">500MB\" or \"10MB-1GB\": %w", expr, err)
+	}
+
+	for _, op := range []string{">=", "<=", ">", "<"} {
+		v, ok := strings.CutPrefix(s, op)
+		if !ok {
+			continue
+		}
+		n, err := parse(v)
+		if err != nil {
+			return nil, invalid(err)
+		}
+		switch op {
+		case ">=":
+			return &SizeRange{Min: n, Max: -1}, nil
+		case ">":
+			return &SizeRange{Min: n + 1, Max: -1}, nil
+		case "<=":
+			return &SizeRange{Min: -1, Max: n}, nil
+		default:
+			if n == 0 {
+				return nil, invalid(fmt.Errorf("no size is smaller than 0"))
+			}
+			return &SizeRange{Min: -1, Max: n - 1}, nil
+		}
+	}
+
+	minStr, maxStr, ok := strings.Cut(s, "-")
+	if !ok {
+		return nil, invalid(fmt.Errorf("missing comparison operator or range"))
+	}
+	minStr, maxStr = strings.TrimSpace(minStr), strings.TrimSpace(maxStr)
+	if minStr == "" && maxStr == "" {
+		return nil, invalid(fmt.Errorf("both bounds are empty"))
+	}
+	r := &SizeRange{Min: -1, Max: -1}
+	var err error
+	if minStr != "" {
+		if r.Min, err = parse(minStr); err != nil {
+			return nil, invalid(err)
+		}
+	}
+	if maxStr != "" {
+		if r.Max, err = parse(maxStr); err != nil {
+			return nil, invalid(err)
+		}
+	}
+	if r.Min >= 0 && r.Max >= 0 && r.Min > r.Max {
+		return nil, invalid(fmt.Errorf("lower bound is greater than upper bound"))
+	}
+	return r, nil
+}
+
+func (r *SizeRange) Match(in Input) bool {
+	if r.Min >= 0 && in.Size < r.Min {
+		return false
+	}
+	if r.Max >= 0 && in.Size > r.Max {
+		return false
+	}
+	return true
+}
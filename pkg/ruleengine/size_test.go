@@ -0,0 +1,65 @@
+package ruleengine
+
+import "testing"
+
+func TestParseSizeRange(t *testing.T) {
+	for _, tc := range []struct {
+		expr     string
+		min, max int64
+	}{
+		{">500MB", 500e6 + 1, -1},
+		{">=500MB", 500e6, -1},
+		{"<1KiB", -1, 1023},
+		{"<= 1KiB", -1, 1024},
+		{"10MB-1GB", 10e6, 1e9},
+		{"10MB - 1GB", 10e6, 1e9},
+		{"1.5GiB-", 1.5 * (1 << 30), -1},
+		{"-1GB", -1, 1e9},
+		{"0-0", 0, 0},
+	} {
+		r, err := ParseSizeRange(tc.expr)
+		if err != nil {
+			t.Errorf("ParseSizeRange(%q): %v", tc.expr, err)
+			continue
+		}
+		if r.Min != tc.min || r.Max != tc.max {
+			t.Errorf("ParseSizeRange(%q) = [%d, %d], want [%d, %d]", tc.expr, r.Min, r.Max, tc.min, tc.max)
+		}
+	}
+}
+
+func TestParseSizeRangeErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"500MB",
+		">",
+		">abc",
+		">500XB",
+		"<0",
+		"-",
+		"1GB-10MB",
+		"10MB-1GB-2GB",
+		">-5MB",
+	} {
+		if _, err := ParseSizeRange(expr); err == nil {
+			t.Errorf("ParseSizeRange(%q): expected an error", expr)
+		}
+	}
+}
+
+func TestSizeRangeMatch(t *testing.T) {
+	r, err := ParseSizeRange("10MB-1GB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for size, want := range map[int64]bool{
+		10e6 - 1: false,
+		10e6:     true,
+		1e9:      true,
+		1e9 + 1:  false,
+	} {
+		if got := r.Match(Input{Size: size}); got != want {
+			t.Errorf("Match(size=%d) = %v, want %v", size, got, want)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package ruleengine
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/krau/SaveAny-Bot/config/types"
+)
+
+// SplitFields parses space separated key=value pairs, values containing spaces can be quoted
+// with ' or ", e.g. type=extension rule="epub, mobi". Keys are lower-cased and must not repeat.
+func SplitFields(text string) (map[string]string, error) {
+	fields := make(map[string]string)
+	rs := []rune(strings.TrimSpace(text))
+	for i := 0; i < len(rs); {
+		if unicode.IsSpace(rs[i]) {
+			i++
+			continue
+		}
+		start := i
+		for i < len(rs) && rs[i] != '=' && !unicode.IsSpace(rs[i]) {
+			i++
+		}
+		if i >= len(rs) || rs[i] != '=' || i == start {
+			return nil, fmt.Errorf("expected key=value, got %q", string(rs[start:i]))
+		}
+		key := strings.ToLower(string(rs[start:i]))
+		i++
+		var value strings.Builder
+		if i < len(rs) && (rs[i] == '"' || rs[i] == '\'') {
+			quote := rs[i]
+			i++
+			closed := false
+			for i < len(rs) {
+				if rs[i] == '\\' && quote == '"' && i+1 < len(rs) && (rs[i+1] == '"' || rs[i+1] == '\\') {
+					value.WriteRune(rs[i+1])
+					i += 2
+					continue
+				}
+				if rs[i] == quote {
+					closed = true
+					i++
+					break
+				}
+				value.WriteRune(rs[i])
+				i++
+			}
+			if !closed {
+				return nil, fmt.Errorf("%s: unterminated quote", key)
+			}
+		} else {
+			for i < len(rs) && !unicode.IsSpace(rs[i]) {
+				value.WriteRune(rs[i])
+				i++
+			}
+		}
+		if _, ok := fields[key]; ok {
+			return nil, fmt.Errorf("%s: set more than once", key)
+		}
+		fields[key] = value.String()
+	}
+	return fields, nil
+}
+
+// ParseRule parses a rule written as key=value pairs with the same fields as the rules in the config,
+// e.g. type=extension rule="epub, mobi" storage=WebDAV path=Books. The rule is not compiled.
+func ParseRule(text string) (Rule, error) {
+	fields, err := SplitFields(text)
+	if err != nil {
+		return Rule{}, err
+	}
+	if len(fields) == 0 {
+		return Rule{}, errors.New("the rule is empty")
+	}
+	var r Rule
+	for key, value := range fields {
+		switch key {
+		case "name":
+			r.Name = value
+		case "type":
+			r.Type = value
+		case "rule":
+			r.Value = value
+		case "all_of":
+			r.AllOf = splitList(value)
+		case "any_of":
+			r.AnyOf = splitList(value)
+		case "storage":
+			r.Storage = value
+		case "path":
+			r.Path = value
+		case "path_template":
+			r.PathTemplate = value
+		case "action":
+			r.Action = value
+		case "silent":
+			if r.Silent, err = strconv.ParseBool(value); err != nil {
+				return Rule{}, fmt.Errorf("silent: invalid bool %q", value)
+			}
+		case "priority":
+			if r.Priority, err = strconv.Atoi(value); err != nil {
+				return Rule{}, fmt.Errorf("priority: invalid number %q", value)
+			}
+		case "user_id":
+			if r.UserID, err = strconv.ParseInt(value, 10, 64); err != nil {
+				return Rule{}, fmt.Errorf("user_id: invalid number %q", value)
+			}
+		default:
+			return Rule{}, fmt.Errorf("unknown field %q", key)
+		}
+	}
+	return r, nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// ParseInput parses a file described as key=value pairs, for testing rules without a message, e.g.
+// name=foo.mkv size=700MB chat=-1001234567890. The keys are name, size, media, caption, chat, chat_title,
+// sender, user and date (2006-01-02).
+func ParseInput(text string) (Input, error) {
+	fields, err := SplitFields(text)
+	if err != nil {
+		return Input{}, err
+	}
+	var in Input
+	for key, value := range fields {
+		switch key {
+		case "name":
+			in.FileName = value
+		case "size":
+			var size types.Size
+			if err := size.UnmarshalText([]byte(value)); err != nil {
+				return Input{}, fmt.Errorf("size: %w", err)
+			}
+			in.Size = size.Bytes()
+		case "media":
+			in.MediaType = strings.ToLower(value)
+			if !slices.Contains(MediaTypes(), in.MediaType) {
+				return Input{}, fmt.Errorf("media: unknown media type %q, must be one of: %s", value, strings.Join(MediaTypes(), ", "))
+			}
+		case "caption":
+			in.Caption = value
+		case "chat", "sender", "user":
+			id, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return Input{}, fmt.Errorf("%s: invalid id %q", key, value)
+			}
+			switch key {
+			case "chat":
+				in.ChatID = NormalizeChatID(id)
+			case "sender":
+				in.SenderID = id
+			default:
+				in.UserID = id
+			}
+		case "chat_title":
+			in.ChatTitle = value
+		case "date":
+			if in.Date, err = time.ParseInLocation("2006-01-02", value, time.Local); err != nil {
+				return Input{}, fmt.Errorf("date: invalid date %q, use one like 2006-01-02", value)
+			}
+		default:
+			return Input{}, fmt.Errorf("unknown field %q", key)
+		}
+	}
+	return in, nil
+}
@@ -0,0 +1,90 @@
+package ruleengine
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitFields(t *testing.T) {
+	got, err := SplitFields(`type=extension  rule="epub, mobi" path='My Books' name=a\b title="say \"hi\""`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"type":  "extension",
+		"rule":  "epub, mobi",
+		"path":  "My Books",
+		"name":  `a\b`,
+		"title": `say "hi"`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SplitFields = %v, want %v", got, want)
+	}
+
+	for text, wantErr := range map[string]string{
+		`type`:               `expected key=value, got "type"`,
+		`=x`:                 `expected key=value, got ""`,
+		`rule="epub`:         "rule: unterminated quote",
+		`path=a path=b`:      "path: set more than once",
+		`type=size rule >1G`: `expected key=value, got "rule"`,
+	} {
+		if _, err := SplitFields(text); err == nil || err.Error() != wantErr {
+			t.Errorf("SplitFields(%q) = %v, want %q", text, err, wantErr)
+		}
+	}
+}
+
+func TestParseRule(t *testing.T) {
+	got, err := ParseRule(`name=wp type=keyword rule="壁纸, wallpaper" any_of="photo, image" storage=local path_template={{.Year}} action=keep-name silent=true priority=-2 user_id=42`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Rule{
+		Name: "wp", Type: TypeKeyword, Value: "壁纸, wallpaper", AnyOf: []string{"photo", "image"},
+		Storage: "local", PathTemplate: "{{.Year}}", Action: ActionKeepName, Silent: true, Priority: -2, UserID: 42,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseRule = %+v, want %+v", got, want)
+	}
+
+	for text, wantErr := range map[string]string{
+		``:                 "the rule is empty",
+		`type=size stor=x`: `unknown field "stor"`,
+		`priority=high`:    "priority: invalid number",
+		`silent=maybe`:     "silent: invalid bool",
+		`user_id=-100abc`:  "user_id: invalid number",
+	} {
+		if _, err := ParseRule(text); err == nil || !strings.HasPrefix(err.Error(), wantErr) {
+			t.Errorf("ParseRule(%q) = %v, want %q", text, err, wantErr)
+		}
+	}
+}
+
+func TestParseInput(t *testing.T) {
+	got, err := ParseInput(`name=foo.mkv size=700MB chat=-1001234567890 chat_title="My Channel" sender=42 media=Video caption="a b" user=7 date=2025-01-31`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Input{
+		UserID: 7, FileName: "foo.mkv", Size: 700e6, MediaType: MediaVideo, Caption: "a b",
+		ChatID: 1234567890, ChatTitle: "My Channel", SenderID: 42,
+		Date: time.Date(2025, 1, 31, 0, 0, 0, 0, time.Local),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseInput = %+v, want %+v", got, want)
+	}
+
+	for text, wantErr := range map[string]string{
+		`size=huge`:      "size:",
+		`media=gif`:      `media: unknown media type "gif"`,
+		`chat=@channel`:  `chat: invalid id "@channel"`,
+		`date=31/01`:     `date: invalid date "31/01"`,
+		`filename=a.mkv`: `unknown field "filename"`,
+	} {
+		if _, err := ParseInput(text); err == nil || !strings.HasPrefix(err.Error(), wantErr) {
+			t.Errorf("ParseInput(%q) = %v, want %q", text, err, wantErr)
+		}
+	}
+}
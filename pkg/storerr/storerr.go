@@ -0,0 +1,22 @@
+// Package storerr defines the errors storages wrap when a save fails for a known reason,
+// so that the tasks retry and report them the same way for every storage.
+package storerr
+
+import "errors"
+
+var (
+	ErrFileTooLarge  = errors.New("file is too large")
+	ErrNoSpace       = errors.New("not enough space in the storage")
+	ErrPermission    = errors.New("permission denied by the storage")
+	ErrInvalidPath   = errors.New("invalid path for the storage")
+	ErrAlreadyExists = errors.New("file already exists in the storage")
+)
+
+// IsPermanent reports whether saving the same file again can't succeed.
+// ErrAlreadyExists is not, the next attempt picks another name
+func IsPermanent(err error) bool {
+	return errors.Is(err, ErrFileTooLarge) ||
+		errors.Is(err, ErrNoSpace) ||
+		errors.Is(err, ErrPermission) ||
+		errors.Is(err, ErrInvalidPath)
+}
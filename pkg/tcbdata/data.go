@@ -9,6 +9,7 @@ import (
 const (
 	TypeAdd        = "add"
 	TypeSetDefault = "setdefault"
+	TypeChoose     = "choose"
 )
 
 // type TaskDataTGFiles struct {
@@ -42,3 +43,9 @@ type Add struct {
 type SetDefaultStorage struct {
 	StorageName string
 }
+
+// Choose replaces a queued task saved to the default storage with the storage selection
+type Choose struct {
+	TaskID string
+	Files  []tfile.TGFileMessage
+}
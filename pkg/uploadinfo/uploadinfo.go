@@ -0,0 +1,44 @@
+// Package uploadinfo collects what the storages report about the files they saved during a task,
+// such as the id given by the remote service, so that the hooks of the task can use it.
+package uploadinfo
+
+import (
+	"context"
+	"sync"
+
+	"github.com/krau/SaveAny-Bot/pkg/enums/ctxkey"
+)
+
+type File struct {
+	Storage string `json:"storage"`
+	Path    string `json:"path"`
+	ID      string `json:"id"`
+}
+
+type Collector struct {
+	mu    sync.Mutex
+	files []File
+}
+
+// WithCollector returns a context whose storages report the saved files to the returned collector
+func WithCollector(ctx context.Context) (context.Context, *Collector) {
+	c := &Collector{}
+	return context.WithValue(ctx, ctxkey.UploadedFiles, c), c
+}
+
+// Record reports a saved file, it does nothing if ctx has no collector
+func Record(ctx context.Context, file File) {
+	c, ok := ctx.Value(ctxkey.UploadedFiles).(*Collector)
+	if !ok {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.files = append(c.files, file)
+}
+
+func (c *Collector) Files() []File {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]File(nil), c.files...)
+}
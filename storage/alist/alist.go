@@ -42,40 +42,13 @@ func (a *Alist) Init(ctx context.Context, cfg config.StorageConfig) error {
 
 	if alistConfig.Token != "" {
 		a.token = alistConfig.Token
-		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+		ctx, cancel := context.WithTimeout(ctx, 1*time.Minute)
 		defer cancel()
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.baseURL+"/api/me", nil)
+		username, err := a.me(ctx)
 		if err != nil {
-			a.logger.Fatalf("Failed to create request: %v", err)
 			return err
 		}
-		req.Header.Set("Authorization", a.token)
-
-		resp, err := a.client.Do(req)
-		if err != nil {
-			a.logger.Fatalf("Failed to send request: %v", err)
-			return err
-		}
-		defer resp.Body.Close()
-		if resp.StatusCode != http.StatusOK {
-			a.logger.Fatalf("Failed to get alist user info: %s", resp.Status)
-			return err
-		}
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			a.logger.Fatalf("Failed to read response body: %v", err)
-			return err
-		}
-		var meResp meResponse
-		if err := json.Unmarshal(body, &meResp); err != nil {
-			a.logger.Fatalf("Failed to unmarshal me response: %v", err)
-			return err
-		}
-		if meResp.Code != http.StatusOK {
-			a.logger.Fatalf("Failed to get alist user info: %s", meResp.Message)
-			return err
-		}
-		a.logger.Debugf("Logged in Alist as %s", meResp.Data.Username)
+		a.logger.Debugf("Logged in Alist as %s", username)
 		return nil
 	}
 	a.loginInfo = &loginRequest{
@@ -84,7 +57,6 @@ func (a *Alist) Init(ctx context.Context, cfg config.StorageConfig) error {
 	}
 
 	if err := a.getToken(ctx); err != nil {
-		a.logger.Fatalf("Failed to login to Alist: %v", err)
 		return err
 	}
 	a.logger.Debug("Logged in to Alist")
@@ -93,6 +65,42 @@ func (a *Alist) Init(ctx context.Context, cfg config.StorageConfig) error {
 	return nil
 }
 
+// me returns the name of the user the token belongs to
+func (a *Alist) me(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.baseURL+"/api/me", nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", a.token)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get alist user info: %s", resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response body: %w", err)
+	}
+	var meResp meResponse
+	if err := json.Unmarshal(body, &meResp); err != nil {
+		return "", fmt.Errorf("failed to unmarshal me response: %w", err)
+	}
+	if meResp.Code != http.StatusOK {
+		return "", fmt.Errorf("failed to get alist user info: %s", meResp.Message)
+	}
+	return meResp.Data.Username, nil
+}
+
+// Probe checks that the token is accepted by /api/me
+func (a *Alist) Probe(ctx context.Context) error {
+	_, err := a.me(ctx)
+	return err
+}
+
 func (a *Alist) Type() storenum.StorageType {
 	return storenum.Alist
 }
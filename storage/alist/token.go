@@ -10,6 +10,7 @@ import (
 	"time"
 
 	config "github.com/krau/SaveAny-Bot/config/storage"
+	"github.com/krau/SaveAny-Bot/config/types"
 )
 
 func (a *Alist) getToken(ctx context.Context) error {
@@ -18,7 +19,7 @@ func (a *Alist) getToken(ctx context.Context) error {
 		return fmt.Errorf("failed to marshal login request: %w", err)
 	}
 
-	req, err := http.NewRequest(http.MethodPost, a.baseURL+"/api/auth/login", bytes.NewBuffer(loginBody))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.baseURL+"/api/auth/login", bytes.NewBuffer(loginBody))
 	if err != nil {
 		return fmt.Errorf("failed to create login request: %w", err)
 	}
@@ -52,10 +53,10 @@ func (a *Alist) refreshToken(cfg config.AlistStorageConfig) {
 	tokenExp := cfg.TokenExp
 	if tokenExp <= 0 {
 		a.logger.Warn("Invalid token expiration time, using default value")
-		tokenExp = 3600
+		tokenExp = types.Duration(time.Hour)
 	}
 	for {
-		time.Sleep(time.Duration(tokenExp) * time.Second)
+		time.Sleep(tokenExp.Duration())
 		if err := a.getToken(context.Background()); err != nil {
 			a.logger.Errorf("Failed to refresh jwt token: %v", err)
 			continue
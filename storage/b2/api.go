@@ -0,0 +1,243 @@
+package b2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/krau/SaveAny-Bot/pkg/storerr"
+)
+
+const (
+	authorizeURL = "https://api.backblazeb2.com/b2api/v2/b2_authorize_account"
+
+	maxRetries = 6
+)
+
+type apiError struct {
+	Status     int    `json:"status"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	RetryAfter time.Duration
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("b2: %d %s: %s", e.Status, e.Code, e.Message)
+}
+
+// Unwrap maps the error to the standard storage errors
+func (e *apiError) Unwrap() error {
+	switch e.Code {
+	case "cap_exceeded", "storage_cap_exceeded":
+		return storerr.ErrNoSpace
+	case "unauthorized", "access_denied":
+		return storerr.ErrPermission
+	}
+	return nil
+}
+
+// expiredAuth reports whether the account has to be authorized again
+func (e *apiError) expiredAuth() bool {
+	return e.Status == http.StatusUnauthorized && (e.Code == "expired_auth_token" || e.Code == "bad_auth_token")
+}
+
+// retryable reports whether the request should be sent again, B2 expects clients to retry
+// on 408, 429 and server errors, and to authorize again when the token expired
+func (e *apiError) retryable() bool {
+	return e.Status == http.StatusRequestTimeout || e.Status == http.StatusTooManyRequests || e.Status >= 500 || e.expiredAuth()
+}
+
+func parseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	apiErr := &apiError{}
+	if json.Unmarshal(body, apiErr) != nil || apiErr.Code == "" {
+		apiErr.Message = resp.Status
+	}
+	apiErr.Status = resp.StatusCode
+	if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && s > 0 {
+		apiErr.RetryAfter = time.Duration(s) * time.Second
+	}
+	return apiErr
+}
+
+func asAPIError(err error) (*apiError, bool) {
+	var apiErr *apiError
+	ok := errors.As(err, &apiErr)
+	return apiErr, ok
+}
+
+func retryDelay(err error, attempt int) time.Duration {
+	if apiErr, ok := asAPIError(err); ok {
+		if apiErr.RetryAfter > 0 {
+			return apiErr.RetryAfter
+		}
+		if apiErr.expiredAuth() {
+			return 0
+		}
+	}
+	d := time.Duration(1<<attempt) * time.Second
+	if d > 32*time.Second {
+		d = 32 * time.Second
+	}
+	return d + time.Duration(rand.Int64N(int64(time.Second)))
+}
+
+// errChecksum is returned when B2 reports another SHA1 for the uploaded data than the one sent
+var errChecksum = errors.New("sha1 of the uploaded data does not match")
+
+// shouldRetry reports whether a request that failed with err can be sent again:
+// on network errors, corrupted uploads and the api errors B2 wants retried
+func shouldRetry(ctx context.Context, err error, attempt int) bool {
+	if ctx.Err() != nil || attempt >= maxRetries {
+		return false
+	}
+	if apiErr, ok := asAPIError(err); ok {
+		return apiErr.retryable()
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, errChecksum)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+// send sends req and decodes the JSON response into out if it is not nil
+func (b *B2) send(req *http.Request, out any) error {
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return parseError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+type authorization struct {
+	AccountID          string `json:"accountId"`
+	AuthorizationToken string `json:"authorizationToken"`
+	APIURL             string `json:"apiUrl"`
+	Allowed            struct {
+		BucketID   string `json:"bucketId"`
+		BucketName string `json:"bucketName"`
+	} `json:"allowed"`
+
+	bucketID string
+}
+
+func (b *B2) authorize(ctx context.Context) (*authorization, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, authorizeURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(b.config.KeyID, b.config.ApplicationKey)
+	var auth authorization
+	if err := b.send(req, &auth); err != nil {
+		return nil, fmt.Errorf("failed to authorize account: %w", err)
+	}
+	if auth.Allowed.BucketID != "" {
+		// the key is restricted to a bucket
+		if auth.Allowed.BucketName != b.config.Bucket {
+			return nil, fmt.Errorf("key is restricted to bucket %s, not %s", auth.Allowed.BucketName, b.config.Bucket)
+		}
+		auth.bucketID = auth.Allowed.BucketID
+		return &auth, nil
+	}
+	var buckets struct {
+		Buckets []struct {
+			BucketID string `json:"bucketId"`
+		} `json:"buckets"`
+	}
+	body, _ := json.Marshal(map[string]any{"accountId": auth.AccountID, "bucketName": b.config.Bucket})
+	req, err = http.NewRequestWithContext(ctx, http.MethodPost, auth.APIURL+"/b2api/v2/b2_list_buckets", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", auth.AuthorizationToken)
+	if err := b.send(req, &buckets); err != nil {
+		return nil, fmt.Errorf("failed to get bucket: %w", err)
+	}
+	if len(buckets.Buckets) == 0 {
+		return nil, fmt.Errorf("bucket %s not found", b.config.Bucket)
+	}
+	auth.bucketID = buckets.Buckets[0].BucketID
+	return &auth, nil
+}
+
+// getAuth returns the authorization of the account, authorizing first if needed
+func (b *B2) getAuth(ctx context.Context) (*authorization, error) {
+	b.authMu.Lock()
+	defer b.authMu.Unlock()
+	if b.auth != nil {
+		return b.auth, nil
+	}
+	auth, err := b.authorize(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b.auth = auth
+	return auth, nil
+}
+
+// resetAuth drops the authorization after its token expired
+func (b *B2) resetAuth(auth *authorization) {
+	b.authMu.Lock()
+	defer b.authMu.Unlock()
+	if b.auth == auth {
+		b.auth = nil
+	}
+}
+
+// call calls an api endpoint such as b2_start_large_file with args as the JSON body
+func (b *B2) call(ctx context.Context, endpoint string, args, out any) error {
+	body, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	for attempt := 0; ; attempt++ {
+		auth, err := b.getAuth(ctx)
+		if err == nil {
+			var req *http.Request
+			req, err = http.NewRequestWithContext(ctx, http.MethodPost, auth.APIURL+"/b2api/v2/"+endpoint, bytes.NewReader(body))
+			if err != nil {
+				return err
+			}
+			req.Header.Set("Authorization", auth.AuthorizationToken)
+			if err = b.send(req, out); err == nil {
+				return nil
+			}
+			if apiErr, ok := asAPIError(err); ok && apiErr.expiredAuth() {
+				b.resetAuth(auth)
+			}
+		}
+		if !shouldRetry(ctx, err, attempt) {
+			return fmt.Errorf("%s: %w", endpoint, err)
+		}
+		delay := retryDelay(err, attempt)
+		b.logger.Warnf("%s failed, retrying in %s: %s", endpoint, delay.Round(time.Millisecond), err)
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package b2
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/log"
+	config "github.com/krau/SaveAny-Bot/config/storage"
+	storenum "github.com/krau/SaveAny-Bot/pkg/enums/storage"
+	"github.com/krau/SaveAny-Bot/pkg/uploadinfo"
+)
+
+const (
+	defaultPartSize    = 32 * 1024 * 1024
+	defaultConcurrency = 4
+)
+
+type B2 struct {
+	config config.B2StorageConfig
+	client *http.Client
+	logger *log.Logger
+
+	authMu sync.Mutex
+	auth   *authorization
+	// upload urls of b2_upload_file, each can be used by one upload at a time
+	uploadURLs *urlPool
+}
+
+func (b *B2) Init(ctx context.Context, cfg config.StorageConfig) error {
+	b2Config, ok := cfg.(*config.B2StorageConfig)
+	if !ok {
+		return fmt.Errorf("failed to cast b2 config")
+	}
+	if err := b2Config.Validate(); err != nil {
+		return err
+	}
+	b.config = *b2Config
+	if b.config.PartSize == 0 {
+		b.config.PartSize = defaultPartSize
+	}
+	if b.config.Concurrency == 0 {
+		b.config.Concurrency = defaultConcurrency
+	}
+	b.logger = log.FromContext(ctx).WithPrefix(fmt.Sprintf("b2[%s]", b.config.Name))
+	b.client = &http.Client{}
+	b.uploadURLs = newURLPool(b.config.Concurrency, func(ctx context.Context) (*uploadURL, error) {
+		auth, err := b.getAuth(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var u uploadURL
+		err = b.call(ctx, "b2_get_upload_url", map[string]any{"bucketId": auth.bucketID}, &u)
+		return &u, err
+	})
+	return nil
+}
+
+func (b *B2) Type() storenum.StorageType {
+	return storenum.B2
+}
+
+func (b *B2) Name() string {
+	return b.config.Name
+}
+
+// JoinStoragePath returns the name of the file in the bucket, which has no leading slash
+func (b *B2) JoinStoragePath(p string) string {
+	return strings.TrimLeft(path.Join(b.config.BasePath, p), "/")
+}
+
+// Save uploads files up to part_size with b2_upload_file and larger ones with the large file api
+func (b *B2) Save(ctx context.Context, r io.Reader, storagePath string) error {
+	b.logger.Infof("Saving file to %s", storagePath)
+
+	ext := path.Ext(storagePath)
+	base := strings.TrimSuffix(storagePath, ext)
+	candidate := storagePath
+	for i := 1; b.Exists(ctx, candidate); i++ {
+		candidate = fmt.Sprintf("%s_%d%s", base, i, ext)
+	}
+
+	br := bufio.NewReader(r)
+	buf := make([]byte, b.config.PartSize.Bytes())
+	n, last, err := readPart(br, buf)
+	if err != nil {
+		return err
+	}
+	var fileID string
+	if last {
+		fileID, err = b.uploadFile(ctx, candidate, buf[:n])
+	} else {
+		fileID, err = b.uploadLarge(ctx, br, buf, n, candidate)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+	b.logger.Debugf("Uploaded %s as %s", candidate, fileID)
+	uploadinfo.Record(ctx, uploadinfo.File{Storage: b.config.Name, Path: candidate, ID: fileID})
+	return nil
+}
+
+// readPart fills buf and reports whether the end of r was reached
+func readPart(r *bufio.Reader, buf []byte) (int, bool, error) {
+	n, err := io.ReadFull(r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return n, true, nil
+	}
+	if err != nil {
+		return n, false, fmt.Errorf("failed to read file: %w", err)
+	}
+	if _, err := r.Peek(1); err == io.EOF {
+		return n, true, nil
+	} else if err != nil {
+		return n, false, fmt.Errorf("failed to read file: %w", err)
+	}
+	return n, false, nil
+}
+
+func (b *B2) Exists(ctx context.Context, storagePath string) bool {
+	auth, err := b.getAuth(ctx)
+	if err != nil {
+		b.logger.Errorf("Failed to check if file exists at %s: %v", storagePath, err)
+		return false
+	}
+	var list struct {
+		Files []struct {
+			FileName string `json:"fileName"`
+		} `json:"files"`
+	}
+	if err := b.call(ctx, "b2_list_file_names", map[string]any{
+		"bucketId":      auth.bucketID,
+		"startFileName": storagePath,
+		"prefix":        storagePath,
+		"maxFileCount":  1,
+	}, &list); err != nil {
+		b.logger.Errorf("Failed to check if file exists at %s: %v", storagePath, err)
+		return false
+	}
+	return len(list.Files) > 0 && list.Files[0].FileName == storagePath
+}
+
+// Probe authorizes the account and looks up the bucket
+func (b *B2) Probe(ctx context.Context) error {
+	_, err := b.getAuth(ctx)
+	return err
+}
@@ -0,0 +1,201 @@
+package b2
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+const maxParts = 10000
+
+type uploadURL struct {
+	UploadURL          string `json:"uploadUrl"`
+	AuthorizationToken string `json:"authorizationToken"`
+}
+
+// urlPool keeps the upload urls that are not in use, one is fetched when none is left
+type urlPool struct {
+	urls  chan *uploadURL
+	fetch func(ctx context.Context) (*uploadURL, error)
+}
+
+func newURLPool(size int, fetch func(ctx context.Context) (*uploadURL, error)) *urlPool {
+	return &urlPool{urls: make(chan *uploadURL, size), fetch: fetch}
+}
+
+func (p *urlPool) get(ctx context.Context) (*uploadURL, error) {
+	select {
+	case u := <-p.urls:
+		return u, nil
+	default:
+		return p.fetch(ctx)
+	}
+}
+
+func (p *urlPool) put(u *uploadURL) {
+	select {
+	case p.urls <- u:
+	default:
+	}
+}
+
+type uploadResult struct {
+	FileID      string `json:"fileId"`
+	ContentSha1 string `json:"contentSha1"`
+}
+
+// encodeName percent-encodes a file name for the X-Bz-File-Name header, B2 would decode "+" as a space
+func encodeName(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(url.QueryEscape(name), "+", "%20"), "%2F", "/")
+}
+
+// post uploads data to a url of the pool and checks the SHA1 B2 computed against the one of data.
+// A url that failed is dropped and the upload continues on a new one, as B2 asks
+func (b *B2) post(ctx context.Context, pool *urlPool, header http.Header, data []byte) (*uploadResult, error) {
+	sum := sha1.Sum(data)
+	sha := hex.EncodeToString(sum[:])
+	for attempt := 0; ; attempt++ {
+		u, err := pool.get(ctx)
+		if err == nil {
+			var req *http.Request
+			req, err = http.NewRequestWithContext(ctx, http.MethodPost, u.UploadURL, bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			req.Header = header.Clone()
+			req.Header.Set("Authorization", u.AuthorizationToken)
+			req.Header.Set("X-Bz-Content-Sha1", sha)
+			var result uploadResult
+			if err = b.send(req, &result); err == nil {
+				if result.ContentSha1 == sha {
+					pool.put(u)
+					return &result, nil
+				}
+				err = fmt.Errorf("%w: sent %s, got %s", errChecksum, sha, result.ContentSha1)
+			}
+		}
+		if !shouldRetry(ctx, err, attempt) {
+			return nil, err
+		}
+		delay := retryDelay(err, attempt)
+		b.logger.Warnf("Upload failed, retrying in %s: %s", delay.Round(time.Millisecond), err)
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (b *B2) uploadFile(ctx context.Context, name string, data []byte) (string, error) {
+	header := http.Header{}
+	header.Set("X-Bz-File-Name", encodeName(name))
+	header.Set("Content-Type", "b2/x-auto")
+	result, err := b.post(ctx, b.uploadURLs, header, data)
+	if err != nil {
+		return "", err
+	}
+	return result.FileID, nil
+}
+
+func (b *B2) uploadLarge(ctx context.Context, r *bufio.Reader, buf []byte, n int, name string) (string, error) {
+	auth, err := b.getAuth(ctx)
+	if err != nil {
+		return "", err
+	}
+	var file struct {
+		FileID string `json:"fileId"`
+	}
+	if err := b.call(ctx, "b2_start_large_file", map[string]any{
+		"bucketId":    auth.bucketID,
+		"fileName":    name,
+		"contentType": "b2/x-auto",
+	}, &file); err != nil {
+		return "", err
+	}
+	sums, err := b.uploadParts(ctx, r, buf, n, file.FileID)
+	if err == nil {
+		err = b.call(ctx, "b2_finish_large_file", map[string]any{"fileId": file.FileID, "partSha1Array": sums}, nil)
+	}
+	if err != nil {
+		// the uploaded parts would be kept and billed otherwise
+		cancelCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		defer cancel()
+		if cancelErr := b.call(cancelCtx, "b2_cancel_large_file", map[string]any{"fileId": file.FileID}, nil); cancelErr != nil {
+			b.logger.Warnf("Failed to cancel large file %s: %s", file.FileID, cancelErr)
+		}
+		return "", err
+	}
+	return file.FileID, nil
+}
+
+// uploadParts uploads the first n bytes in buf as the first part and the rest of r as the following ones,
+// up to concurrency parts at the same time, and returns the SHA1 of every part
+func (b *B2) uploadParts(ctx context.Context, r *bufio.Reader, buf []byte, n int, fileID string) ([]string, error) {
+	pool := newURLPool(b.config.Concurrency, func(ctx context.Context) (*uploadURL, error) {
+		var u uploadURL
+		err := b.call(ctx, "b2_get_upload_part_url", map[string]any{"fileId": fileID}, &u)
+		return &u, err
+	})
+	g, gctx := errgroup.WithContext(ctx)
+	// free holds the buffers of the finished parts, nil until another buffer is allocated
+	free := make(chan []byte, b.config.Concurrency)
+	for range b.config.Concurrency - 1 {
+		free <- nil
+	}
+	var mu sync.Mutex
+	var sums []string
+	last := false
+	for part := 1; ; part++ {
+		if part > maxParts {
+			g.Wait()
+			return nil, fmt.Errorf("file needs more than %d parts, increase part_size", maxParts)
+		}
+		data := buf[:n]
+		mu.Lock()
+		sums = append(sums, "")
+		mu.Unlock()
+		g.Go(func() error {
+			defer func() { free <- data[:cap(data)] }()
+			header := http.Header{}
+			header.Set("X-Bz-Part-Number", strconv.Itoa(part))
+			result, err := b.post(gctx, pool, header, data)
+			if err != nil {
+				return fmt.Errorf("failed to upload part %d: %w", part, err)
+			}
+			mu.Lock()
+			sums[part-1] = result.ContentSha1
+			mu.Unlock()
+			return nil
+		})
+		if last {
+			break
+		}
+		select {
+		case buf = <-free:
+		case <-gctx.Done():
+			return nil, g.Wait()
+		}
+		if buf == nil {
+			buf = make([]byte, b.config.PartSize.Bytes())
+		}
+		var err error
+		if n, last, err = readPart(r, buf); err != nil {
+			g.Wait()
+			return nil, err
+		}
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return sums, nil
+}
@@ -0,0 +1,234 @@
+package dropbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/krau/SaveAny-Bot/pkg/storerr"
+)
+
+const (
+	apiURL     = "https://api.dropboxapi.com/2"
+	contentURL = "https://content.dropboxapi.com/2"
+	tokenURL   = "https://api.dropboxapi.com/oauth2/token"
+
+	maxRetries = 6
+)
+
+type fileMetadata struct {
+	ID          string `json:"id"`
+	PathDisplay string `json:"path_display"`
+}
+
+type apiError struct {
+	Status int
+	// Summary is the error_summary of Dropbox, e.g. "path/conflict/file/.."
+	Summary       string
+	CorrectOffset int64
+	RetryAfter    time.Duration
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("dropbox: %d %s", e.Status, e.Summary)
+}
+
+// has reports whether the summary contains one of the tags
+func (e *apiError) has(tags ...string) bool {
+	for _, tag := range strings.Split(e.Summary, "/") {
+		if slices.Contains(tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Unwrap maps the error to the standard storage errors
+func (e *apiError) Unwrap() error {
+	switch {
+	case e.Status == http.StatusRequestEntityTooLarge || e.has("too_large", "payload_too_large"):
+		return storerr.ErrFileTooLarge
+	case e.Status == http.StatusInsufficientStorage || e.has("insufficient_space", "insufficient_quota"):
+		return storerr.ErrNoSpace
+	case e.has("no_write_permission", "team_folder", "restricted_content"):
+		return storerr.ErrPermission
+	case e.has("disallowed_name", "malformed_path"):
+		return storerr.ErrInvalidPath
+	case e.has("conflict"):
+		return storerr.ErrAlreadyExists
+	}
+	return nil
+}
+
+func (e *apiError) notFound() bool {
+	return e.has("not_found")
+}
+
+func (e *apiError) incorrectOffset() bool {
+	return e.has("incorrect_offset")
+}
+
+// retryable reports whether the request should be sent again, after rate limits and server errors
+func (e *apiError) retryable() bool {
+	return e.Status == http.StatusTooManyRequests || e.Status >= 500 && e.Status != http.StatusInsufficientStorage
+}
+
+func parseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	apiErr := &apiError{Status: resp.StatusCode, Summary: strings.TrimSpace(string(body))}
+	var errResp struct {
+		Summary string `json:"error_summary"`
+		Error   struct {
+			CorrectOffset *int64 `json:"correct_offset"`
+			LookupFailed  struct {
+				CorrectOffset *int64 `json:"correct_offset"`
+			} `json:"lookup_failed"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &errResp) == nil && errResp.Summary != "" {
+		apiErr.Summary = errResp.Summary
+		if o := errResp.Error.CorrectOffset; o != nil {
+			apiErr.CorrectOffset = *o
+		} else if o := errResp.Error.LookupFailed.CorrectOffset; o != nil {
+			apiErr.CorrectOffset = *o
+		}
+	}
+	if apiErr.Summary == "" {
+		apiErr.Summary = resp.Status
+	}
+	if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && s > 0 {
+		apiErr.RetryAfter = time.Duration(s) * time.Second
+	}
+	return apiErr
+}
+
+func asAPIError(err error) (*apiError, bool) {
+	var apiErr *apiError
+	ok := errors.As(err, &apiErr)
+	return apiErr, ok
+}
+
+// apiArg encodes the Dropbox-API-Arg header, which has to be ASCII
+func apiArg(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, r := range string(data) {
+		if r < 0x80 {
+			b.WriteRune(r)
+			continue
+		}
+		for _, u := range utf16.Encode([]rune{r}) {
+			fmt.Fprintf(&b, `\u%04x`, u)
+		}
+	}
+	return b.String(), nil
+}
+
+func retryDelay(err error, attempt int) time.Duration {
+	if apiErr, ok := asAPIError(err); ok && apiErr.RetryAfter > 0 {
+		return apiErr.RetryAfter
+	}
+	d := time.Duration(1<<attempt) * time.Second
+	if d > 32*time.Second {
+		d = 32 * time.Second
+	}
+	return d + time.Duration(rand.Int64N(int64(time.Second)))
+}
+
+func shouldRetry(ctx context.Context, err error, attempt int) bool {
+	if ctx.Err() != nil || attempt >= maxRetries {
+		return false
+	}
+	apiErr, ok := asAPIError(err)
+	return !ok || apiErr.retryable()
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+// rpc calls an endpoint of the RPC api with args as the JSON body, decoding the result into out if it is not nil
+func (d *Dropbox) rpc(ctx context.Context, endpoint string, args, out any) error {
+	body := []byte("null")
+	if args != nil {
+		var err error
+		if body, err = json.Marshal(args); err != nil {
+			return err
+		}
+	}
+	return d.do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodPost, apiURL+endpoint, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	}, out)
+}
+
+// content calls an endpoint of the content api with data as the body
+func (d *Dropbox) content(ctx context.Context, endpoint string, args any, data []byte, out any) error {
+	arg, err := apiArg(args)
+	if err != nil {
+		return err
+	}
+	return d.do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodPost, contentURL+endpoint, bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/octet-stream")
+		req.Header.Set("Dropbox-API-Arg", arg)
+		return req, nil
+	}, out)
+}
+
+// do sends the request built by newReq, retrying on rate limits and server errors
+func (d *Dropbox) do(ctx context.Context, newReq func() (*http.Request, error), out any) error {
+	for attempt := 0; ; attempt++ {
+		req, err := newReq()
+		if err != nil {
+			return err
+		}
+		resp, err := d.client.Do(req.WithContext(ctx))
+		if err == nil {
+			if resp.StatusCode < 400 {
+				defer resp.Body.Close()
+				if out != nil {
+					if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+						return fmt.Errorf("failed to decode response: %w", err)
+					}
+				}
+				return nil
+			}
+			err = parseError(resp)
+			resp.Body.Close()
+		}
+		if !shouldRetry(ctx, err, attempt) {
+			return err
+		}
+		delay := retryDelay(err, attempt)
+		d.logger.Warnf("Request failed, retrying in %s: %s", delay.Round(time.Millisecond), err)
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
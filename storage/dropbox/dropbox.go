@@ -0,0 +1,196 @@
+package dropbox
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	config "github.com/krau/SaveAny-Bot/config/storage"
+	storenum "github.com/krau/SaveAny-Bot/pkg/enums/storage"
+	"github.com/krau/SaveAny-Bot/pkg/uploadinfo"
+	"golang.org/x/oauth2"
+)
+
+const defaultChunkSize = 8 * 1024 * 1024
+
+type Dropbox struct {
+	config config.DropboxStorageConfig
+	client *http.Client
+	logger *log.Logger
+}
+
+func (d *Dropbox) Init(ctx context.Context, cfg config.StorageConfig) error {
+	dropboxConfig, ok := cfg.(*config.DropboxStorageConfig)
+	if !ok {
+		return fmt.Errorf("failed to cast dropbox config")
+	}
+	if err := dropboxConfig.Validate(); err != nil {
+		return err
+	}
+	d.config = *dropboxConfig
+	if d.config.ChunkSize == 0 {
+		d.config.ChunkSize = defaultChunkSize
+	}
+	d.logger = log.FromContext(ctx).WithPrefix(fmt.Sprintf("dropbox[%s]", d.config.Name))
+
+	var ts oauth2.TokenSource
+	if d.config.RefreshToken == "" {
+		ts = oauth2.StaticTokenSource(&oauth2.Token{AccessToken: d.config.AccessToken})
+	} else {
+		// access_token is not used, it would never be refreshed as its expiry is unknown
+		conf := &oauth2.Config{
+			ClientID:     d.config.AppKey,
+			ClientSecret: d.config.AppSecret,
+			Endpoint:     oauth2.Endpoint{TokenURL: tokenURL, AuthStyle: oauth2.AuthStyleInParams},
+		}
+		ts = conf.TokenSource(context.Background(), &oauth2.Token{RefreshToken: d.config.RefreshToken})
+	}
+	d.client = oauth2.NewClient(context.Background(), ts)
+	return nil
+}
+
+func (d *Dropbox) Type() storenum.StorageType {
+	return storenum.Dropbox
+}
+
+func (d *Dropbox) Name() string {
+	return d.config.Name
+}
+
+// JoinStoragePath returns the Dropbox path, which is absolute and has no trailing slash
+func (d *Dropbox) JoinStoragePath(p string) string {
+	return path.Join("/", d.config.BasePath, p)
+}
+
+func commitInfo(storagePath string) map[string]any {
+	return map[string]any{
+		"path":       storagePath,
+		"mode":       "add",
+		"autorename": false,
+		"mute":       true,
+	}
+}
+
+// Save uploads files up to chunk_size in a single request and larger ones in an upload session.
+// Dropbox creates the missing parent folders
+func (d *Dropbox) Save(ctx context.Context, r io.Reader, storagePath string) error {
+	d.logger.Infof("Saving file to %s", storagePath)
+
+	ext := path.Ext(storagePath)
+	base := strings.TrimSuffix(storagePath, ext)
+	candidate := storagePath
+	for i := 1; d.Exists(ctx, candidate); i++ {
+		candidate = fmt.Sprintf("%s_%d%s", base, i, ext)
+	}
+
+	br := bufio.NewReader(r)
+	buf := make([]byte, d.config.ChunkSize.Bytes())
+	n, last, err := readChunk(br, buf)
+	if err != nil {
+		return err
+	}
+	var meta fileMetadata
+	if last {
+		err = d.content(ctx, "/files/upload", commitInfo(candidate), buf[:n], &meta)
+	} else {
+		err = d.uploadSession(ctx, br, buf, n, candidate, &meta)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+	d.logger.Debugf("Uploaded %s as %s", candidate, meta.ID)
+	uploadinfo.Record(ctx, uploadinfo.File{Storage: d.config.Name, Path: candidate, ID: meta.ID})
+	return nil
+}
+
+// readChunk fills buf and reports whether the end of r was reached
+func readChunk(r *bufio.Reader, buf []byte) (int, bool, error) {
+	n, err := io.ReadFull(r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return n, true, nil
+	}
+	if err != nil {
+		return n, false, fmt.Errorf("failed to read file: %w", err)
+	}
+	if _, err := r.Peek(1); err == io.EOF {
+		return n, true, nil
+	} else if err != nil {
+		return n, false, fmt.Errorf("failed to read file: %w", err)
+	}
+	return n, false, nil
+}
+
+// uploadSession uploads the first n bytes in buf and the rest of r in chunks, the last one commits the file
+func (d *Dropbox) uploadSession(ctx context.Context, r *bufio.Reader, buf []byte, n int, storagePath string, meta *fileMetadata) error {
+	var session struct {
+		SessionID string `json:"session_id"`
+	}
+	if err := d.content(ctx, "/files/upload_session/start", map[string]any{"close": false}, buf[:n], &session); err != nil {
+		return fmt.Errorf("failed to start upload session: %w", err)
+	}
+	offset := int64(n)
+	for {
+		n, last, err := readChunk(r, buf)
+		if err != nil {
+			return err
+		}
+		if last {
+			return d.send(ctx, "/files/upload_session/finish", func(offset int64) any {
+				return map[string]any{
+					"cursor": map[string]any{"session_id": session.SessionID, "offset": offset},
+					"commit": commitInfo(storagePath),
+				}
+			}, buf[:n], offset, meta)
+		}
+		if err := d.send(ctx, "/files/upload_session/append_v2", func(offset int64) any {
+			return map[string]any{
+				"cursor": map[string]any{"session_id": session.SessionID, "offset": offset},
+				"close":  false,
+			}
+		}, buf[:n], offset, nil); err != nil {
+			return err
+		}
+		offset += int64(n)
+	}
+}
+
+// send sends chunk at offset. If a retried request is answered with incorrect_offset
+// because Dropbox got the earlier attempt, only the rest of the chunk is sent again
+func (d *Dropbox) send(ctx context.Context, endpoint string, args func(offset int64) any, chunk []byte, offset int64, out any) error {
+	at := offset
+	for {
+		err := d.content(ctx, endpoint, args(at), chunk[at-offset:], out)
+		apiErr, ok := asAPIError(err)
+		if !ok || !apiErr.incorrectOffset() {
+			return err
+		}
+		correct := apiErr.CorrectOffset
+		if correct == at || correct < offset || correct > offset+int64(len(chunk)) {
+			return err
+		}
+		d.logger.Warnf("Upload session is at %d bytes, continuing from there", correct)
+		at = correct
+	}
+}
+
+func (d *Dropbox) Exists(ctx context.Context, storagePath string) bool {
+	err := d.rpc(ctx, "/files/get_metadata", map[string]any{"path": storagePath}, nil)
+	if apiErr, ok := asAPIError(err); ok && apiErr.notFound() {
+		return false
+	}
+	if err != nil {
+		d.logger.Errorf("Failed to check if file exists at %s: %v", storagePath, err)
+		return false
+	}
+	return true
+}
+
+// Probe gets the current account, which checks the token
+func (d *Dropbox) Probe(ctx context.Context) error {
+	return d.rpc(ctx, "/users/get_current_account", nil, nil)
+}
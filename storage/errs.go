@@ -2,8 +2,11 @@ package storage
 
 import (
 	"errors"
+
+	"github.com/krau/SaveAny-Bot/pkg/storerr"
 )
 
 var (
 	ErrStorageNameEmpty = errors.New("storage name is empty")
+	ErrFileTooLarge     = storerr.ErrFileTooLarge
 )
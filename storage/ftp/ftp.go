@@ -0,0 +1,355 @@
+package ftp
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/log"
+	"github.com/jlaffaye/ftp"
+	"github.com/krau/SaveAny-Bot/common/utils/strutil"
+	config "github.com/krau/SaveAny-Bot/config/storage"
+	"github.com/krau/SaveAny-Bot/pkg/enums/ctxkey"
+	storenum "github.com/krau/SaveAny-Bot/pkg/enums/storage"
+)
+
+// partSuffix is appended to the name of a file while it is uploaded, after the id of the task.
+// A retry of the same task resumes from its size
+const partSuffix = ".part"
+
+type FTP struct {
+	config config.FTPStorageConfig
+	pool   *pool
+	logger *log.Logger
+
+	homeMu sync.Mutex
+	home   string // working directory after login, relative paths are resolved against it
+
+	uploadingMu sync.Mutex
+	uploading   map[string]struct{} // paths being uploaded, so concurrent uploads don't share a .part file
+}
+
+func (f *FTP) Init(ctx context.Context, cfg config.StorageConfig) error {
+	ftpConfig, ok := cfg.(*config.FTPStorageConfig)
+	if !ok {
+		return fmt.Errorf("failed to cast ftp config")
+	}
+	if err := ftpConfig.Validate(); err != nil {
+		return err
+	}
+	f.config = *ftpConfig
+	if f.config.Port == 0 {
+		f.config.Port = 21
+	}
+	if f.config.Username == "" {
+		f.config.Username = "anonymous"
+		f.config.Password = "anonymous"
+	}
+	if f.config.MaxConnections == 0 {
+		f.config.MaxConnections = 2
+	}
+	f.logger = log.FromContext(ctx).WithPrefix(fmt.Sprintf("ftp[%s]", f.config.Name))
+	f.pool = newPool(f.config.MaxConnections, f.dial)
+	f.uploading = make(map[string]struct{})
+	return nil
+}
+
+func (f *FTP) dial(ctx context.Context) (*ftp.ServerConn, error) {
+	opts := []ftp.DialOption{
+		ftp.DialWithContext(ctx),
+		ftp.DialWithTimeout(time.Minute),
+		ftp.DialWithDisabledEPSV(f.config.DisableEPSV),
+	}
+	if f.config.ExplicitTLS {
+		opts = append(opts, ftp.DialWithExplicitTLS(&tls.Config{
+			ServerName:         f.config.Host,
+			InsecureSkipVerify: f.config.SkipTLSVerify,
+		}))
+	}
+	conn, err := ftp.Dial(net.JoinHostPort(f.config.Host, strconv.Itoa(f.config.Port)), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ftp server: %w", err)
+	}
+	if err := conn.Login(f.config.Username, f.config.Password); err != nil {
+		conn.Quit()
+		return nil, fmt.Errorf("failed to login to ftp server: %w", err)
+	}
+	f.homeMu.Lock()
+	defer f.homeMu.Unlock()
+	if f.home == "" {
+		home, err := conn.CurrentDir()
+		if err != nil || !path.IsAbs(home) {
+			home = "/"
+		}
+		f.home = home
+	}
+	return conn, nil
+}
+
+// abs resolves p against the login directory, as changing directories moves the working directory
+// of a pooled connection
+func (f *FTP) abs(p string) string {
+	if path.IsAbs(p) {
+		return path.Clean(p)
+	}
+	f.homeMu.Lock()
+	defer f.homeMu.Unlock()
+	return path.Join(f.home, p)
+}
+
+func (f *FTP) Type() storenum.StorageType {
+	return storenum.Ftp
+}
+
+func (f *FTP) Name() string {
+	return f.config.Name
+}
+
+func (f *FTP) JoinStoragePath(p string) string {
+	return path.Join(f.config.BasePath, p)
+}
+
+func (f *FTP) Save(ctx context.Context, r io.Reader, storagePath string) (err error) {
+	f.logger.Infof("Saving file to %s", storagePath)
+	conn, err := f.pool.get(ctx)
+	if err != nil {
+		return err
+	}
+	// closing the connection is the only way to abort a transfer
+	stop := context.AfterFunc(ctx, func() { conn.Quit() })
+	defer func() {
+		if !stop() {
+			f.pool.put(conn, ctx.Err())
+			return
+		}
+		f.pool.put(conn, err)
+	}()
+
+	length := int64(-1)
+	if l, ok := ctx.Value(ctxkey.ContentLength).(int64); ok {
+		length = l
+	}
+	taskID, _ := ctx.Value(ctxkey.TaskID).(string)
+	target := f.abs(storagePath)
+	if f.config.ASCIINames {
+		target = asciiPath(target)
+	}
+	err = f.upload(conn, r, target, length, taskID)
+	if err != nil && !f.config.ASCIINames && nameRejected(err) && asciiPath(target) != target && rewind(r, err) {
+		f.logger.Warnf("Server rejected %s, retrying with an ASCII name: %s", target, err)
+		target = asciiPath(target)
+		err = f.upload(conn, r, target, length, taskID)
+	}
+	return err
+}
+
+// unreadError is returned by upload if it failed before r was read or moved
+type unreadError struct {
+	err error
+}
+
+func (e *unreadError) Error() string { return e.err.Error() }
+func (e *unreadError) Unwrap() error { return e.err }
+
+// rewind reports whether r can be stored again after upload failed with err,
+// seeking it back to the start if some of it was read already
+func rewind(r io.Reader, err error) bool {
+	var unread *unreadError
+	if errors.As(err, &unread) {
+		return true
+	}
+	rs, ok := r.(io.Seeker)
+	if !ok {
+		return false
+	}
+	_, err = rs.Seek(0, io.SeekStart)
+	return err == nil
+}
+
+// readTracker reports whether anything was read from r
+type readTracker struct {
+	r    io.Reader
+	read bool
+}
+
+func (t *readTracker) Read(p []byte) (int, error) {
+	t.read = true
+	return t.r.Read(p)
+}
+
+// upload stores r under a unique name derived from p. The data goes to a .part file first, which is
+// renamed when complete. If a previous attempt of the same task left the .part file behind and r can seek,
+// the upload resumes from its size with REST. Without a task id the .part file may be left by another
+// upload, so it is deleted instead. Errors before r is touched are returned as *unreadError
+func (f *FTP) upload(conn *ftp.ServerConn, r io.Reader, p string, length int64, taskID string) error {
+	if err := mkdirAll(conn, path.Dir(p)); err != nil {
+		return &unreadError{err: err}
+	}
+	ext := path.Ext(p)
+	base := strings.TrimSuffix(p, ext)
+	candidate := p
+	for i := 1; exists(conn, candidate) || !f.reserve(candidate); i++ {
+		candidate = fmt.Sprintf("%s_%d%s", base, i, ext)
+	}
+	defer f.release(candidate)
+	part := candidate + partSuffix
+	if taskID != "" {
+		part = candidate + "." + taskID + partSuffix
+	}
+
+	var offset int64
+	if taskID == "" {
+		if exists(conn, part) {
+			if err := conn.Delete(part); err != nil {
+				f.logger.Warnf("Failed to delete stale %s: %s", part, err)
+			}
+		}
+	} else if rs, ok := r.(io.Seeker); ok {
+		if size, err := conn.FileSize(part); err == nil && size > 0 && (length < 0 || size <= length) {
+			if _, err := rs.Seek(size, io.SeekStart); err == nil {
+				f.logger.Infof("Resuming upload of %s from %d bytes", candidate, size)
+				offset = size
+			} else if _, err := rs.Seek(0, io.SeekStart); err != nil {
+				return fmt.Errorf("failed to seek reader: %w", err)
+			}
+		}
+	}
+	tr := &readTracker{r: r}
+	if err := conn.StorFrom(part, tr, uint64(offset)); err != nil {
+		err = fmt.Errorf("failed to upload %s: %w", part, err)
+		if !tr.read && offset == 0 {
+			return &unreadError{err: err}
+		}
+		return err
+	}
+	if err := conn.Rename(part, candidate); err != nil {
+		if nameRejected(err) {
+			// the .part file can never get this name, it would be left behind
+			if delErr := conn.Delete(part); delErr != nil {
+				f.logger.Warnf("Failed to delete %s: %s", part, delErr)
+			}
+		}
+		return fmt.Errorf("failed to rename %s: %w", part, err)
+	}
+	return nil
+}
+
+func (f *FTP) reserve(p string) bool {
+	f.uploadingMu.Lock()
+	defer f.uploadingMu.Unlock()
+	if _, ok := f.uploading[p]; ok {
+		return false
+	}
+	f.uploading[p] = struct{}{}
+	return true
+}
+
+func (f *FTP) release(p string) {
+	f.uploadingMu.Lock()
+	defer f.uploadingMu.Unlock()
+	delete(f.uploading, p)
+}
+
+// mkdirAll creates dir and its missing parents with MKD
+func mkdirAll(conn *ftp.ServerConn, dir string) error {
+	if dir == "/" || dir == "." {
+		return nil
+	}
+	if err := conn.ChangeDir(dir); err == nil {
+		return nil
+	}
+	if err := mkdirAll(conn, path.Dir(dir)); err != nil {
+		return err
+	}
+	if err := conn.MakeDir(dir); err != nil {
+		// created by another upload in the meantime
+		if conn.ChangeDir(dir) == nil {
+			return nil
+		}
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+	return nil
+}
+
+func exists(conn *ftp.ServerConn, p string) bool {
+	_, err := conn.FileSize(p)
+	return err == nil
+}
+
+func (f *FTP) Exists(ctx context.Context, storagePath string) bool {
+	conn, err := f.pool.get(ctx)
+	if err != nil {
+		f.logger.Errorf("Failed to check if file exists at %s: %v", storagePath, err)
+		return false
+	}
+	target := f.abs(storagePath)
+	_, err = conn.FileSize(target)
+	f.pool.put(conn, err)
+	if err == nil {
+		return true
+	}
+	if !f.config.ASCIINames && asciiPath(target) == target {
+		return false
+	}
+	conn, err = f.pool.get(ctx)
+	if err != nil {
+		return false
+	}
+	_, err = conn.FileSize(asciiPath(target))
+	f.pool.put(conn, err)
+	return err == nil
+}
+
+// Probe logs in and reads the working directory
+func (f *FTP) Probe(ctx context.Context) error {
+	conn, err := f.pool.get(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = conn.CurrentDir()
+	f.pool.put(conn, err)
+	return err
+}
+
+// asciiPath transliterates the non-ASCII elements of p
+func asciiPath(p string) string {
+	elems := strings.Split(p, "/")
+	for i, elem := range elems {
+		if !isASCII(elem) {
+			elems[i] = strutil.ToASCII(elem)
+		}
+	}
+	return strings.Join(elems, "/")
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// nameRejected reports whether the server refused a command because of the file name
+func nameRejected(err error) bool {
+	var reply *textproto.Error
+	if !errors.As(err, &reply) {
+		return false
+	}
+	switch reply.Code {
+	case ftp.StatusBadArguments, ftp.StatusFileUnavailable, ftp.StatusBadFileName:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,207 @@
+package ftp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	config "github.com/krau/SaveAny-Bot/config/storage"
+)
+
+// fakeServer is an FTP server keeping the files in memory. Every directory exists,
+// and RNTO is refused for the names rejectName matches
+type fakeServer struct {
+	ln         net.Listener
+	rejectName func(name string) bool
+
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+func newFakeServer(t *testing.T, rejectName func(name string) bool) *fakeServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{ln: ln, rejectName: rejectName, files: make(map[string][]byte)}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeServer) port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeServer) names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	for name := range s.files {
+		names = append(names, name)
+	}
+	return names
+}
+
+func (s *fakeServer) file(name string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.files[name]
+	return data, ok
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(format string, args ...any) {
+		fmt.Fprintf(conn, format+"\r\n", args...)
+	}
+	reply("220 ready")
+	var data net.Listener
+	var rest int64
+	var renameFrom string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd, arg, _ := strings.Cut(strings.TrimRight(line, "\r\n"), " ")
+		switch strings.ToUpper(cmd) {
+		case "USER":
+			reply("331 password please")
+		case "PASS":
+			reply("230 logged in")
+		case "TYPE", "NOOP":
+			reply("200 ok")
+		case "PWD":
+			reply(`257 "/" is the current directory`)
+		case "CWD":
+			reply("250 ok")
+		case "SIZE":
+			if d, ok := s.file(arg); ok {
+				reply("213 %d", len(d))
+			} else {
+				reply("550 not found")
+			}
+		case "EPSV":
+			if data, err = net.Listen("tcp", "127.0.0.1:0"); err != nil {
+				reply("425 can't open data connection")
+				continue
+			}
+			reply("229 Entering Extended Passive Mode (|||%d|)", data.Addr().(*net.TCPAddr).Port)
+		case "REST":
+			rest, _ = strconv.ParseInt(arg, 10, 64)
+			reply("350 restarting")
+		case "STOR":
+			reply("150 sending")
+			dc, err := data.Accept()
+			data.Close()
+			if err != nil {
+				reply("425 no data connection")
+				continue
+			}
+			body, _ := io.ReadAll(dc)
+			dc.Close()
+			s.mu.Lock()
+			old := s.files[arg]
+			if int64(len(old)) > rest {
+				old = old[:rest]
+			}
+			s.files[arg] = append(old, body...)
+			s.mu.Unlock()
+			rest = 0
+			reply("226 done")
+		case "RNFR":
+			if _, ok := s.file(arg); !ok {
+				reply("550 not found")
+				continue
+			}
+			renameFrom = arg
+			reply("350 ready for RNTO")
+		case "RNTO":
+			if s.rejectName(arg) {
+				reply("550 bad file name")
+				continue
+			}
+			s.mu.Lock()
+			s.files[arg] = s.files[renameFrom]
+			delete(s.files, renameFrom)
+			s.mu.Unlock()
+			reply("250 renamed")
+		case "DELE":
+			s.mu.Lock()
+			delete(s.files, arg)
+			s.mu.Unlock()
+			reply("250 deleted")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func newTestFTP(t *testing.T, s *fakeServer) *FTP {
+	t.Helper()
+	f := new(FTP)
+	if err := f.Init(context.Background(), &config.FTPStorageConfig{
+		BaseConfig: config.BaseConfig{Name: "ftp"},
+		Host:       "127.0.0.1",
+		Port:       s.port(),
+		BasePath:   "/upload",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestSaveRenameRejected(t *testing.T) {
+	content := bytes.Repeat([]byte("data"), 1000)
+	rejectNonASCII := func(name string) bool { return !isASCII(name) }
+
+	t.Run("seekable", func(t *testing.T) {
+		s := newFakeServer(t, rejectNonASCII)
+		f := newTestFTP(t, s)
+		target := f.JoinStoragePath("视频.bin")
+		if err := f.Save(context.Background(), bytes.NewReader(content), target); err != nil {
+			t.Fatal(err)
+		}
+		// the data is stored again under the ASCII name after RNTO was refused
+		data, ok := s.file(asciiPath(target))
+		if !ok || !bytes.Equal(data, content) {
+			t.Errorf("%s has %d bytes, want %d", asciiPath(target), len(data), len(content))
+		}
+		if names := s.names(); len(names) != 1 {
+			t.Errorf("files on the server = %v, want only %s", names, asciiPath(target))
+		}
+	})
+
+	t.Run("not seekable", func(t *testing.T) {
+		s := newFakeServer(t, rejectNonASCII)
+		f := newTestFTP(t, s)
+		// r was read completely and can not be stored again
+		if err := f.Save(context.Background(), io.MultiReader(bytes.NewReader(content)), f.JoinStoragePath("视频.bin")); err == nil {
+			t.Error("Save() succeeded without storing the data")
+		}
+		if names := s.names(); len(names) != 0 {
+			t.Errorf("files on the server = %v, want none", names)
+		}
+	})
+}
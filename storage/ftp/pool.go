@@ -0,0 +1,69 @@
+package ftp
+
+import (
+	"context"
+	"errors"
+	"net/textproto"
+	"sync"
+
+	"github.com/jlaffaye/ftp"
+)
+
+// pool keeps the connections of a storage, at most cap(sem) of them are open at the same time
+type pool struct {
+	dial func(ctx context.Context) (*ftp.ServerConn, error)
+	sem  chan struct{}
+
+	mu   sync.Mutex
+	idle []*ftp.ServerConn
+}
+
+func newPool(size int, dial func(ctx context.Context) (*ftp.ServerConn, error)) *pool {
+	return &pool{
+		dial: dial,
+		sem:  make(chan struct{}, size),
+	}
+}
+
+// get returns an idle connection that is still alive or dials a new one, waiting while all are in use
+func (p *pool) get(ctx context.Context) (*ftp.ServerConn, error) {
+	select {
+	case p.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	for {
+		p.mu.Lock()
+		if len(p.idle) == 0 {
+			p.mu.Unlock()
+			break
+		}
+		conn := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		p.mu.Unlock()
+		if err := conn.NoOp(); err == nil {
+			return conn, nil
+		}
+		conn.Quit()
+	}
+	conn, err := p.dial(ctx)
+	if err != nil {
+		<-p.sem
+		return nil, err
+	}
+	return conn, nil
+}
+
+// put gives the connection back. It is closed instead if err is not a reply of the server,
+// as the connection may be broken
+func (p *pool) put(conn *ftp.ServerConn, err error) {
+	defer func() { <-p.sem }()
+	var reply *textproto.Error
+	if err != nil && !errors.As(err, &reply) {
+		conn.Quit()
+		return
+	}
+	p.mu.Lock()
+	p.idle = append(p.idle, conn)
+	p.mu.Unlock()
+}
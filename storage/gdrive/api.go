@@ -0,0 +1,148 @@
+package gdrive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	apiURL    = "https://www.googleapis.com/drive/v3"
+	uploadURL = "https://www.googleapis.com/upload/drive/v3/files"
+
+	folderMimeType = "application/vnd.google-apps.folder"
+
+	maxRetries = 6
+)
+
+type driveFile struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type apiError struct {
+	Status  int
+	Message string
+	Reason  string
+}
+
+func (e *apiError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("google drive: %d %s (%s)", e.Status, e.Message, e.Reason)
+	}
+	return fmt.Sprintf("google drive: %d %s", e.Status, e.Message)
+}
+
+// retryable reports whether the request should be sent again after a backoff: rate limits,
+// which Drive reports as 403 or 429, and server errors
+func (e *apiError) retryable() bool {
+	switch {
+	case e.Status == http.StatusTooManyRequests || e.Status >= 500:
+		return true
+	case e.Status == http.StatusForbidden:
+		return e.Reason == "rateLimitExceeded" || e.Reason == "userRateLimitExceeded"
+	}
+	return false
+}
+
+func parseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var errResp struct {
+		Error struct {
+			Message string `json:"message"`
+			Errors  []struct {
+				Reason string `json:"reason"`
+			} `json:"errors"`
+		} `json:"error"`
+	}
+	apiErr := &apiError{Status: resp.StatusCode, Message: resp.Status}
+	if json.Unmarshal(body, &errResp) == nil && errResp.Error.Message != "" {
+		apiErr.Message = errResp.Error.Message
+		if len(errResp.Error.Errors) > 0 {
+			apiErr.Reason = errResp.Error.Errors[0].Reason
+		}
+	}
+	return apiErr
+}
+
+// backoff returns the delay before the given retry, exponential with random jitter as recommended by Google
+func backoff(attempt int) time.Duration {
+	d := time.Duration(1<<attempt) * time.Second
+	if d > 32*time.Second {
+		d = 32 * time.Second
+	}
+	return d + time.Duration(rand.Int64N(int64(time.Second)))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+// do sends the request built by newReq, retrying with backoff on rate limits and server errors.
+// The response is decoded into out if it is not nil
+func (g *GDrive) do(ctx context.Context, newReq func() (*http.Request, error), out any) (*http.Response, error) {
+	return g.doChecked(ctx, newReq, out, nil)
+}
+
+// doChecked is do for requests that must not take effect twice. Before each retry it calls
+// check, which reports whether the failed attempt took effect anyway, e.g. the response was
+// lost after Drive created the file. In that case doChecked returns a nil response and error
+func (g *GDrive) doChecked(ctx context.Context, newReq func() (*http.Request, error), out any, check func() (bool, error)) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && check != nil {
+			done, err := check()
+			if err != nil {
+				return nil, err
+			}
+			if done {
+				return nil, nil
+			}
+		}
+		req, err := newReq()
+		if err != nil {
+			return nil, err
+		}
+		resp, err := g.client.Do(req.WithContext(ctx))
+		if err == nil && resp.StatusCode < 400 {
+			defer resp.Body.Close()
+			if out != nil {
+				if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+					return resp, fmt.Errorf("failed to decode response: %w", err)
+				}
+			}
+			return resp, nil
+		}
+		if err == nil {
+			err = parseError(resp)
+			resp.Body.Close()
+			var apiErr *apiError
+			if errors.As(err, &apiErr) && !apiErr.retryable() {
+				return nil, err
+			}
+		}
+		if ctx.Err() != nil || attempt >= maxRetries {
+			return nil, err
+		}
+		delay := backoff(attempt)
+		if resp != nil {
+			if s, convErr := strconv.Atoi(resp.Header.Get("Retry-After")); convErr == nil && time.Duration(s)*time.Second > delay {
+				delay = time.Duration(s) * time.Second
+			}
+		}
+		g.logger.Warnf("Request failed, retrying in %s: %s", delay.Round(time.Millisecond), err)
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
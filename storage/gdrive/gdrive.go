@@ -0,0 +1,310 @@
+package gdrive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/log"
+	config "github.com/krau/SaveAny-Bot/config/storage"
+	storenum "github.com/krau/SaveAny-Bot/pkg/enums/storage"
+	"github.com/krau/SaveAny-Bot/pkg/uploadinfo"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/jwt"
+)
+
+const (
+	driveScope = "https://www.googleapis.com/auth/drive"
+	tokenURL   = "https://oauth2.googleapis.com/token"
+	authURL    = "https://accounts.google.com/o/oauth2/auth"
+
+	defaultChunkSize = 8 * 1024 * 1024
+)
+
+type GDrive struct {
+	config config.GDriveStorageConfig
+	client *http.Client
+	logger *log.Logger
+	rootID string
+
+	// folderMu serializes the creation of folders, Drive would accept duplicates
+	folderMu  sync.Mutex
+	foldersMu sync.RWMutex
+	folders   map[string]string // folder path -> id
+}
+
+func (g *GDrive) Init(ctx context.Context, cfg config.StorageConfig) error {
+	gdriveConfig, ok := cfg.(*config.GDriveStorageConfig)
+	if !ok {
+		return fmt.Errorf("failed to cast gdrive config")
+	}
+	if err := gdriveConfig.Validate(); err != nil {
+		return err
+	}
+	g.config = *gdriveConfig
+	if g.config.ChunkSize == 0 {
+		g.config.ChunkSize = defaultChunkSize
+	}
+	g.logger = log.FromContext(ctx).WithPrefix(fmt.Sprintf("gdrive[%s]", g.config.Name))
+
+	ts, err := g.tokenSource()
+	if err != nil {
+		return err
+	}
+	g.client = oauth2.NewClient(context.Background(), ts)
+	g.rootID = g.config.FolderID
+	if g.rootID == "" {
+		g.rootID = g.config.DriveID
+	}
+	if g.rootID == "" {
+		g.rootID = "root"
+	}
+	g.folders = make(map[string]string)
+	return nil
+}
+
+func (g *GDrive) tokenSource() (oauth2.TokenSource, error) {
+	if g.config.ServiceAccountFile == "" {
+		conf := &oauth2.Config{
+			ClientID:     g.config.ClientID,
+			ClientSecret: g.config.ClientSecret,
+			Endpoint:     oauth2.Endpoint{AuthURL: authURL, TokenURL: tokenURL},
+			Scopes:       []string{driveScope},
+		}
+		return conf.TokenSource(context.Background(), &oauth2.Token{RefreshToken: g.config.RefreshToken}), nil
+	}
+	data, err := os.ReadFile(g.config.ServiceAccountFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account file: %w", err)
+	}
+	var key struct {
+		Type         string `json:"type"`
+		ClientEmail  string `json:"client_email"`
+		PrivateKey   string `json:"private_key"`
+		PrivateKeyID string `json:"private_key_id"`
+		TokenURI     string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(data, &key); err != nil {
+		return nil, fmt.Errorf("failed to parse service account file: %w", err)
+	}
+	if key.Type != "service_account" {
+		return nil, fmt.Errorf("service account file has type %q, want service_account", key.Type)
+	}
+	conf := &jwt.Config{
+		Email:        key.ClientEmail,
+		PrivateKey:   []byte(key.PrivateKey),
+		PrivateKeyID: key.PrivateKeyID,
+		TokenURL:     key.TokenURI,
+		Scopes:       []string{driveScope},
+	}
+	if conf.TokenURL == "" {
+		conf.TokenURL = tokenURL
+	}
+	return conf.TokenSource(context.Background()), nil
+}
+
+func (g *GDrive) Type() storenum.StorageType {
+	return storenum.Gdrive
+}
+
+func (g *GDrive) Name() string {
+	return g.config.Name
+}
+
+func (g *GDrive) JoinStoragePath(p string) string {
+	return path.Join(g.config.BasePath, p)
+}
+
+// listParams adds the parameters needed to see the files of shared drives
+func (g *GDrive) listParams(q url.Values) url.Values {
+	q.Set("supportsAllDrives", "true")
+	q.Set("includeItemsFromAllDrives", "true")
+	if g.config.DriveID != "" {
+		q.Set("corpora", "drive")
+		q.Set("driveId", g.config.DriveID)
+	}
+	return q
+}
+
+func escapeQuery(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s)
+}
+
+// find returns the oldest child of parent with the given name, nil if there is none
+func (g *GDrive) find(ctx context.Context, parentID, name string, folder bool) (*driveFile, error) {
+	query := fmt.Sprintf("'%s' in parents and name = '%s' and trashed = false", escapeQuery(parentID), escapeQuery(name))
+	if folder {
+		query += fmt.Sprintf(" and mimeType = '%s'", folderMimeType)
+	}
+	params := g.listParams(url.Values{
+		"q":        {query},
+		"fields":   {"files(id,name)"},
+		"orderBy":  {"createdTime"},
+		"pageSize": {"1"},
+	})
+	var list struct {
+		Files []driveFile `json:"files"`
+	}
+	if _, err := g.do(ctx, func() (*http.Request, error) {
+		return http.NewRequest(http.MethodGet, apiURL+"/files?"+params.Encode(), nil)
+	}, &list); err != nil {
+		return nil, err
+	}
+	if len(list.Files) == 0 {
+		return nil, nil
+	}
+	return &list.Files[0], nil
+}
+
+func (g *GDrive) createFolder(ctx context.Context, parentID, name string) (string, error) {
+	body, err := json.Marshal(map[string]any{
+		"name":     name,
+		"mimeType": folderMimeType,
+		"parents":  []string{parentID},
+	})
+	if err != nil {
+		return "", err
+	}
+	var created driveFile
+	// a retried create would add a second folder if the first one went through
+	recheck := func() (bool, error) {
+		folder, err := g.find(ctx, parentID, name, true)
+		if err != nil || folder == nil {
+			return false, err
+		}
+		created = *folder
+		return true, nil
+	}
+	if _, err := g.doChecked(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodPost, apiURL+"/files?supportsAllDrives=true&fields=id", bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	}, &created, recheck); err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+// folderID resolves the folder path to its id, creating the missing folders if create is set.
+// An existing folder is reused even if Drive holds several with the same name. The empty id is
+// returned if the folder does not exist and create is not set
+func (g *GDrive) folderID(ctx context.Context, dir string, create bool) (string, error) {
+	dir = strings.Trim(path.Clean("/"+dir), "/")
+	if dir == "" {
+		return g.rootID, nil
+	}
+	g.foldersMu.RLock()
+	id, ok := g.folders[dir]
+	g.foldersMu.RUnlock()
+	if ok {
+		return id, nil
+	}
+	parentID, err := g.folderID(ctx, path.Dir(dir), create)
+	if err != nil || parentID == "" {
+		return "", err
+	}
+	if create {
+		g.folderMu.Lock()
+		defer g.folderMu.Unlock()
+	}
+	name := path.Base(dir)
+	folder, err := g.find(ctx, parentID, name, true)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case folder != nil:
+		id = folder.ID
+	case create:
+		if id, err = g.createFolder(ctx, parentID, name); err != nil {
+			return "", fmt.Errorf("failed to create folder %s: %w", dir, err)
+		}
+	default:
+		return "", nil
+	}
+	g.foldersMu.Lock()
+	g.folders[dir] = id
+	g.foldersMu.Unlock()
+	return id, nil
+}
+
+// forget drops the cached folder ids, e.g. after a folder was deleted in Drive
+func (g *GDrive) forget() {
+	g.foldersMu.Lock()
+	defer g.foldersMu.Unlock()
+	clear(g.folders)
+}
+
+func (g *GDrive) Save(ctx context.Context, r io.Reader, storagePath string) error {
+	g.logger.Infof("Saving file to %s", storagePath)
+	dir, name := path.Split(storagePath)
+	parentID, err := g.folderID(ctx, dir, true)
+	if err != nil {
+		return fmt.Errorf("failed to resolve folder: %w", err)
+	}
+
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	candidate := name
+	for i := 1; ; i++ {
+		file, err := g.find(ctx, parentID, candidate, false)
+		if err != nil {
+			return fmt.Errorf("failed to check if file exists: %w", err)
+		}
+		if file == nil {
+			break
+		}
+		candidate = fmt.Sprintf("%s_%d%s", base, i, ext)
+	}
+
+	id, err := g.upload(ctx, r, parentID, candidate)
+	if err != nil {
+		var apiErr *apiError
+		if errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound {
+			// the cached parent may have been deleted
+			g.forget()
+		}
+		return err
+	}
+	g.logger.Debugf("Uploaded %s as %s", path.Join(dir, candidate), id)
+	uploadinfo.Record(ctx, uploadinfo.File{Storage: g.config.Name, Path: path.Join(dir, candidate), ID: id})
+	return nil
+}
+
+func (g *GDrive) Exists(ctx context.Context, storagePath string) bool {
+	dir, name := path.Split(storagePath)
+	parentID, err := g.folderID(ctx, dir, false)
+	if err != nil {
+		g.logger.Errorf("Failed to check if file exists at %s: %v", storagePath, err)
+		return false
+	}
+	if parentID == "" {
+		return false
+	}
+	file, err := g.find(ctx, parentID, name, false)
+	if err != nil {
+		g.logger.Errorf("Failed to check if file exists at %s: %v", storagePath, err)
+		return false
+	}
+	return file != nil
+}
+
+// Probe gets the root folder, which checks the credentials and the access to folder_id or drive_id
+func (g *GDrive) Probe(ctx context.Context) error {
+	_, err := g.do(ctx, func() (*http.Request, error) {
+		return http.NewRequest(http.MethodGet, apiURL+"/files/"+url.PathEscape(g.rootID)+"?supportsAllDrives=true&fields=id", nil)
+	}, nil)
+	return err
+}
@@ -0,0 +1,82 @@
+package gdrive
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/charmbracelet/log"
+)
+
+// fakeDrive serves the folder list and create requests from memory. The first create
+// fails with a 503 after the folder was created, like a response lost on the way back
+type fakeDrive struct {
+	mu      sync.Mutex
+	folders []driveFile
+	creates int
+}
+
+func (d *fakeDrive) RoundTrip(req *http.Request) (*http.Response, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	reply := func(status int, v any) (*http.Response, error) {
+		body, _ := json.Marshal(v)
+		return &http.Response{
+			StatusCode: status,
+			Status:     http.StatusText(status),
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(string(body))),
+			Request:    req,
+		}, nil
+	}
+	switch req.Method {
+	case http.MethodGet:
+		var files []driveFile
+		for _, f := range d.folders {
+			if strings.Contains(req.URL.Query().Get("q"), fmt.Sprintf("name = '%s'", f.Name)) {
+				files = append(files, f)
+			}
+		}
+		return reply(http.StatusOK, map[string]any{"files": files})
+	case http.MethodPost:
+		var body struct {
+			Name string `json:"name"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			return nil, err
+		}
+		d.creates++
+		folder := driveFile{ID: fmt.Sprintf("folder%d", d.creates), Name: body.Name}
+		d.folders = append(d.folders, folder)
+		if d.creates == 1 {
+			return reply(http.StatusServiceUnavailable, map[string]any{"error": map[string]any{"message": "backend error"}})
+		}
+		return reply(http.StatusOK, folder)
+	}
+	return reply(http.StatusMethodNotAllowed, nil)
+}
+
+func TestFolderIDRetriedCreate(t *testing.T) {
+	drive := &fakeDrive{}
+	g := &GDrive{
+		client:  &http.Client{Transport: drive},
+		logger:  log.New(io.Discard),
+		rootID:  "root",
+		folders: make(map[string]string),
+	}
+	id, err := g.folderID(context.Background(), "videos", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if drive.creates != 1 {
+		t.Errorf("folder created %d times, want 1", drive.creates)
+	}
+	if id != "folder1" {
+		t.Errorf("folderID() = %q, want folder1", id)
+	}
+}
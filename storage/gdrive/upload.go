@@ -0,0 +1,167 @@
+package gdrive
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/krau/SaveAny-Bot/pkg/enums/ctxkey"
+)
+
+// upload sends r in a resumable upload session and returns the id of the new file.
+// A chunk is kept until Drive confirmed it, so it can be sent again from the offset Drive
+// reports after a failure even if r can't seek
+func (g *GDrive) upload(ctx context.Context, r io.Reader, parentID, name string) (string, error) {
+	total := int64(-1)
+	if length, ok := ctx.Value(ctxkey.ContentLength).(int64); ok && length >= 0 {
+		total = length
+	}
+	session, err := g.createSession(ctx, parentID, name, total)
+	if err != nil {
+		return "", fmt.Errorf("failed to create upload session: %w", err)
+	}
+
+	br := bufio.NewReader(r)
+	buf := make([]byte, g.config.ChunkSize.Bytes())
+	var offset int64
+	for {
+		n, readErr := io.ReadFull(br, buf)
+		if readErr != nil && readErr != io.EOF && readErr != io.ErrUnexpectedEOF {
+			return "", fmt.Errorf("failed to read file: %w", readErr)
+		}
+		last := readErr != nil
+		if !last {
+			if _, err := br.Peek(1); err == io.EOF {
+				last = true
+			} else if err != nil {
+				return "", fmt.Errorf("failed to read file: %w", err)
+			}
+		}
+		if last {
+			total = offset + int64(n)
+		}
+		id, err := g.sendChunk(ctx, session, buf[:n], offset, total)
+		if err != nil {
+			return "", fmt.Errorf("failed to upload file: %w", err)
+		}
+		if last {
+			if id == "" {
+				return "", fmt.Errorf("upload finished without a file id")
+			}
+			return id, nil
+		}
+		offset += int64(n)
+	}
+}
+
+func (g *GDrive) createSession(ctx context.Context, parentID, name string, total int64) (string, error) {
+	body, err := json.Marshal(map[string]any{
+		"name":    name,
+		"parents": []string{parentID},
+	})
+	if err != nil {
+		return "", err
+	}
+	resp, err := g.do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodPost, uploadURL+"?uploadType=resumable&supportsAllDrives=true&fields=id", bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+		if total >= 0 {
+			req.Header.Set("X-Upload-Content-Length", strconv.FormatInt(total, 10))
+		}
+		return req, nil
+	}, nil)
+	if err != nil {
+		return "", err
+	}
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return "", fmt.Errorf("response has no upload session location")
+	}
+	return location, nil
+}
+
+// sendChunk uploads the chunk starting at offset, total is -1 while the size is unknown.
+// The file id is returned once Drive accepted the last chunk
+func (g *GDrive) sendChunk(ctx context.Context, session string, chunk []byte, offset, total int64) (string, error) {
+	end := offset + int64(len(chunk))
+	data := chunk
+	for attempt := 0; ; {
+		id, received, err := g.put(ctx, session, data, end-int64(len(data)), total)
+		if err != nil {
+			var apiErr *apiError
+			if ctx.Err() != nil || (errors.As(err, &apiErr) && !apiErr.retryable()) || attempt >= maxRetries {
+				return "", err
+			}
+			delay := backoff(attempt)
+			attempt++
+			g.logger.Warnf("Chunk at %d bytes failed, resuming in %s: %s", offset, delay.Round(time.Millisecond), err)
+			if err := sleep(ctx, delay); err != nil {
+				return "", err
+			}
+			// an empty request asks Drive how many bytes it got
+			data = nil
+			continue
+		}
+		if id != "" || received >= end {
+			return id, nil
+		}
+		if received < offset {
+			return "", fmt.Errorf("upload session is at %d bytes, want at least %d", received, offset)
+		}
+		data = chunk[received-offset:]
+	}
+}
+
+// put sends data at start and returns either the file id, once the upload is complete,
+// or the number of bytes Drive received so far. Empty data only queries the upload status
+func (g *GDrive) put(ctx context.Context, session string, data []byte, start, total int64) (string, int64, error) {
+	size := "*"
+	if total >= 0 {
+		size = strconv.FormatInt(total, 10)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, session, bytes.NewReader(data))
+	if err != nil {
+		return "", 0, err
+	}
+	if len(data) == 0 {
+		req.Header.Set("Content-Range", "bytes */"+size)
+	} else {
+		req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%s", start, start+int64(len(data))-1, size))
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		var file driveFile
+		if err := json.NewDecoder(resp.Body).Decode(&file); err != nil {
+			return "", 0, fmt.Errorf("failed to decode response: %w", err)
+		}
+		return file.ID, 0, nil
+	case http.StatusPermanentRedirect:
+		// Range is "bytes=0-<last received byte>", missing if nothing was received
+		rng := resp.Header.Get("Range")
+		if i := strings.LastIndexByte(rng, '-'); i >= 0 {
+			last, err := strconv.ParseInt(rng[i+1:], 10, 64)
+			if err != nil {
+				return "", 0, fmt.Errorf("invalid range %q in response", rng)
+			}
+			return "", last + 1, nil
+		}
+		return "", 0, nil
+	}
+	return "", 0, parseError(resp)
+}
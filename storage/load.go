@@ -3,12 +3,20 @@ package storage
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"sync"
 
 	"github.com/charmbracelet/log"
 	"github.com/krau/SaveAny-Bot/config"
+	storcfg "github.com/krau/SaveAny-Bot/config/storage"
 )
 
-var UserStorages = make(map[int64][]Storage)
+var (
+	UserStorages = make(map[int64][]Storage)
+	// configs the cached storages were created from
+	storageConfigs = make(map[string]storcfg.StorageConfig)
+	storagesMu     sync.RWMutex
+)
 
 // GetStorageByName returns storage by name from cache or creates new one
 func getStorageByName(ctx context.Context, name string) (Storage, error) {
@@ -16,11 +24,13 @@ func getStorageByName(ctx context.Context, name string) (Storage, error) {
 		return nil, ErrStorageNameEmpty
 	}
 
+	storagesMu.RLock()
 	storage, ok := Storages[name]
+	storagesMu.RUnlock()
 	if ok {
 		return storage, nil
 	}
-	cfg := config.Cfg.GetStorageByName(name)
+	cfg := config.C().GetStorageByName(name)
 	if cfg == nil {
 		return nil, fmt.Errorf("未找到存储 %s", name)
 	}
@@ -29,7 +39,10 @@ func getStorageByName(ctx context.Context, name string) (Storage, error) {
 	if err != nil {
 		return nil, err
 	}
+	storagesMu.Lock()
 	Storages[name] = storage
+	storageConfigs[name] = cfg
+	storagesMu.Unlock()
 	return storage, nil
 }
 
@@ -39,7 +52,7 @@ func GetStorageByUserIDAndName(ctx context.Context, chatID int64, name string) (
 		return nil, ErrStorageNameEmpty
 	}
 
-	if !config.Cfg.HasStorage(chatID, name) {
+	if !config.C().HasStorage(chatID, name) {
 		return nil, fmt.Errorf("没有找到用户 %d 的存储 %s", chatID, name)
 	}
 
@@ -50,11 +63,13 @@ func GetUserStorages(ctx context.Context, chatID int64) []Storage {
 	if chatID <= 0 {
 		return nil
 	}
-	if storages, ok := UserStorages[chatID]; ok {
+	storagesMu.RLock()
+	storages, ok := UserStorages[chatID]
+	storagesMu.RUnlock()
+	if ok {
 		return storages
 	}
-	var storages []Storage
-	for _, name := range config.Cfg.GetStorageNamesByUserID(chatID) {
+	for _, name := range config.C().GetStorageNamesByUserID(chatID) {
 		storage, err := getStorageByName(ctx, name)
 		if err != nil {
 			continue
@@ -67,14 +82,47 @@ func GetUserStorages(ctx context.Context, chatID int64) []Storage {
 func LoadStorages(ctx context.Context) {
 	logger := log.FromContext(ctx)
 	logger.Info("加载存储...")
-	for _, storage := range config.Cfg.Storages {
+	loadStorages(ctx)
+	config.OnReload(func(ctx context.Context, _, _ *config.Config) {
+		reloadStorages(ctx)
+	})
+}
+
+func loadStorages(ctx context.Context) {
+	logger := log.FromContext(ctx)
+	for _, storage := range config.C().Storages {
 		_, err := getStorageByName(ctx, storage.GetName())
 		if err != nil {
 			logger.Errorf("加载存储 %s 失败: %v", storage.GetName(), err)
 		}
 	}
+	storagesMu.RLock()
 	logger.Infof("成功加载 %d 个存储", len(Storages))
-	for user := range config.Cfg.GetUsersID() {
-		UserStorages[int64(user)] = GetUserStorages(ctx, int64(user))
+	storagesMu.RUnlock()
+	userStorages := make(map[int64][]Storage)
+	for _, user := range config.C().GetUsersID() {
+		userStorages[user] = GetUserStorages(ctx, user)
+	}
+	storagesMu.Lock()
+	UserStorages = userStorages
+	storagesMu.Unlock()
+}
+
+// reloadStorages drops the storages whose config was changed or removed and re-initializes them.
+// Running tasks keep the storage instance they were created with.
+func reloadStorages(ctx context.Context) {
+	logger := log.FromContext(ctx)
+	storagesMu.Lock()
+	for name, oldCfg := range storageConfigs {
+		newCfg := config.C().GetStorageByName(name)
+		if newCfg != nil && reflect.DeepEqual(oldCfg, newCfg) {
+			continue
+		}
+		logger.Infof("存储 %s 的配置已变更, 将重新加载", name)
+		delete(Storages, name)
+		delete(storageConfigs, name)
 	}
+	UserStorages = make(map[int64][]Storage)
+	storagesMu.Unlock()
+	loadStorages(ctx)
 }
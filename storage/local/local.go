@@ -7,16 +7,21 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/charmbracelet/log"
 	"github.com/duke-git/lancet/v2/fileutil"
+	"github.com/krau/SaveAny-Bot/common/utils/fsutil"
 	config "github.com/krau/SaveAny-Bot/config/storage"
+	"github.com/krau/SaveAny-Bot/config/types"
 	storenum "github.com/krau/SaveAny-Bot/pkg/enums/storage"
 )
 
 type Local struct {
-	config config.LocalStorageConfig
-	logger *log.Logger
+	config    config.LocalStorageConfig
+	logger    *log.Logger
+	uid, gid  int // -1 keeps the owner of the bot
+	chownOnce sync.Once
 }
 
 func (l *Local) Init(ctx context.Context, cfg config.StorageConfig) error {
@@ -28,11 +33,57 @@ func (l *Local) Init(ctx context.Context, cfg config.StorageConfig) error {
 		return err
 	}
 	l.config = *localConfig
-	err := os.MkdirAll(localConfig.BasePath, os.ModePerm)
+	uid, gid, err := localConfig.Ownership()
 	if err != nil {
-		return fmt.Errorf("failed to create local storage directory: %w", err)
+		return err
 	}
+	l.uid, l.gid = uid, gid
 	l.logger = log.FromContext(ctx).WithPrefix(fmt.Sprintf("local[%s]", l.config.Name))
+	if err := l.mkdirAll(localConfig.BasePath); err != nil {
+		return fmt.Errorf("failed to create local storage directory: %w", err)
+	}
+	return nil
+}
+
+// mkdirAll creates dir and its missing parents, dir_mode and owner/group are applied to the created ones only
+func (l *Local) mkdirAll(dir string) error {
+	var created []string
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil {
+			break
+		}
+		created = append(created, d)
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	for i := len(created) - 1; i >= 0; i-- {
+		if err := l.setPerm(created[i], l.config.DirMode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setPerm applies the configured mode and owner to a created file or directory.
+// Failing to change the owner is not an error, it is logged once per storage
+func (l *Local) setPerm(path string, mode types.FileMode) error {
+	if mode != 0 {
+		if err := os.Chmod(path, mode.Mode()); err != nil {
+			return fmt.Errorf("failed to change mode of %s: %w", path, err)
+		}
+	}
+	if l.uid < 0 && l.gid < 0 {
+		return nil
+	}
+	if err := os.Chown(path, l.uid, l.gid); err != nil {
+		l.chownOnce.Do(func() {
+			l.logger.Warnf("Failed to change owner of %s, files of this storage keep the owner of the bot: %s", path, err)
+		})
+	}
 	return nil
 }
 
@@ -48,6 +99,24 @@ func (l *Local) JoinStoragePath(path string) string {
 	return filepath.Join(l.config.BasePath, path)
 }
 
+func (l *Local) CheckFreeSpace(need int64) error {
+	return fsutil.CheckFreeSpace(l.config.BasePath, need)
+}
+
+// Probe writes and removes a file in base_path
+func (l *Local) Probe(ctx context.Context) error {
+	file, err := os.CreateTemp(l.config.BasePath, ".saveany-probe-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.WriteString("ok"); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
 func (l *Local) Save(ctx context.Context, r io.Reader, storagePath string) error {
 	l.logger.Infof("Saving file to %s", storagePath)
 
@@ -62,7 +131,7 @@ func (l *Local) Save(ctx context.Context, r io.Reader, storagePath string) error
 	if err != nil {
 		return err
 	}
-	if err := fileutil.CreateDir(filepath.Dir(absPath)); err != nil {
+	if err := l.mkdirAll(filepath.Dir(absPath)); err != nil {
 		return err
 	}
 	file, err := os.Create(absPath)
@@ -70,6 +139,9 @@ func (l *Local) Save(ctx context.Context, r io.Reader, storagePath string) error
 		return err
 	}
 	defer file.Close()
+	if err := l.setPerm(absPath, l.config.FileMode); err != nil {
+		return err
+	}
 	_, err = io.Copy(file, r)
 	return err
 }
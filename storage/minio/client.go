@@ -41,16 +41,8 @@ func (m *Minio) Init(ctx context.Context, cfg config.StorageConfig) error {
 		return fmt.Errorf("failed to create minio client: %w", err)
 	}
 
-	exists, err := client.BucketExists(ctx, m.config.BucketName)
-	if err != nil {
-		return fmt.Errorf("failed to check bucket existence: %w", err)
-	}
-	if !exists {
-		return fmt.Errorf("bucket %s does not exist", m.config.BucketName)
-	}
-
 	m.client = client
-	return nil
+	return m.Probe(ctx)
 }
 
 func (m *Minio) Type() storenum.StorageType {
@@ -94,6 +86,18 @@ func (m *Minio) Save(ctx context.Context, r io.Reader, storagePath string) error
 	return nil
 }
 
+// Probe checks that the bucket exists and is accessible
+func (m *Minio) Probe(ctx context.Context) error {
+	exists, err := m.client.BucketExists(ctx, m.config.BucketName)
+	if err != nil {
+		return fmt.Errorf("failed to check bucket existence: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("bucket %s does not exist", m.config.BucketName)
+	}
+	return nil
+}
+
 func (m *Minio) Exists(ctx context.Context, storagePath string) bool {
 	m.logger.Debugf("Checking if file exists at %s", storagePath)
 	_, err := m.client.StatObject(ctx, m.config.BucketName, storagePath, minio.StatObjectOptions{})
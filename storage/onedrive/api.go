@@ -0,0 +1,132 @@
+package onedrive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	graphURL = "https://graph.microsoft.com/v1.0"
+
+	maxRetries = 6
+)
+
+type driveItem struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type apiError struct {
+	Status     int
+	Code       string
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *apiError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("onedrive: %d %s (%s)", e.Status, e.Message, e.Code)
+	}
+	return fmt.Sprintf("onedrive: %d %s", e.Status, e.Message)
+}
+
+// retryable reports whether the request should be sent again, after throttling (429) and server errors
+func (e *apiError) retryable() bool {
+	return e.Status == http.StatusTooManyRequests || e.Status >= 500
+}
+
+func parseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var errResp struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	apiErr := &apiError{Status: resp.StatusCode, Message: resp.Status}
+	if json.Unmarshal(body, &errResp) == nil && errResp.Error.Message != "" {
+		apiErr.Code = errResp.Error.Code
+		apiErr.Message = errResp.Error.Message
+	}
+	if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && s > 0 {
+		apiErr.RetryAfter = time.Duration(s) * time.Second
+	}
+	return apiErr
+}
+
+func isStatus(err error, status int) bool {
+	var apiErr *apiError
+	return errors.As(err, &apiErr) && apiErr.Status == status
+}
+
+// retryDelay returns how long to wait before the given retry of a request that failed with err.
+// Graph asks throttled clients to wait for Retry-After, otherwise the delay grows exponentially
+func retryDelay(err error, attempt int) time.Duration {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return apiErr.RetryAfter
+	}
+	d := time.Duration(1<<attempt) * time.Second
+	if d > 32*time.Second {
+		d = 32 * time.Second
+	}
+	return d + time.Duration(rand.Int64N(int64(time.Second)))
+}
+
+// shouldRetry reports whether a request that failed with err can be retried
+func shouldRetry(ctx context.Context, err error, attempt int) bool {
+	if ctx.Err() != nil || attempt >= maxRetries {
+		return false
+	}
+	var apiErr *apiError
+	return !errors.As(err, &apiErr) || apiErr.retryable()
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+// do sends the request built by newReq with the given client, retrying on throttling and server errors.
+// The response is decoded into out if it is not nil
+func (o *OneDrive) do(ctx context.Context, client *http.Client, newReq func() (*http.Request, error), out any) error {
+	for attempt := 0; ; attempt++ {
+		req, err := newReq()
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req.WithContext(ctx))
+		if err == nil {
+			if resp.StatusCode < 400 {
+				defer resp.Body.Close()
+				if out != nil {
+					if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+						return fmt.Errorf("failed to decode response: %w", err)
+					}
+				}
+				return nil
+			}
+			err = parseError(resp)
+			resp.Body.Close()
+		}
+		if !shouldRetry(ctx, err, attempt) {
+			return err
+		}
+		delay := retryDelay(err, attempt)
+		o.logger.Warnf("Request failed, retrying in %s: %s", delay.Round(time.Millisecond), err)
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package onedrive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/log"
+	config "github.com/krau/SaveAny-Bot/config/storage"
+	"github.com/krau/SaveAny-Bot/pkg/enums/ctxkey"
+	storenum "github.com/krau/SaveAny-Bot/pkg/enums/storage"
+	"github.com/krau/SaveAny-Bot/pkg/uploadinfo"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/microsoft"
+)
+
+const (
+	// files up to this size are uploaded in a single request, larger ones in an upload session
+	simpleUploadLimit = 4 * 1024 * 1024
+	defaultChunkSize  = 10 * 1024 * 1024
+)
+
+// Scopes returns the scopes to request for the drive type
+func Scopes(driveType string) []string {
+	scopes := []string{"offline_access", "Files.ReadWrite.All"}
+	if driveType == "sharepoint" {
+		scopes = append(scopes, "Sites.ReadWrite.All")
+	}
+	return scopes
+}
+
+// Endpoint returns the OAuth endpoint of the tenant, the client secret is sent in the body
+// so that public clients without a secret work as well
+func Endpoint(tenant string) oauth2.Endpoint {
+	endpoint := microsoft.AzureADEndpoint(tenant)
+	endpoint.AuthStyle = oauth2.AuthStyleInParams
+	return endpoint
+}
+
+// names OneDrive and SharePoint reject
+var invalidChars = strings.NewReplacer(`"`, "_", "*", "_", ":", "_", "<", "_", ">", "_", "?", "_", `\`, "_", "|", "_")
+
+type OneDrive struct {
+	config config.OneDriveStorageConfig
+	client *http.Client
+	// upload session urls are pre-authenticated and must be sent without the token
+	uploadClient *http.Client
+	logger       *log.Logger
+	drive        string // path of the drive in the Graph API, e.g. /me/drive
+
+	dirs sync.Map // folders known to exist
+}
+
+func (o *OneDrive) Init(ctx context.Context, cfg config.StorageConfig) error {
+	onedriveConfig, ok := cfg.(*config.OneDriveStorageConfig)
+	if !ok {
+		return fmt.Errorf("failed to cast onedrive config")
+	}
+	if err := onedriveConfig.Validate(); err != nil {
+		return err
+	}
+	o.config = *onedriveConfig
+	if o.config.DriveType == "" {
+		o.config.DriveType = "personal"
+	}
+	if o.config.Tenant == "" {
+		o.config.Tenant = "organizations"
+		if o.config.DriveType == "personal" {
+			o.config.Tenant = "consumers"
+		}
+	}
+	if o.config.ChunkSize == 0 {
+		o.config.ChunkSize = defaultChunkSize
+	}
+	o.logger = log.FromContext(ctx).WithPrefix(fmt.Sprintf("onedrive[%s]", o.config.Name))
+
+	switch {
+	case o.config.DriveID != "":
+		o.drive = "/drives/" + url.PathEscape(o.config.DriveID)
+	case o.config.DriveType == "sharepoint":
+		o.drive = "/sites/" + url.PathEscape(o.config.SiteID) + "/drive"
+	default:
+		o.drive = "/me/drive"
+	}
+	conf := &oauth2.Config{
+		ClientID:     o.config.ClientID,
+		ClientSecret: o.config.ClientSecret,
+		Endpoint:     Endpoint(o.config.Tenant),
+		Scopes:       Scopes(o.config.DriveType),
+	}
+	// the refresh token is rotated on every refresh, the token source keeps the latest one
+	ts := conf.TokenSource(context.Background(), &oauth2.Token{RefreshToken: o.config.RefreshToken})
+	o.client = oauth2.NewClient(context.Background(), ts)
+	o.uploadClient = &http.Client{}
+	return nil
+}
+
+func (o *OneDrive) Type() storenum.StorageType {
+	return storenum.Onedrive
+}
+
+func (o *OneDrive) Name() string {
+	return o.config.Name
+}
+
+// JoinStoragePath replaces the characters OneDrive does not allow in names
+func (o *OneDrive) JoinStoragePath(p string) string {
+	elems := strings.Split(invalidChars.Replace(p), "/")
+	for i, elem := range elems {
+		elems[i] = strings.TrimRight(strings.TrimSpace(elem), ".")
+	}
+	return path.Join(o.config.BasePath, path.Join(elems...))
+}
+
+// CannotStream implements StorageCannotStream, the size of the file has to be known before uploading
+func (o *OneDrive) CannotStream() string {
+	return "OneDrive storage needs the file size before uploading"
+}
+
+// itemURL returns the url of the item at p relative to the drive root, with an optional action
+// such as "/content" in the path based addressing of Graph
+func (o *OneDrive) itemURL(p, action string) string {
+	p = strings.Trim(path.Clean("/"+p), "/")
+	if p == "" {
+		return graphURL + o.drive + "/root" + action
+	}
+	elems := strings.Split(p, "/")
+	for i, elem := range elems {
+		elems[i] = url.PathEscape(elem)
+	}
+	u := graphURL + o.drive + "/root:/" + strings.Join(elems, "/")
+	if action != "" {
+		u += ":" + action
+	}
+	return u
+}
+
+func jsonRequest(method, url string, body any) func() (*http.Request, error) {
+	data, err := json.Marshal(body)
+	return func() (*http.Request, error) {
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequest(method, url, bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	}
+}
+
+// mkdirAll creates dir and its parents, folders that already exist are skipped
+func (o *OneDrive) mkdirAll(ctx context.Context, dir string) error {
+	dir = strings.Trim(path.Clean("/"+dir), "/")
+	if dir == "" {
+		return nil
+	}
+	if _, ok := o.dirs.Load(dir); ok {
+		return nil
+	}
+	parent := path.Dir(dir)
+	if err := o.mkdirAll(ctx, parent); err != nil {
+		return err
+	}
+	err := o.do(ctx, o.client, jsonRequest(http.MethodPost, o.itemURL(parent, "/children"), map[string]any{
+		"name":                              path.Base(dir),
+		"folder":                            map[string]any{},
+		"@microsoft.graph.conflictBehavior": "fail",
+	}), nil)
+	if err != nil && !isStatus(err, http.StatusConflict) {
+		return fmt.Errorf("failed to create folder %s: %w", dir, err)
+	}
+	o.dirs.Store(dir, struct{}{})
+	return nil
+}
+
+func (o *OneDrive) Save(ctx context.Context, r io.Reader, storagePath string) error {
+	o.logger.Infof("Saving file to %s", storagePath)
+	size, err := contentLength(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	ext := path.Ext(storagePath)
+	base := strings.TrimSuffix(storagePath, ext)
+	candidate := storagePath
+	for i := 1; o.Exists(ctx, candidate); i++ {
+		candidate = fmt.Sprintf("%s_%d%s", base, i, ext)
+	}
+	if err := o.mkdirAll(ctx, path.Dir(candidate)); err != nil {
+		if isStatus(err, http.StatusNotFound) {
+			o.dirs.Clear()
+		}
+		return err
+	}
+
+	var item *driveItem
+	if size <= simpleUploadLimit {
+		item, err = o.uploadSmall(ctx, r, candidate)
+	} else {
+		item, err = o.uploadLarge(ctx, r, candidate, size)
+	}
+	if err != nil {
+		if isStatus(err, http.StatusNotFound) {
+			// a cached folder may have been deleted
+			o.dirs.Clear()
+		}
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+	o.logger.Debugf("Uploaded %s as %s", candidate, item.ID)
+	uploadinfo.Record(ctx, uploadinfo.File{Storage: o.config.Name, Path: candidate, ID: item.ID})
+	return nil
+}
+
+// contentLength returns the size of the file from the context or by seeking r.
+// A seekable r is rewound, a file left at its end by the writer would be uploaded empty otherwise
+func contentLength(ctx context.Context, r io.Reader) (int64, error) {
+	if length, ok := ctx.Value(ctxkey.ContentLength).(int64); ok && length >= 0 {
+		return length, nil
+	}
+	seeker, ok := r.(io.Seeker)
+	if !ok {
+		return 0, fmt.Errorf("onedrive storage needs the file size")
+	}
+	end, err := seeker.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return end, nil
+}
+
+func (o *OneDrive) uploadSmall(ctx context.Context, r io.Reader, storagePath string) (*driveItem, error) {
+	data, err := io.ReadAll(io.LimitReader(r, simpleUploadLimit+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	var item driveItem
+	if err := o.do(ctx, o.client, func() (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodPut, o.itemURL(storagePath, "/content")+"?@microsoft.graph.conflictBehavior=fail", bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/octet-stream")
+		return req, nil
+	}, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (o *OneDrive) Exists(ctx context.Context, storagePath string) bool {
+	err := o.do(ctx, o.client, func() (*http.Request, error) {
+		return http.NewRequest(http.MethodGet, o.itemURL(storagePath, "")+"?$select=id", nil)
+	}, nil)
+	if err != nil && !isStatus(err, http.StatusNotFound) {
+		o.logger.Errorf("Failed to check if file exists at %s: %v", storagePath, err)
+	}
+	return err == nil
+}
+
+// Probe gets the drive, which checks the credentials and the drive selection
+func (o *OneDrive) Probe(ctx context.Context) error {
+	return o.do(ctx, o.client, func() (*http.Request, error) {
+		return http.NewRequest(http.MethodGet, graphURL+o.drive+"?$select=id", nil)
+	}, nil)
+}
@@ -0,0 +1,37 @@
+package onedrive
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/krau/SaveAny-Bot/pkg/enums/ctxkey"
+)
+
+func TestContentLength(t *testing.T) {
+	r := bytes.NewReader([]byte("hello world"))
+	// left at the end, as a cache file after it was written
+	if _, err := r.Seek(0, io.SeekEnd); err != nil {
+		t.Fatal(err)
+	}
+	size, err := contentLength(context.Background(), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 11 {
+		t.Errorf("size = %d, want 11", size)
+	}
+	if data, _ := io.ReadAll(r); string(data) != "hello world" {
+		t.Errorf("read %q after contentLength, want the whole file", data)
+	}
+
+	ctx := context.WithValue(context.Background(), ctxkey.ContentLength, int64(42))
+	if size, err := contentLength(ctx, r); err != nil || size != 42 {
+		t.Errorf("contentLength() = %d, %v, want the length from the context", size, err)
+	}
+
+	if _, err := contentLength(context.Background(), io.LimitReader(r, 1)); err == nil {
+		t.Error("contentLength() of a reader without seeking or length succeeded")
+	}
+}
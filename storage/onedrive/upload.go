@@ -0,0 +1,137 @@
+package onedrive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type uploadSession struct {
+	UploadURL          string   `json:"uploadUrl"`
+	NextExpectedRanges []string `json:"nextExpectedRanges"`
+}
+
+// next returns the first offset Graph still expects
+func (s *uploadSession) next() (int64, error) {
+	if len(s.NextExpectedRanges) == 0 {
+		return 0, fmt.Errorf("upload session has no expected ranges")
+	}
+	start, _, _ := strings.Cut(s.NextExpectedRanges[0], "-")
+	return strconv.ParseInt(start, 10, 64)
+}
+
+// uploadLarge uploads r in the chunks of an upload session. A chunk is kept until Graph accepted it,
+// so after a failed request it is sent again from the offset Graph expects next
+func (o *OneDrive) uploadLarge(ctx context.Context, r io.Reader, storagePath string, size int64) (*driveItem, error) {
+	var session uploadSession
+	if err := o.do(ctx, o.client, jsonRequest(http.MethodPost, o.itemURL(storagePath, "/createUploadSession"), map[string]any{
+		"item": map[string]any{"@microsoft.graph.conflictBehavior": "fail"},
+	}), &session); err != nil {
+		return nil, fmt.Errorf("failed to create upload session: %w", err)
+	}
+
+	item, err := o.sendChunks(ctx, r, session.UploadURL, size)
+	if err != nil {
+		// the session would otherwise keep the uploaded bytes until it expires
+		req, reqErr := http.NewRequestWithContext(context.WithoutCancel(ctx), http.MethodDelete, session.UploadURL, nil)
+		if reqErr == nil {
+			if resp, err := o.uploadClient.Do(req); err == nil {
+				resp.Body.Close()
+			}
+		}
+		return nil, err
+	}
+	return item, nil
+}
+
+func (o *OneDrive) sendChunks(ctx context.Context, r io.Reader, uploadURL string, size int64) (*driveItem, error) {
+	buf := make([]byte, o.config.ChunkSize.Bytes())
+	var offset int64
+	for offset < size {
+		n, err := io.ReadFull(r, buf[:min(int64(len(buf)), size-offset)])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+		chunk := buf[:n]
+		end := offset + int64(n)
+		data := chunk
+		for attempt := 0; ; {
+			start := end - int64(len(data))
+			item, next, err := o.put(ctx, uploadURL, data, start, size)
+			if err != nil {
+				if !shouldRetry(ctx, err, attempt) {
+					return nil, err
+				}
+				delay := retryDelay(err, attempt)
+				attempt++
+				o.logger.Warnf("Chunk at %d bytes failed, resuming in %s: %s", start, delay.Round(time.Millisecond), err)
+				if err := sleep(ctx, delay); err != nil {
+					return nil, err
+				}
+				if next, err = o.status(ctx, uploadURL); err != nil {
+					continue
+				}
+			}
+			if item != nil {
+				return item, nil
+			}
+			if next >= end {
+				break
+			}
+			if next < offset {
+				return nil, fmt.Errorf("upload session expects %d bytes, want at least %d", next, offset)
+			}
+			data = chunk[next-offset:]
+		}
+		offset = end
+	}
+	return nil, fmt.Errorf("upload finished without a file item")
+}
+
+// put sends data at start and returns either the item, once the upload is complete,
+// or the offset Graph expects next
+func (o *OneDrive) put(ctx context.Context, uploadURL string, data []byte, start, size int64) (*driveItem, int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, uploadURL, bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, start+int64(len(data))-1, size))
+	resp, err := o.uploadClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		var item driveItem
+		if err := json.NewDecoder(resp.Body).Decode(&item); err != nil {
+			return nil, 0, fmt.Errorf("failed to decode response: %w", err)
+		}
+		return &item, 0, nil
+	case http.StatusAccepted:
+		var session uploadSession
+		if err := json.NewDecoder(resp.Body).Decode(&session); err != nil {
+			return nil, 0, fmt.Errorf("failed to decode response: %w", err)
+		}
+		next, err := session.next()
+		return nil, next, err
+	}
+	return nil, 0, parseError(resp)
+}
+
+// status returns the offset Graph expects next
+func (o *OneDrive) status(ctx context.Context, uploadURL string) (int64, error) {
+	var session uploadSession
+	if err := o.do(ctx, o.uploadClient, func() (*http.Request, error) {
+		return http.NewRequest(http.MethodGet, uploadURL, nil)
+	}, &session); err != nil {
+		return 0, err
+	}
+	return session.next()
+}
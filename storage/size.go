@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/krau/SaveAny-Bot/common/utils/fsutil"
+	"github.com/krau/SaveAny-Bot/config"
+)
+
+// CheckFileSize returns an error wrapping ErrFileTooLarge if the size reported by Telegram
+// exceeds the max file size of the storage
+func CheckFileSize(stor Storage, size int64) error {
+	limit := config.C().GetMaxFileSize(stor.Name())
+	if limit > 0 && size > limit {
+		return fmt.Errorf("%w: %.2f MB exceeds the limit of %.2f MB",
+			ErrFileTooLarge, float64(size)/(1024*1024), float64(limit)/(1024*1024))
+	}
+	return nil
+}
+
+// CheckFreeSpace returns an error if a file of size plus free_space_margin does not fit in tempDir,
+// or on the disk of the storage if it is a local one. tempDir is empty if the file is not downloaded first
+func CheckFreeSpace(stor Storage, size int64, tempDir string) error {
+	return checkFreeSpace(stor, size, config.C().FreeSpaceMargin.Bytes(), tempDir)
+}
+
+func checkFreeSpace(stor Storage, size, margin int64, tempDir string) error {
+	need := size + margin
+	if tempDir != "" {
+		if err := fsutil.CheckFreeSpace(tempDir, need); err != nil {
+			return err
+		}
+	}
+	if s, ok := stor.(StorageFreeSpace); ok {
+		return s.CheckFreeSpace(need)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/krau/SaveAny-Bot/pkg/storerr"
+)
+
+// freeSpaceStorage records the bytes it was asked for
+type freeSpaceStorage struct {
+	Storage
+	need int64
+	err  error
+}
+
+func (s *freeSpaceStorage) CheckFreeSpace(need int64) error {
+	s.need = need
+	return s.err
+}
+
+func TestCheckFreeSpace(t *testing.T) {
+	t.Run("margin added", func(t *testing.T) {
+		stor := &freeSpaceStorage{}
+		if err := checkFreeSpace(stor, 100, 50, ""); err != nil {
+			t.Fatal(err)
+		}
+		if stor.need != 150 {
+			t.Errorf("need = %d, want 150", stor.need)
+		}
+	})
+
+	t.Run("storage error", func(t *testing.T) {
+		stor := &freeSpaceStorage{err: storerr.ErrNoSpace}
+		if err := checkFreeSpace(stor, 100, 0, t.TempDir()); !errors.Is(err, storerr.ErrNoSpace) {
+			t.Errorf("checkFreeSpace() = %v, want ErrNoSpace", err)
+		}
+		if stor.need != 100 {
+			t.Errorf("need = %d, want 100", stor.need)
+		}
+	})
+
+	t.Run("temp dir full", func(t *testing.T) {
+		stor := &freeSpaceStorage{}
+		// the margin alone does not fit in the temp dir, the storage is not checked then
+		err := checkFreeSpace(stor, 1, math.MaxInt64-1, t.TempDir())
+		if !errors.Is(err, storerr.ErrNoSpace) {
+			t.Errorf("checkFreeSpace() = %v, want ErrNoSpace", err)
+		}
+		if stor.need != 0 {
+			t.Errorf("storage was checked for %d bytes", stor.need)
+		}
+	})
+}
@@ -0,0 +1,297 @@
+package smb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/hirochachacha/go-smb2"
+	config "github.com/krau/SaveAny-Bot/config/storage"
+	storenum "github.com/krau/SaveAny-Bot/pkg/enums/storage"
+)
+
+const (
+	chunkSize = 1024 * 1024
+	// times a chunk is written again after reconnecting
+	maxReconnects = 3
+)
+
+// NTSTATUS codes after which the session has to be established again
+const (
+	statusNetworkNameDeleted    = 0xC00000C9
+	statusUserSessionDeleted    = 0xC0000203
+	statusNetworkSessionExpired = 0xC000035C
+)
+
+type SMB struct {
+	config config.SMBStorageConfig
+	logger *log.Logger
+
+	mu      sync.Mutex
+	session *smb2.Session
+	share   *smb2.Share
+}
+
+func (s *SMB) Init(ctx context.Context, cfg config.StorageConfig) error {
+	smbConfig, ok := cfg.(*config.SMBStorageConfig)
+	if !ok {
+		return fmt.Errorf("failed to cast smb config")
+	}
+	if err := smbConfig.Validate(); err != nil {
+		return err
+	}
+	s.config = *smbConfig
+	if s.config.Guest {
+		// the guest account is used even if a username is set
+		s.config.Username, s.config.Password = "Guest", ""
+	}
+	s.logger = log.FromContext(ctx).WithPrefix(fmt.Sprintf("smb[%s]", s.config.Name))
+	return nil
+}
+
+// getShare returns the mounted share, connecting first if needed
+func (s *SMB) getShare(ctx context.Context) (*smb2.Share, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.share != nil {
+		return s.share, nil
+	}
+	addr := s.config.Address
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "445")
+	}
+	dialer := net.Dialer{Timeout: 30 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to smb server: %w", err)
+	}
+	d := &smb2.Dialer{
+		Initiator: &smb2.NTLMInitiator{
+			User:     s.config.Username,
+			Password: s.config.Password,
+			Domain:   s.config.Domain,
+		},
+	}
+	session, err := d.DialContext(ctx, conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to login to smb server: %w", err)
+	}
+	share, err := session.Mount(s.config.Share)
+	if err != nil {
+		session.Logoff()
+		return nil, fmt.Errorf("failed to mount share %s: %w", s.config.Share, err)
+	}
+	s.session, s.share = session, share
+	return share, nil
+}
+
+// reset drops the session the share belongs to, the next getShare connects again
+func (s *SMB) reset(share *smb2.Share) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.share != share || share == nil {
+		return
+	}
+	s.share.Umount()
+	s.session.Logoff()
+	s.session, s.share = nil, nil
+}
+
+// do runs fn on the share, connecting again once if the session expired
+func (s *SMB) do(ctx context.Context, fn func(fs *smb2.Share) error) error {
+	share, err := s.getShare(ctx)
+	if err != nil {
+		return err
+	}
+	if err = fn(share.WithContext(ctx)); err == nil || !sessionLost(err) {
+		return err
+	}
+	s.logger.Warnf("Session lost, reconnecting: %s", err)
+	s.reset(share)
+	if share, err = s.getShare(ctx); err != nil {
+		return err
+	}
+	return fn(share.WithContext(ctx))
+}
+
+// sessionLost reports whether err means the session or the connection is gone
+func sessionLost(err error) bool {
+	var transportErr *smb2.TransportError
+	if errors.As(err, &transportErr) {
+		return true
+	}
+	var respErr *smb2.ResponseError
+	if errors.As(err, &respErr) {
+		switch respErr.Code {
+		case statusNetworkNameDeleted, statusUserSessionDeleted, statusNetworkSessionExpired:
+			return true
+		}
+	}
+	return false
+}
+
+// sharePath converts a slash separated path to a path relative to the share root
+func sharePath(p string) string {
+	return strings.TrimLeft(strings.ReplaceAll(path.Clean("/"+p), "/", `\`), `\`)
+}
+
+func (s *SMB) Type() storenum.StorageType {
+	return storenum.Smb
+}
+
+func (s *SMB) Name() string {
+	return s.config.Name
+}
+
+// JoinStoragePath makes every element of p a valid Windows file name
+func (s *SMB) JoinStoragePath(p string) string {
+	elems := strings.Split(p, "/")
+	for i, elem := range elems {
+		elems[i] = sanitizeName(elem)
+	}
+	return path.Join(s.config.BasePath, path.Join(elems...))
+}
+
+func (s *SMB) Save(ctx context.Context, r io.Reader, storagePath string) error {
+	s.logger.Infof("Saving file to %s", storagePath)
+
+	ext := path.Ext(storagePath)
+	base := strings.TrimSuffix(storagePath, ext)
+	candidate := storagePath
+	for i := 1; s.Exists(ctx, candidate); i++ {
+		candidate = fmt.Sprintf("%s_%d%s", base, i, ext)
+	}
+	name := sharePath(candidate)
+
+	if err := s.do(ctx, func(fs *smb2.Share) error {
+		return fs.MkdirAll(sharePath(path.Dir(candidate)), 0o755)
+	}); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	w := &writer{smb: s, name: name, openFile: s.openFile}
+	if err := w.open(ctx, true); err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer w.close()
+
+	// the chunk that failed is kept, so the write can be continued on a new session even if r can't seek
+	buf := make([]byte, chunkSize)
+	var offset int64
+	for {
+		n, readErr := io.ReadFull(r, buf)
+		if n > 0 {
+			if err := w.writeAt(ctx, buf[:n], offset); err != nil {
+				return fmt.Errorf("failed to write file: %w", err)
+			}
+			offset += int64(n)
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			return fmt.Errorf("failed to read file: %w", readErr)
+		}
+	}
+	return w.close()
+}
+
+// remoteFile is the part of *smb2.File the writer uses
+type remoteFile interface {
+	io.WriterAt
+	io.Closer
+}
+
+// openFile creates or opens name for writing on the current session
+func (s *SMB) openFile(ctx context.Context, name string, create bool) (*smb2.Share, remoteFile, error) {
+	share, err := s.getShare(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	var file *smb2.File
+	if create {
+		file, err = share.WithContext(ctx).Create(name)
+	} else {
+		file, err = share.WithContext(ctx).OpenFile(name, os.O_WRONLY, 0o644)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return share, file, nil
+}
+
+// writer writes a file in chunks and opens it again on a new session when the current one is lost
+type writer struct {
+	smb      *SMB
+	name     string
+	openFile func(ctx context.Context, name string, create bool) (*smb2.Share, remoteFile, error)
+	share    *smb2.Share
+	file     remoteFile
+}
+
+func (w *writer) open(ctx context.Context, create bool) error {
+	share, file, err := w.openFile(ctx, w.name, create)
+	if err != nil {
+		return err
+	}
+	w.share, w.file = share, file
+	return nil
+}
+
+func (w *writer) writeAt(ctx context.Context, b []byte, offset int64) error {
+	for attempt := 0; ; attempt++ {
+		_, err := w.file.WriteAt(b, offset)
+		if err == nil {
+			return nil
+		}
+		if !sessionLost(err) || attempt >= maxReconnects {
+			return err
+		}
+		w.smb.logger.Warnf("Session lost while writing %s at %d bytes, reconnecting: %s", w.name, offset, err)
+		w.close()
+		w.smb.reset(w.share)
+		if err := w.open(ctx, false); err != nil {
+			return err
+		}
+	}
+}
+
+func (w *writer) close() error {
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+func (s *SMB) Exists(ctx context.Context, storagePath string) bool {
+	err := s.do(ctx, func(fs *smb2.Share) error {
+		_, err := fs.Stat(sharePath(storagePath))
+		return err
+	})
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		s.logger.Errorf("Failed to check if file exists at %s: %v", storagePath, err)
+	}
+	return err == nil
+}
+
+// Probe mounts the share and looks up base_path, a missing base_path is fine as it is created on save
+func (s *SMB) Probe(ctx context.Context) error {
+	err := s.do(ctx, func(fs *smb2.Share) error {
+		_, err := fs.Stat(sharePath(s.config.BasePath))
+		return err
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
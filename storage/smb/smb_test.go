@@ -0,0 +1,202 @@
+package smb
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/charmbracelet/log"
+	"github.com/hirochachacha/go-smb2"
+	config "github.com/krau/SaveAny-Bot/config/storage"
+)
+
+func TestSanitizeName(t *testing.T) {
+	testCases := []struct {
+		name string
+		want string
+	}{
+		{"video.mp4", "video.mp4"},
+		{"a:b*c?.txt", "a_b_c_.txt"},
+		{`say "hi" <now>|`, "say _hi_ _now__"},
+		{"trailing dots...", "trailing dots"},
+		{"trailing space ", "trailing space"},
+		{"CON", "_CON"},
+		{"nul.txt", "_nul.txt"},
+		{"COM1.tar.gz", "_COM1.tar.gz"},
+		{"CONSOLE.txt", "CONSOLE.txt"},
+		{"频道\t名", "频道_名"},
+	}
+	for _, tc := range testCases {
+		if got := sanitizeName(tc.name); got != tc.want {
+			t.Errorf("sanitizeName(%q) = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestJoinStoragePath(t *testing.T) {
+	s := &SMB{config: config.SMBStorageConfig{BasePath: "backup"}}
+	if got, want := s.JoinStoragePath("2025/频道: 新闻/aux.mp4"), "backup/2025/频道_ 新闻/_aux.mp4"; got != want {
+		t.Errorf("JoinStoragePath() = %q, want %q", got, want)
+	}
+	if got, want := sharePath("/backup/a/b.txt"), `backup\a\b.txt`; got != want {
+		t.Errorf("sharePath() = %q, want %q", got, want)
+	}
+	if got := sharePath(""); got != "" {
+		t.Errorf("sharePath(\"\") = %q, want root", got)
+	}
+}
+
+func TestInitGuest(t *testing.T) {
+	s := new(SMB)
+	err := s.Init(context.Background(), &config.SMBStorageConfig{
+		BaseConfig: config.BaseConfig{Name: "smb"},
+		Address:    "127.0.0.1",
+		Share:      "share",
+		Username:   "user",
+		Password:   "pass",
+		Guest:      true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.config.Username != "Guest" || s.config.Password != "" {
+		t.Errorf("logs in as %q with password %q, want the guest account", s.config.Username, s.config.Password)
+	}
+}
+
+// fakeFile writes into data and fails the writes while failures is above zero
+type fakeFile struct {
+	data     *[]byte
+	failures *int
+	closed   bool
+}
+
+func (f *fakeFile) WriteAt(b []byte, offset int64) (int, error) {
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	if *f.failures > 0 {
+		*f.failures--
+		return 0, &smb2.TransportError{Err: io.ErrUnexpectedEOF}
+	}
+	if end := int(offset) + len(b); end > len(*f.data) {
+		*f.data = append(*f.data, make([]byte, end-len(*f.data))...)
+	}
+	copy((*f.data)[offset:], b)
+	return len(b), nil
+}
+
+func (f *fakeFile) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestWriterReconnect(t *testing.T) {
+	ctx := context.Background()
+	newWriter := func(failures int) (*writer, *[]byte, *[]bool) {
+		data := []byte{}
+		var opens []bool
+		s := &SMB{logger: log.New(io.Discard)}
+		w := &writer{smb: s, name: "file.bin", openFile: func(ctx context.Context, name string, create bool) (*smb2.Share, remoteFile, error) {
+			opens = append(opens, create)
+			return nil, &fakeFile{data: &data, failures: &failures}, nil
+		}}
+		if err := w.open(ctx, true); err != nil {
+			t.Fatal(err)
+		}
+		return w, &data, &opens
+	}
+
+	t.Run("continues on a new session", func(t *testing.T) {
+		w, data, opens := newWriter(2)
+		if err := w.writeAt(ctx, []byte("hello "), 0); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.writeAt(ctx, []byte("world"), 6); err != nil {
+			t.Fatal(err)
+		}
+		if string(*data) != "hello world" {
+			t.Errorf("data = %q, want %q", *data, "hello world")
+		}
+		// created once, then opened again without truncating after each lost session
+		if want := []bool{true, false, false}; !slices.Equal(*opens, want) {
+			t.Errorf("opens = %v, want %v", *opens, want)
+		}
+	})
+
+	t.Run("gives up", func(t *testing.T) {
+		w, _, opens := newWriter(maxReconnects + 1)
+		var transportErr *smb2.TransportError
+		if err := w.writeAt(ctx, []byte("hello"), 0); !errors.As(err, &transportErr) {
+			t.Errorf("writeAt() = %v, want the transport error", err)
+		}
+		if len(*opens) != maxReconnects+1 {
+			t.Errorf("opened %d times, want %d", len(*opens), maxReconnects+1)
+		}
+	})
+}
+
+// TestSaveIntegration runs against a real server, e.g.
+//
+//	docker run -d -p 445:445 dperson/samba -u "user;pass" -s "share;/share;yes;no;no;user"
+//	SMB_TEST_ADDRESS=127.0.0.1 SMB_TEST_SHARE=share SMB_TEST_USERNAME=user SMB_TEST_PASSWORD=pass go test ./storage/smb
+func TestSaveIntegration(t *testing.T) {
+	address := os.Getenv("SMB_TEST_ADDRESS")
+	if address == "" {
+		t.Skip("SMB_TEST_ADDRESS is not set")
+	}
+	ctx := context.Background()
+	s := new(SMB)
+	err := s.Init(ctx, &config.SMBStorageConfig{
+		BaseConfig: config.BaseConfig{Name: "smb"},
+		Address:    address,
+		Share:      os.Getenv("SMB_TEST_SHARE"),
+		BasePath:   "saveany-test",
+		Username:   os.Getenv("SMB_TEST_USERNAME"),
+		Password:   os.Getenv("SMB_TEST_PASSWORD"),
+		Guest:      os.Getenv("SMB_TEST_USERNAME") == "",
+	})
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	if err := s.Probe(ctx); err != nil {
+		t.Fatalf("Probe: %v", err)
+	}
+	t.Cleanup(func() {
+		s.do(ctx, func(fs *smb2.Share) error { return fs.RemoveAll("saveany-test") })
+	})
+
+	// larger than a chunk, so it is written in several requests
+	content := make([]byte, 3*chunkSize+123)
+	rand.Read(content)
+	storagePath := s.JoinStoragePath("nested/dir/big:file.bin")
+	for _, want := range []string{`saveany-test\nested\dir\big_file.bin`, `saveany-test\nested\dir\big_file_1.bin`} {
+		if err := s.Save(ctx, bytes.NewReader(content), storagePath); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		var data []byte
+		if err := s.do(ctx, func(fs *smb2.Share) (err error) {
+			data, err = fs.ReadFile(want)
+			return err
+		}); err != nil {
+			t.Fatalf("ReadFile %s: %v", want, err)
+		}
+		if !bytes.Equal(data, content) {
+			t.Fatalf("content of %s does not match, got %d bytes, want %d", want, len(data), len(content))
+		}
+	}
+	if !s.Exists(ctx, storagePath) {
+		t.Fatalf("Exists(%s) = false", storagePath)
+	}
+
+	// a lost session is established again and the write continues
+	s.reset(s.share)
+	if err := s.Save(ctx, bytes.NewReader(content), s.JoinStoragePath("after-reset.bin")); err != nil {
+		t.Fatalf("Save after reset: %v", err)
+	}
+}
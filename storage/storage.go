@@ -8,8 +8,14 @@ import (
 	storcfg "github.com/krau/SaveAny-Bot/config/storage"
 	storenum "github.com/krau/SaveAny-Bot/pkg/enums/storage"
 	"github.com/krau/SaveAny-Bot/storage/alist"
+	"github.com/krau/SaveAny-Bot/storage/b2"
+	"github.com/krau/SaveAny-Bot/storage/dropbox"
+	"github.com/krau/SaveAny-Bot/storage/ftp"
+	"github.com/krau/SaveAny-Bot/storage/gdrive"
 	"github.com/krau/SaveAny-Bot/storage/local"
 	"github.com/krau/SaveAny-Bot/storage/minio"
+	"github.com/krau/SaveAny-Bot/storage/onedrive"
+	"github.com/krau/SaveAny-Bot/storage/smb"
 	"github.com/krau/SaveAny-Bot/storage/telegram"
 	"github.com/krau/SaveAny-Bot/storage/webdav"
 )
@@ -28,6 +34,18 @@ type StorageCannotStream interface {
 	CannotStream() string
 }
 
+// StorageFreeSpace is implemented by storages on a local filesystem, whose free space is checked before saving
+type StorageFreeSpace interface {
+	Storage
+	CheckFreeSpace(need int64) error
+}
+
+// StorageProber is implemented by storages that can check they are reachable and usable, used by check-config
+type StorageProber interface {
+	Storage
+	Probe(ctx context.Context) error
+}
+
 var Storages = make(map[string]Storage)
 
 type StorageConstructor func() Storage
@@ -38,6 +56,12 @@ var storageConstructors = map[storenum.StorageType]StorageConstructor{
 	storenum.Webdav:   func() Storage { return new(webdav.Webdav) },
 	storenum.Minio:    func() Storage { return new(minio.Minio) },
 	storenum.Telegram: func() Storage { return new(telegram.Telegram) },
+	storenum.Ftp:      func() Storage { return new(ftp.FTP) },
+	storenum.Smb:      func() Storage { return new(smb.SMB) },
+	storenum.Gdrive:   func() Storage { return new(gdrive.GDrive) },
+	storenum.Onedrive: func() Storage { return new(onedrive.OneDrive) },
+	storenum.Dropbox:  func() Storage { return new(dropbox.Dropbox) },
+	storenum.B2:       func() Storage { return new(b2.B2) },
 }
 
 func NewStorage(ctx context.Context, cfg storcfg.StorageConfig) (Storage, error) {
@@ -0,0 +1,55 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/krau/SaveAny-Bot/config"
+)
+
+const botAPIURL = "https://api.telegram.org"
+
+// Probe calls getMe of the Bot API with the bot token, through the proxy if enabled
+func (t *Telegram) Probe(ctx context.Context) error {
+	tgConfig := config.C().Telegram
+	if tgConfig.Token == "" {
+		return fmt.Errorf("telegram.token is not set")
+	}
+	transport := &http.Transport{}
+	if tgConfig.Proxy.Enable && tgConfig.Proxy.URL != "" {
+		proxyURL, err := url.Parse(tgConfig.Proxy.URL)
+		if err != nil {
+			return fmt.Errorf("invalid proxy url: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	client := &http.Client{Transport: transport}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, botAPIURL+"/bot"+tgConfig.Token+"/getMe", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		// the error contains the url, keep the token out of it
+		if uerr, ok := err.(*url.Error); ok {
+			return fmt.Errorf("failed to send request: %w", uerr.Err)
+		}
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	var meResp struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&meResp); err != nil {
+		return fmt.Errorf("failed to decode getMe response: %s", resp.Status)
+	}
+	if !meResp.OK {
+		return fmt.Errorf("getMe: %s", meResp.Description)
+	}
+	return nil
+}
@@ -75,6 +75,12 @@ func (w *Webdav) Save(ctx context.Context, r io.Reader, storagePath string) erro
 	return nil
 }
 
+// Probe sends a PROPFIND to base_path, a missing base_path is fine as it is created on save
+func (w *Webdav) Probe(ctx context.Context) error {
+	_, err := w.client.Exists(ctx, w.config.BasePath)
+	return err
+}
+
 func (w *Webdav) Exists(ctx context.Context, storagePath string) bool {
 	w.logger.Debugf("Checking if file exists at %s", storagePath)
 	exists, err := w.client.Exists(ctx, storagePath)